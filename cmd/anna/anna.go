@@ -14,10 +14,29 @@ import (
 
 type Cmd struct {
 	RenderDrafts       bool
+	RenderExpired      bool
+	Strict             bool
+	StrictConfig       bool
+	NoAnalytics        bool
+	ProfileRender      int
+	ReportOrphans      bool
+	Jobs               int
+	Version            string
+	OnlyTypes          []string
+	SkipTypes          []string
+	LintFormat         string
 	Addr               string
 	LiveReload         bool
 	RenderSpecificSite string
 	ServeSpecificSite  string
+	// Suppresses progress output, only warnings and errors are printed
+	Quiet bool
+	// Serves the site without watching it for changes or reloading the open pages
+	NoReload bool
+	// Overrides the base URL of the site config when set, such as when serving a preview
+	BaseURL string
+	// Overrides the basePath of the site config when set, prefixing links and assets independently of the base URL
+	BasePath string
 
 	// Common logger for all cmd functions
 	ErrorLogger *log.Logger
@@ -28,6 +47,38 @@ type AnnaConfig struct {
 	SiteDataPaths map[string]string `json:"siteDataPaths"`
 }
 
+// siteDir returns the directory of the site set with --render-site, or site/ when it is not set, with a trailing slash
+func (cmd *Cmd) siteDir() string {
+	siteDirPath := cmd.RenderSpecificSite
+	if siteDirPath == "" {
+		siteDirPath = "site/"
+	}
+	if !strings.HasSuffix(siteDirPath, "/") {
+		siteDirPath += "/"
+	}
+	return siteDirPath
+}
+
+// newParser returns a parser of the site at siteDirPath with the options of the command, its config is not parsed yet
+func (cmd *Cmd) newParser(siteDirPath string) *parser.Parser {
+	return &parser.Parser{
+		Templates:                 make(map[template.URL]parser.TemplateData, 10),
+		TagsMap:                   make(map[template.URL][]parser.TemplateData, 10),
+		CollectionsMap:            make(map[template.URL][]parser.TemplateData, 10),
+		CollectionsSubPageLayouts: make(map[template.URL]string, 10),
+		SiteDataPath:              siteDirPath,
+		ErrorLogger:               cmd.ErrorLogger,
+		RenderDrafts:              cmd.RenderDrafts,
+		RenderExpired:             cmd.RenderExpired,
+		StrictConfig:              cmd.StrictConfig,
+		OnlyTypes:                 cmd.OnlyTypes,
+		SkipTypes:                 cmd.SkipTypes,
+		Jobs:                      cmd.Jobs,
+		LiveReload:                cmd.LiveReload && !cmd.NoReload,
+		Warnings:                  helpers.NewWarningCollector(),
+	}
+}
+
 func (cmd *Cmd) VanillaRenderManager() {
 
 	// Check if the configuration file exists
@@ -86,15 +137,12 @@ func (cmd *Cmd) VanillaRenderManager() {
 }
 
 func (cmd *Cmd) ValidateHTMLManager() {
-	// Rendering all sites
-	cmd.VanillaRenderManager()
-
 	// Check if the configuration file exists
 	// If it does not, validate only the site/ directory
 
 	_, err := os.Stat("anna.json")
 	if os.IsNotExist(err) {
-		cmd.VanillaRender("site/")
+		cmd.reportFindings(cmd.ValidateHTMLContent("site/"))
 		return
 	}
 
@@ -112,10 +160,11 @@ func (cmd *Cmd) ValidateHTMLManager() {
 	}
 
 	// Validating sites
+	var findings []LintFinding
 	validatedSites := false
 
 	for _, sitePath := range annaConfig.SiteDataPaths {
-		cmd.ValidateHTMLContent(sitePath)
+		findings = append(findings, cmd.ValidateHTMLContent(sitePath)...)
 		if !validatedSites {
 			validatedSites = true
 		}
@@ -123,9 +172,10 @@ func (cmd *Cmd) ValidateHTMLManager() {
 
 	// If no site has been validated due to empty "anna.yml", validate the default "site/" path
 	if !validatedSites {
-		cmd.ValidateHTMLContent("site/")
+		findings = cmd.ValidateHTMLContent("site/")
 	}
 
+	cmd.reportFindings(findings)
 }
 
 func (cmd *Cmd) LiveReloadManager() {
@@ -170,22 +220,23 @@ func (cmd *Cmd) LiveReloadManager() {
 }
 
 func (cmd *Cmd) VanillaRender(siteDirPath string) {
+	warnings := helpers.NewWarningCollector()
 
-	// Defining Engine and Parser Structures
-	p := parser.Parser{
-		Templates:                 make(map[template.URL]parser.TemplateData, 10),
-		TagsMap:                   make(map[template.URL][]parser.TemplateData, 10),
-		CollectionsMap:            make(map[template.URL][]parser.TemplateData, 10),
-		CollectionsSubPageLayouts: make(map[template.URL]string, 10),
-		SiteDataPath:              siteDirPath,
-		ErrorLogger:               log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
-		RenderDrafts:              cmd.RenderDrafts,
-		LiveReload:                cmd.LiveReload,
+	if len(cmd.OnlyTypes) > 0 || len(cmd.SkipTypes) > 0 {
+		cmd.InfoLogger.Println("Partial build, the rendered site is incomplete and should not be deployed")
 	}
 
+	// Defining Engine and Parser Structures
+	p := cmd.newParser(siteDirPath)
+	p.ErrorLogger = log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
+	p.Warnings = warnings
+
 	e := engine.Engine{
-		SiteDataPath: siteDirPath,
-		ErrorLogger:  log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		SiteDataPath:     siteDirPath,
+		ErrorLogger:      log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:         warnings,
+		DisableAnalytics: cmd.LiveReload || cmd.NoAnalytics,
+		ProfileRender:    cmd.ProfileRender > 0,
 	}
 	e.DeepDataMerge.Templates = make(map[template.URL]parser.TemplateData, 10)
 	e.DeepDataMerge.TagsMap = make(map[template.URL][]parser.TemplateData, 10)
@@ -193,30 +244,62 @@ func (cmd *Cmd) VanillaRender(siteDirPath string) {
 
 	helper := helpers.Helper{
 		ErrorLogger: e.ErrorLogger,
+		Jobs:        cmd.Jobs,
 	}
 
 	helper.CreateRenderedDir(siteDirPath)
 
 	p.ParseConfig(siteDirPath + "layout/config.json")
-	p.ParseRobots(siteDirPath+"layout/robots.txt", siteDirPath+"rendered/robots.txt")
+	if cmd.BaseURL != "" {
+		p.LayoutConfig.BaseURL = strings.TrimSuffix(cmd.BaseURL, "/")
+	}
+	if cmd.BasePath != "" {
+		p.LayoutConfig.PathPrefix = cmd.BasePath
+	}
 
-	fileSystem := os.DirFS(siteDirPath + "content/")
-	p.ParseMDDir(siteDirPath+"content/", fileSystem)
+	p.ParseContentDirs()
 
 	templ := p.ParseLayoutFiles()
 
 	e.DeepDataMerge.Templates = p.Templates
+	e.DeepDataMerge.Posts = p.Posts
 	e.DeepDataMerge.TagsMap = p.TagsMap
 	e.DeepDataMerge.CollectionsMap = p.CollectionsMap
 	e.DeepDataMerge.CollectionsSubPageLayouts = p.CollectionsSubPageLayouts
+	e.DeepDataMerge.CollectionsMetadata = p.CollectionsMetadata
+	e.DeepDataMerge.DisplayNames = p.DisplayNames
+	e.DeepDataMerge.TaxonomyMap = p.TaxonomyMap
+	e.DeepDataMerge.Env = engine.EnvProduction
+	if cmd.LiveReload {
+		e.DeepDataMerge.Env = engine.EnvDevelopment
+	}
+	e.DeepDataMerge.Version = cmd.Version
+	buildTime, err := helpers.BuildTime()
+	if err != nil {
+		cmd.ErrorLogger.Fatal("Invalid SOURCE_DATE_EPOCH: ", err)
+	}
+	e.DeepDataMerge.BuildTime = buildTime
+	p.LayoutConfig.Copyright = p.LayoutConfig.CopyrightNotice(buildTime)
 	e.DeepDataMerge.LayoutConfig = p.LayoutConfig
 
+	e.SortPages(e.DeepDataMerge.Posts)
+
+	// Undefined layouts are reported before anything is rendered
+	e.CheckLayouts(templ)
+
+	// The static files of the theme are copied first, so that the files of the site replace them
+	if themePath := p.LayoutConfig.ThemePath(); themePath != "" {
+		if _, err := os.Stat(siteDirPath + themePath + "static/"); err == nil {
+			helper.CopyDirectoryContents(siteDirPath+themePath+"static/", siteDirPath+"rendered/static/")
+		}
+	}
+
 	// Copies the contents of the 'static/' directory to 'rendered/'
 	helper.CopyDirectoryContents(siteDirPath+"static/", siteDirPath+"rendered/static/")
 
 	// Check if the public folder exists ands copy contents
 
-	_, err := os.Stat(siteDirPath + "public/")
+	_, err = os.Stat(siteDirPath + "public/")
 	if os.IsNotExist(err) {
 	} else {
 		// Check if the public folder exists ands copy contents
@@ -230,10 +313,69 @@ func (cmd *Cmd) VanillaRender(siteDirPath string) {
 	}
 
 	e.GenerateSitemap(siteDirPath + "rendered/sitemap.xml")
+	e.GenerateFavicons(siteDirPath)
+	e.GenerateManifest(siteDirPath + "rendered/manifest.webmanifest")
+	e.GenerateHumansTxt(siteDirPath + "rendered/humans.txt")
+	e.GenerateSecurityTxt(siteDirPath)
 	e.GenerateFeed()
+	e.GenerateCollectionOutputs()
+	if e.DeepDataMerge.LayoutConfig.OPML {
+		e.GenerateOPML(siteDirPath + "rendered/feeds.opml")
+	}
 	e.GenerateJSONIndex(siteDirPath)
+	if e.DeepDataMerge.LayoutConfig.API {
+		e.GenerateAPI(siteDirPath)
+	}
+	if e.DeepDataMerge.LayoutConfig.TagsJSON {
+		e.GenerateTagsJSON(siteDirPath)
+	}
+
+	e.BuildPostNavigation()
+	e.BuildArchive()
+	e.BuildSections()
+	e.BuildMenus()
+	e.BuildRelatedNotes()
+	e.BuildOrphanNotes()
+
+	e.RenderTemplateFiles(siteDirPath)
 
 	e.RenderUserDefinedPages(siteDirPath, templ)
-	e.RenderTags(siteDirPath, templ)
-	e.RenderCollections(siteDirPath, templ)
+	if e.DeepDataMerge.LayoutConfig.ReaderMode {
+		e.RenderReaderPages(siteDirPath, templ)
+	}
+	if e.DeepDataMerge.LayoutConfig.Archive != nil {
+		e.RenderArchive(siteDirPath, templ)
+	}
+	e.RenderTaxonomies(siteDirPath, templ)
+	if e.DeepDataMerge.LayoutConfig.HTMLSitemap {
+		e.RenderHTMLSitemap(siteDirPath, templ)
+	}
+	if e.DeepDataMerge.LayoutConfig.PostsListing {
+		e.RenderPostsListing(siteDirPath, templ)
+	}
+
+	// The service worker precaches the rendered site, so it is generated last
+	e.GenerateServiceWorker(siteDirPath)
+
+	if cmd.ReportOrphans {
+		cmd.PrintOrphanNotes(e.DeepDataMerge.OrphanNotes)
+	}
+	if cmd.ProfileRender > 0 {
+		cmd.PrintSlowestPages(e.SlowestPages(cmd.ProfileRender))
+	}
+
+	cmd.checkWarnings(warnings)
+}
+
+// checkWarnings fails the build if any warnings were reported in strict mode
+func (cmd *Cmd) checkWarnings(warnings *helpers.WarningCollector) {
+	count := warnings.Count()
+	if count == 0 {
+		return
+	}
+
+	if cmd.Strict {
+		cmd.ErrorLogger.Fatalf("Build failed with %d warning(s) in strict mode", count)
+	}
+	cmd.InfoLogger.Printf("Build completed with %d warning(s)", count)
 }
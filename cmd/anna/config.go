@@ -0,0 +1,104 @@
+package anna
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// Source of the baseURL when it is overridden with the --base-url flag
+const baseURLFlagSource = "flag --base-url"
+
+// Source of the basePath when it is overridden with the --base-path flag
+const basePathFlagSource = "flag --base-path"
+
+// ConfigManager prints the effective config of the site, after the environment variables and flags are applied, without building it
+func (cmd *Cmd) ConfigManager(format string, verbose bool) {
+	siteDirPath := cmd.siteDir()
+	p := cmd.newParser(siteDirPath)
+	configPath := siteDirPath + "layout/config.json"
+	p.ParseConfig(configPath)
+
+	var sources map[string]string
+	if verbose {
+		sources = p.ConfigSources(configPath)
+	}
+	if cmd.BaseURL != "" {
+		p.LayoutConfig.BaseURL = strings.TrimSuffix(cmd.BaseURL, "/")
+		if sources != nil {
+			sources["baseURL"] = baseURLFlagSource
+		}
+	}
+	if cmd.BasePath != "" {
+		p.LayoutConfig.PathPrefix = cmd.BasePath
+		if sources != nil {
+			sources["basePath"] = basePathFlagSource
+		}
+	}
+
+	if err := WriteConfig(os.Stdout, p.LayoutConfig, format, sources); err != nil {
+		cmd.ErrorLogger.Fatal(err)
+	}
+}
+
+/*
+WriteConfig writes the config as JSON or YAML with the keys of config.json
+
+When the sources of the keys are given, they are written as a comment next to every key in YAML,
+while JSON holds the config and the sources in the "config" and "sources" fields
+*/
+func WriteConfig(w io.Writer, config parser.LayoutConfig, format string, sources map[string]string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if sources == nil {
+			return encoder.Encode(config)
+		}
+		return encoder.Encode(struct {
+			Config  parser.LayoutConfig `json:"config"`
+			Sources map[string]string   `json:"sources"`
+		}{config, sources})
+
+	case "yaml":
+		configJSON, err := json.Marshal(config)
+		if err != nil {
+			return err
+		}
+
+		// JSON is parsed as YAML so that the keys of config.json are kept in the order of the fields
+		var document yaml.Node
+		if err := yaml.Unmarshal(configJSON, &document); err != nil {
+			return err
+		}
+		blockStyle(&document)
+		if mapping := document.Content[0]; sources != nil {
+			for i := 0; i < len(mapping.Content); i += 2 {
+				mapping.Content[i].LineComment = sources[mapping.Content[i].Value]
+			}
+		}
+
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&document); err != nil {
+			return err
+		}
+		return encoder.Close()
+
+	default:
+		return fmt.Errorf("unknown config format %q, expected json or yaml", format)
+	}
+}
+
+// blockStyle clears the styles of the nodes parsed from JSON, so that collections are written as YAML blocks and strings are only quoted when needed
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,283 @@
+package anna
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/anna-ssg/anna/v3/pkg/engine"
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+// DoctorCheck is the result of a check of anna doctor
+// A check passes when it has no findings, and is skipped when an earlier check failed with errors
+type DoctorCheck struct {
+	Name     string
+	Skipped  bool
+	Findings []LintFinding
+}
+
+// Status returns ok, warn, fail or skip depending on the findings of the check
+func (c DoctorCheck) Status() string {
+	if c.Skipped {
+		return "skip"
+	}
+	status := "ok"
+	for _, finding := range c.Findings {
+		if finding.Severity == lintSeverityError {
+			return "fail"
+		}
+		status = "warn"
+	}
+	return status
+}
+
+/*
+Doctor checks the config, frontmatter and layouts of a site, then renders a copy of the site in a temporary
+directory to check that its pages are rendered and that the internal links of the rendered site are not broken
+
+The site is not rendered when any of the first checks fail, as the build would fail
+*/
+func (cmd *Cmd) Doctor(siteDirPath string) []DoctorCheck {
+	configCheck := DoctorCheck{Name: "config", Findings: doctorConfig(siteDirPath)}
+	checks := []DoctorCheck{configCheck}
+	if configCheck.Status() == "fail" {
+		return append(checks,
+			DoctorCheck{Name: "frontmatter", Skipped: true},
+			DoctorCheck{Name: "layouts", Skipped: true},
+			DoctorCheck{Name: "pages", Skipped: true},
+			DoctorCheck{Name: "links", Skipped: true},
+		)
+	}
+
+	checks = append(checks, DoctorCheck{Name: "frontmatter", Findings: cmd.lintFrontmatter(siteDirPath)})
+
+	p := cmd.newParser(siteDirPath)
+	p.ParseConfig(siteDirPath + "layout/config.json")
+	p.ParseContentDirs()
+
+	checks = append(checks, DoctorCheck{Name: "layouts", Findings: doctorLayouts(p)})
+	for _, check := range checks {
+		if check.Status() == "fail" {
+			return append(checks,
+				DoctorCheck{Name: "pages", Skipped: true},
+				DoctorCheck{Name: "links", Skipped: true},
+			)
+		}
+	}
+
+	tempDirPath, err := os.MkdirTemp("", "anna-doctor-")
+	if err != nil {
+		cmd.ErrorLogger.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tempDirPath); err != nil {
+			cmd.ErrorLogger.Println(err)
+		}
+	}()
+
+	// The site is rendered from a copy so that its rendered/ directory is left untouched
+	buildDirPath := tempDirPath + "/site/"
+	helper := helpers.Helper{ErrorLogger: cmd.ErrorLogger, Jobs: cmd.Jobs}
+	helper.CopyDirectoryContents(siteDirPath, buildDirPath)
+
+	build := Cmd{
+		Jobs:        cmd.Jobs,
+		Version:     cmd.Version,
+		NoAnalytics: true,
+		ErrorLogger: cmd.ErrorLogger,
+		InfoLogger:  log.New(io.Discard, "", 0),
+	}
+	build.VanillaRender(buildDirPath)
+
+	renderedPath := buildDirPath + "rendered/"
+	return append(checks,
+		DoctorCheck{Name: "pages", Findings: doctorPages(p, renderedPath)},
+		DoctorCheck{Name: "links", Findings: doctorLinks(renderedPath, p.LayoutConfig.BasePath())},
+	)
+}
+
+// doctorConfig reports a config.json that is missing or invalid, and the fields of the site left unset
+func doctorConfig(siteDirPath string) []LintFinding {
+	configPath := siteDirPath + "layout/config.json"
+	configFile, err := os.ReadFile(configPath)
+	if err != nil {
+		return []LintFinding{{File: configPath, Rule: "config", Severity: lintSeverityError, Message: err.Error()}}
+	}
+
+	var config parser.LayoutConfig
+	if err := json.Unmarshal(configFile, &config); err != nil {
+		return []LintFinding{{File: configPath, Rule: "config", Severity: lintSeverityError, Message: err.Error()}}
+	}
+
+	var findings []LintFinding
+	if config.BaseURL == "" {
+		findings = append(findings, LintFinding{File: configPath, Rule: "config", Severity: lintSeverityWarning, Message: "baseURL is not set, the sitemap, feed and canonical URLs will not be absolute"})
+	}
+	if config.SiteTitle == "" {
+		findings = append(findings, LintFinding{File: configPath, Rule: "config", Severity: lintSeverityWarning, Message: "siteTitle is not set"})
+	}
+	return findings
+}
+
+// doctorLayouts reports layouts that fail to parse, and the layouts of the pages, tags and collections that are not defined
+// The layouts are checked with the same rules as the build, which fails on undefined layouts before rendering
+func doctorLayouts(p *parser.Parser) []LintFinding {
+	layoutPath := p.SiteDataPath + "layout/"
+	templ, err := p.LayoutTemplates()
+	if err != nil {
+		return []LintFinding{{File: layoutPath, Rule: "layouts", Severity: lintSeverityError, Message: err.Error()}}
+	}
+
+	e := engine.Engine{
+		SiteDataPath: p.SiteDataPath,
+		ErrorLogger:  p.ErrorLogger,
+	}
+	e.DeepDataMerge.Templates = p.Templates
+	e.DeepDataMerge.Posts = p.Posts
+	e.DeepDataMerge.TagsMap = p.TagsMap
+	e.DeepDataMerge.CollectionsMap = p.CollectionsMap
+	e.DeepDataMerge.CollectionsSubPageLayouts = p.CollectionsSubPageLayouts
+	e.DeepDataMerge.TaxonomyMap = p.TaxonomyMap
+	e.DeepDataMerge.LayoutConfig = p.LayoutConfig
+
+	var findings []LintFinding
+	for _, message := range e.MissingLayouts(templ) {
+		findings = append(findings, LintFinding{File: layoutPath, Rule: "layouts", Severity: lintSeverityError, Message: message})
+	}
+	return findings
+}
+
+// doctorPages reports a site without pages and the pages missing from the rendered site
+func doctorPages(p *parser.Parser, renderedPath string) []LintFinding {
+	if len(p.Templates) == 0 {
+		return []LintFinding{{File: p.ContentDirPath(), Rule: "pages", Severity: lintSeverityError, Message: "no pages were rendered, add markdown files with a title in their frontmatter"}}
+	}
+
+	var findings []LintFinding
+	for pageURL := range p.Templates {
+		if _, err := os.Stat(renderedPath + string(pageURL)); err != nil {
+			findings = append(findings, LintFinding{File: string(pageURL), Rule: "pages", Severity: lintSeverityError, Message: "the page was not rendered"})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].File < findings[j].File
+	})
+	return findings
+}
+
+// doctorLinks reports the links and assets of the rendered pages pointing to files missing from the rendered site
+func doctorLinks(renderedPath string, basePath string) []LintFinding {
+	var findings []LintFinding
+
+	err := filepath.WalkDir(renderedPath, func(filePath string, dir fs.DirEntry, err error) error {
+		if err != nil || dir.IsDir() || filepath.Ext(filePath) != ".html" {
+			return err
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		doc, err := goquery.NewDocumentFromReader(file)
+		if err != nil {
+			return err
+		}
+
+		pagePath, _ := filepath.Rel(renderedPath, filePath)
+		pagePath = filepath.ToSlash(pagePath)
+		reported := make(map[string]bool)
+
+		doc.Find("a[href], link[href], img[src], script[src]").Each(func(_ int, s *goquery.Selection) {
+			link, ok := s.Attr("href")
+			if !ok {
+				link, _ = s.Attr("src")
+			}
+			target, ok := internalLinkTarget(link, pagePath, basePath)
+			if !ok || reported[link] || renderedFileExists(renderedPath, target) {
+				return
+			}
+			reported[link] = true
+			findings = append(findings, LintFinding{File: pagePath, Rule: "broken-link", Severity: lintSeverityWarning, Message: "links to missing " + link})
+		})
+		return nil
+	})
+	if err != nil {
+		findings = append(findings, LintFinding{File: renderedPath, Rule: "broken-link", Severity: lintSeverityError, Message: err.Error()})
+	}
+	return findings
+}
+
+// internalLinkTarget returns the path of the target of a link relative to the rendered site, links to other sites are skipped
+func internalLinkTarget(link string, pagePath string, basePath string) (string, bool) {
+	linkURL, err := url.Parse(link)
+	if err != nil || linkURL.Scheme != "" || linkURL.Host != "" || linkURL.Path == "" {
+		return "", false
+	}
+
+	if !strings.HasPrefix(linkURL.Path, "/") {
+		target := path.Join(path.Dir(pagePath), linkURL.Path)
+		if strings.HasSuffix(linkURL.Path, "/") {
+			target += "/"
+		}
+		return target, true
+	}
+
+	target, ok := strings.CutPrefix(linkURL.Path, basePath)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimPrefix(target, "/"), true
+}
+
+// renderedFileExists reports whether a link target is rendered, as a file, as the index.html of a directory or with the .html extension
+func renderedFileExists(renderedPath string, target string) bool {
+	candidates := []string{target}
+	if target == "" || strings.HasSuffix(target, "/") {
+		candidates = []string{target + "index.html"}
+	} else if path.Ext(target) == "" {
+		candidates = append(candidates, target+".html", target+"/index.html")
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(renderedPath + candidate); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// DoctorManager prints the health report of the site and fails when any check finds errors
+func (cmd *Cmd) DoctorManager() {
+	checks := cmd.Doctor(cmd.siteDir())
+
+	errorCount, warningCount := 0, 0
+	for _, check := range checks {
+		fmt.Printf("%-4s  %s\n", check.Status(), check.Name)
+		for _, finding := range check.Findings {
+			fmt.Printf("      %s\n", finding)
+			if finding.Severity == lintSeverityError {
+				errorCount++
+			} else {
+				warningCount++
+			}
+		}
+	}
+
+	if errorCount > 0 {
+		cmd.ErrorLogger.Fatalf("Doctor found %d error(s) and %d warning(s)", errorCount, warningCount)
+	}
+	cmd.InfoLogger.Printf("Doctor found %d warning(s)", warningCount)
+}
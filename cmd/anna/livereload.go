@@ -1,17 +1,26 @@
 package anna
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"html/template"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
-	"sync/atomic"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
-)
 
-var reloadPageBool atomic.Bool
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
 
 type liveReload struct {
 	errorLogger *log.Logger
@@ -26,56 +35,314 @@ type liveReload struct {
 	serverRunning bool
 
 	siteDataPath string
+
+	// Suppresses the files reported as changed
+	quiet bool
+
+	// Overrides the base URL of the site config when set
+	baseURL string
+	// Overrides the basePath of the site config when set
+	pathPrefix string
+
+	// Notifies the open pages of rebuilds
+	hub *reloadHub
 }
 
-func newLiveReload(siteDataPath string) *liveReload {
+func newLiveReload(siteDataPath string, quiet bool) *liveReload {
 	lr := liveReload{
 		errorLogger:  log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
 		fileTimes:    make(map[string]time.Time),
 		rootDirs:     []string{siteDataPath},
 		extensions:   []string{".md"},
 		siteDataPath: siteDataPath,
+		quiet:        quiet,
+		hub:          newReloadHub(),
 	}
 	return &lr
 }
 
+/*
+StartLiveReload renders the site, serves it at the address of the cmd and, unless NoReload is set, re-renders it and
+reloads the open pages whenever a markdown file, layout or the config changes
+
+The server is shut down once the process is interrupted, letting the requests being served complete
+*/
 func (cmd *Cmd) StartLiveReload(siteDataPath string) {
-	fmt.Println("Live Reload is active")
-	lr := newLiveReload(siteDataPath)
-	go lr.startServer(cmd.Addr)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	addr, err := NormalizeAddr(cmd.Addr)
+	if err != nil {
+		cmd.ErrorLogger.Fatal(err)
+	}
+
+	lr := newLiveReload(siteDataPath, cmd.Quiet)
+	lr.baseURL = cmd.BaseURL
+	lr.pathPrefix = cmd.BasePath
+
+	// The files are recorded before the initial build, so that only later changes trigger a rebuild
+	for _, rootDir := range lr.rootDirs {
+		lr.traverseDirectory(rootDir)
+	}
+	lr.serverRunning = true
+	cmd.VanillaRender(siteDataPath)
+
+	server := lr.newServer(addr)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			lr.errorLogger.Fatal(err)
+		}
+	}()
 
+	if !cmd.Quiet {
+		lr.printAddress(addr)
+		if !cmd.NoReload {
+			fmt.Println("Live Reload is active")
+		}
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 	for {
-		for _, rootDir := range lr.rootDirs {
-			if lr.traverseDirectory(rootDir) {
-				cmd.VanillaRender(lr.siteDataPath)
-				reloadPageBool.CompareAndSwap(false, true)
+		select {
+		case <-ctx.Done():
+			cmd.InfoLogger.Println("Shutting down the server")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				lr.errorLogger.Println(err)
+			}
+			lr.hub.close()
+			return
+		case <-ticker.C:
+			if cmd.NoReload {
+				continue
+			}
+			var changed []string
+			for _, rootDir := range lr.rootDirs {
+				changed = append(changed, lr.traverseDirectory(rootDir)...)
+			}
+			if len(changed) == 0 {
+				continue
+			}
+			if err := cmd.rebuild(ctx, lr.siteDataPath); err != nil {
+				cmd.ErrorLogger.Println("Rebuild failed, serving the last successful build:", err)
+				continue
 			}
+
+			lr.hub.broadcast(lr.reloadMessage(changed))
 		}
-		if !lr.serverRunning {
-			lr.serverRunning = true
+	}
+}
+
+// RebuildCommand is the hidden subcommand rendering a site being served, run by the serve loop in a child process
+const RebuildCommand = "rebuild"
+
+/*
+rebuild renders a site being served again in a child process, so that a build failing on an invalid config, layout or
+page is reported without stopping the server
+
+The site is rendered from a copy in its build directory, and the rendered site is swapped in only once the build succeeds,
+so that the server keeps serving the complete previous build while rendering and after a failed build
+*/
+func (cmd *Cmd) rebuild(ctx context.Context, siteDataPath string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	buildPath := siteDataPath + buildDir + "/"
+	if err := os.RemoveAll(buildPath); err != nil {
+		return err
+	}
+	defer os.RemoveAll(buildPath)
+	if err := copySiteSources(siteDataPath, buildPath); err != nil {
+		return err
+	}
+
+	build := exec.CommandContext(ctx, executable, cmd.rebuildArgs(buildPath)...)
+	build.Stdout = os.Stdout
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		return err
+	}
+	return swapRendered(siteDataPath, buildPath+"rendered")
+}
+
+const (
+	// buildDir is the directory of the site a copy of its sources is rendered in during a rebuild
+	buildDir = ".rendered-build"
+	// lastBuildDir is the directory of the site the previous build is moved to while the new build is swapped in
+	lastBuildDir = ".rendered-last"
+)
+
+// copySiteSources copies the files of a site to buildPath along with their modification times, leaving out its rendered site
+func copySiteSources(siteDataPath string, buildPath string) error {
+	sitePath := filepath.Clean(siteDataPath)
+	return filepath.WalkDir(sitePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(sitePath, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if relPath == "rendered" || relPath == buildDir || relPath == lastBuildDir {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(buildPath, relPath), 0750)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
 		}
-		time.Sleep(time.Second)
+		destPath := filepath.Join(buildPath, relPath)
+		if err := os.WriteFile(destPath, content, info.Mode().Perm()); err != nil {
+			return err
+		}
+		return os.Chtimes(destPath, info.ModTime(), info.ModTime())
+	})
+}
+
+// swapRendered replaces the rendered site with the one at newRenderedPath, renaming both so that no partial build is served
+func swapRendered(siteDataPath string, newRenderedPath string) error {
+	renderedPath := siteDataPath + "rendered"
+	lastBuildPath := siteDataPath + lastBuildDir
+	if err := os.RemoveAll(lastBuildPath); err != nil {
+		return err
+	}
+	if err := os.Rename(renderedPath, lastBuildPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(newRenderedPath, renderedPath); err != nil {
+		// The previous build is restored so that the site is still served
+		if restoreErr := os.Rename(lastBuildPath, renderedPath); restoreErr != nil {
+			return errors.Join(err, restoreErr)
+		}
+		return err
+	}
+	return os.RemoveAll(lastBuildPath)
+}
+
+// rebuildArgs returns the arguments of the rebuild subcommand rendering the site with the options of the cmd
+func (cmd *Cmd) rebuildArgs(siteDataPath string) []string {
+	args := []string{RebuildCommand, siteDataPath}
+	if cmd.RenderDrafts {
+		args = append(args, "--draft")
+	}
+	if cmd.RenderExpired {
+		args = append(args, "--expired")
+	}
+	if cmd.StrictConfig {
+		args = append(args, "--strict-config")
 	}
+	if cmd.Quiet {
+		args = append(args, "--quiet")
+	}
+	if cmd.Jobs > 0 {
+		args = append(args, "--jobs", strconv.Itoa(cmd.Jobs))
+	}
+	if len(cmd.OnlyTypes) > 0 {
+		args = append(args, "--only", strings.Join(cmd.OnlyTypes, ","))
+	}
+	if len(cmd.SkipTypes) > 0 {
+		args = append(args, "--skip", strings.Join(cmd.SkipTypes, ","))
+	}
+	if cmd.BaseURL != "" {
+		args = append(args, "--base-url", cmd.BaseURL)
+	}
+	if cmd.BasePath != "" {
+		args = append(args, "--base-path", cmd.BasePath)
+	}
+	return args
 }
 
-func (lr *liveReload) traverseDirectory(rootDir string) bool {
-	filesChanged := false
+/*
+traverseDirectory returns the monitored files of a directory that were added, changed or removed since the last traversal
+The markdown files of the site are monitored along with every file of its layout/ and static/ directories, the rendered site is not
+*/
+func (lr *liveReload) traverseDirectory(rootDir string) []string {
+	var changed []string
+	seen := make(map[string]bool, len(lr.fileTimes))
+	watchedDirs := []string{
+		filepath.Clean(lr.siteDataPath+"layout") + string(filepath.Separator),
+		filepath.Clean(lr.siteDataPath+"static") + string(filepath.Separator),
+	}
+
 	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && lr.hasValidExtension(path) {
+		if info.IsDir() {
+			if path == filepath.Clean(lr.siteDataPath+"rendered") || path == filepath.Clean(lr.siteDataPath+buildDir) ||
+				path == filepath.Clean(lr.siteDataPath+lastBuildDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if lr.hasValidExtension(path) || strings.HasPrefix(path, watchedDirs[0]) || strings.HasPrefix(path, watchedDirs[1]) {
+			seen[path] = true
 			if lr.checkFile(path, info.ModTime()) {
-				filesChanged = true
-				return nil
+				changed = append(changed, path)
 			}
 		}
 		return nil
 	})
 	if err != nil {
-		lr.errorLogger.Fatal(err)
+		// Files removed while the directory is traversed are found on the next traversal
+		lr.errorLogger.Println(err)
+		return nil
+	}
+
+	for path := range lr.fileTimes {
+		if !seen[path] && strings.HasPrefix(path, filepath.Clean(rootDir)+string(filepath.Separator)) {
+			delete(lr.fileTimes, path)
+			if lr.serverRunning && !lr.quiet {
+				fmt.Println("The following file was removed: ", path)
+			}
+			changed = append(changed, path)
+		}
 	}
-	return filesChanged
+	return changed
+}
+
+// reloadMessage returns the message notifying the open pages of a rebuild for the changed files
+func (lr *liveReload) reloadMessage(changed []string) string {
+	if lr.onlyStylesheets(changed) {
+		return reloadCSSMessage
+	}
+	return reloadPageMessage
+}
+
+/*
+onlyStylesheets reports whether the changed files are all stylesheets, which the open pages reload without reloading the page
+Stylesheets inlined into the pages with the inlineCSS config are only updated by reloading the page
+*/
+func (lr *liveReload) onlyStylesheets(changed []string) bool {
+	config := lr.layoutConfig()
+
+	sitePath := filepath.ToSlash(filepath.Clean(lr.siteDataPath)) + "/"
+	for _, path := range changed {
+		if filepath.Ext(path) != ".css" {
+			return false
+		}
+		relPath := strings.TrimPrefix(filepath.ToSlash(path), sitePath)
+		for _, stylesheet := range config.InlineCSS {
+			if strings.TrimPrefix(stylesheet, "/") == relPath {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 func (lr *liveReload) hasValidExtension(path string) bool {
@@ -92,7 +359,7 @@ func (lr *liveReload) checkFile(path string, modTime time.Time) bool {
 	prevModTime, ok := lr.fileTimes[path]
 	if !ok || !modTime.Equal(prevModTime) {
 		lr.fileTimes[path] = modTime
-		if lr.serverRunning {
+		if lr.serverRunning && !lr.quiet {
 			fmt.Println("The following file has changed: ", path)
 			print("-----------------------------\n")
 		}
@@ -101,36 +368,69 @@ func (lr *liveReload) checkFile(path string, modTime time.Time) bool {
 	return false
 }
 
-func (lr *liveReload) startServer(addr string) {
-	fmt.Print("Serving content at: http://localhost:", addr, "\n")
-	fmt.Print("Profile data can be viewed at: http://localhost:", addr, "/debug/pprof", "\n")
-	http.Handle("/", http.FileServer(http.Dir(lr.siteDataPath+"./rendered")))
-	http.HandleFunc("/events", eventsHandler)
-	err := http.ListenAndServe(":"+addr, nil)
-	if err != nil {
-		lr.errorLogger.Fatal(err)
-	}
+// newServer returns the server of the rendered site, the reload events and the profile data
+func (lr *liveReload) newServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	config := lr.layoutConfig()
+	mux.Handle("/", newSiteFileServer(lr.siteDataPath+"rendered", lr.siteDataPath+"public", config.BasePath(), config.PageURLStyle()))
+	mux.Handle(liveReloadPath, lr.hub.handler())
+	mux.Handle("/debug/pprof/", http.DefaultServeMux)
+	return &http.Server{Addr: addr, Handler: mux}
 }
 
-func eventsHandler(w http.ResponseWriter, r *http.Request) {
-	// Set CORS headers to allow all origins.
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Expose-Headers", "Content-Type")
+// printAddress prints the URLs of the served site and of its profile data
+func (lr *liveReload) printAddress(addr string) {
+	siteURL := "http://" + addr
+	fmt.Print("Serving content at: ", siteURL, lr.basePath(), "/\n")
+	fmt.Print("Profile data can be viewed at: ", siteURL, "/debug/pprof", "\n")
+}
 
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
+/*
+NormalizeAddr returns the host:port address to serve the site at from a port (8000), a port prefixed with a colon (:8000)
+or a host and port (localhost:8000), the host defaults to localhost
+*/
+func NormalizeAddr(addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return "", errors.New("the address is empty, expected a port such as 8000 or a host and port such as localhost:8000")
+	}
 
-	if !reloadPageBool.Load() {
-		return
+	host, port := "", addr
+	if strings.Contains(addr, ":") {
+		var err error
+		host, port, err = net.SplitHostPort(addr)
+		if err != nil {
+			return "", fmt.Errorf("invalid address %q, expected a port such as 8000 or a host and port such as localhost:8000", addr)
+		}
 	}
 
-	event := "event:\ndata:\n\n"
-	_, err := w.Write([]byte(event))
-	if err != nil {
-		log.Fatal(err)
+	portNumber, err := strconv.Atoi(port)
+	if err != nil || portNumber < 1 || portNumber > 65535 {
+		return "", fmt.Errorf("invalid port %q in the address %q, expected a number from 1 to 65535", port, addr)
+	}
+	if host == "" {
+		host = "localhost"
 	}
-	w.(http.Flusher).Flush()
+	return net.JoinHostPort(host, strconv.Itoa(portNumber)), nil
+}
+
+// basePath returns the base path of the site, under which the rendered site is served
+func (lr *liveReload) basePath() string {
+	return lr.layoutConfig().BasePath()
+}
 
-	reloadPageBool.CompareAndSwap(true, false)
+// layoutConfig returns the config of the site as it is built, with the base URL and base path of the serve command
+func (lr *liveReload) layoutConfig() parser.LayoutConfig {
+	p := parser.Parser{
+		CollectionsSubPageLayouts: make(map[template.URL]string),
+		ErrorLogger:               lr.errorLogger,
+	}
+	p.ParseConfig(lr.siteDataPath + "layout/config.json")
+	if lr.baseURL != "" {
+		p.LayoutConfig.BaseURL = strings.TrimSuffix(lr.baseURL, "/")
+	}
+	if lr.pathPrefix != "" {
+		p.LayoutConfig.PathPrefix = lr.pathPrefix
+	}
+	return p.LayoutConfig
 }
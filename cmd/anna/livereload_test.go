@@ -0,0 +1,208 @@
+package anna
+
+import (
+	"context"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// The rebuilds of the tests run the test binary as the child process, which stands in for the rebuild subcommand
+const rebuildHelperEnv = "ANNA_TEST_REBUILD"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(rebuildHelperEnv) {
+	case "":
+		os.Exit(m.Run())
+	case "fail":
+		os.Exit(1)
+	case "succeed":
+		// The arguments are the rebuild subcommand and the site to render, as passed by rebuildArgs
+		renderedPath := os.Args[2] + "rendered/"
+		if err := os.MkdirAll(renderedPath, 0750); err != nil {
+			os.Exit(1)
+		}
+		content, err := os.ReadFile(os.Args[2] + "content/index.md")
+		if err != nil {
+			os.Exit(1)
+		}
+		if err := os.WriteFile(renderedPath+"index.html", content, 0666); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+}
+
+func TestRebuildArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  Cmd
+		want []string
+	}{
+		{
+			name: "default options",
+			want: []string{RebuildCommand, "site/"},
+		},
+		{
+			name: "every option",
+			cmd: Cmd{
+				RenderDrafts:  true,
+				RenderExpired: true,
+				StrictConfig:  true,
+				Quiet:         true,
+				Jobs:          4,
+				OnlyTypes:     []string{"post", "page"},
+				SkipTypes:     []string{"note"},
+				BaseURL:       "https://example.org/blog",
+				BasePath:      "/preview",
+			},
+			want: []string{
+				RebuildCommand, "site/", "--draft", "--expired", "--strict-config", "--quiet", "--jobs", "4",
+				"--only", "post,page", "--skip", "note", "--base-url", "https://example.org/blog", "--base-path", "/preview",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cmd.rebuildArgs("site/"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	tests := []struct {
+		name    string
+		helper  string
+		wantErr bool
+		// Content of the rendered index.html once the rebuild completes
+		want string
+	}{
+		{name: "the previous build is kept when the build fails", helper: "fail", wantErr: true, want: "previous build"},
+		{name: "the new build is swapped in when the build succeeds", helper: "succeed", want: "new build"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(rebuildHelperEnv, tt.helper)
+
+			siteDataPath := t.TempDir() + "/"
+			writeTestFile(t, siteDataPath+"content/index.md", "new build")
+			writeTestFile(t, siteDataPath+"rendered/index.html", "previous build")
+
+			cmd := Cmd{ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", 0)}
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			err := cmd.rebuild(ctx, siteDataPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want an error %t", err, tt.wantErr)
+			}
+
+			got, err := os.ReadFile(siteDataPath + "rendered/index.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got rendered page %q, want %q", got, tt.want)
+			}
+			for _, dir := range []string{buildDir, lastBuildDir} {
+				if _, err := os.Stat(siteDataPath + dir); !os.IsNotExist(err) {
+					t.Errorf("the %s directory was not removed after the rebuild", dir)
+				}
+			}
+		})
+	}
+}
+
+func TestReloadMessage(t *testing.T) {
+	siteDataPath := t.TempDir() + "/"
+	writeTestFile(t, siteDataPath+"layout/config.json", `{"inlineCSS": ["/static/critical.css"]}`)
+	lr := newLiveReload(siteDataPath, true)
+
+	tests := []struct {
+		name    string
+		changed []string
+		want    string
+	}{
+		{"stylesheets are reloaded in place", []string{siteDataPath + "static/style.css", siteDataPath + "static/print.css"}, reloadCSSMessage},
+		{"inlined stylesheets reload the page", []string{siteDataPath + "static/style.css", siteDataPath + "static/critical.css"}, reloadPageMessage},
+		{"content reloads the page", []string{siteDataPath + "static/style.css", siteDataPath + "content/index.md"}, reloadPageMessage},
+		{"layouts reload the page", []string{siteDataPath + "layout/page.html"}, reloadPageMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lr.reloadMessage(tt.changed); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadHub(t *testing.T) {
+	siteDataPath := t.TempDir() + "/"
+	writeTestFile(t, siteDataPath+"layout/config.json", `{}`)
+	lr := newLiveReload(siteDataPath, true)
+	server := httptest.NewServer(lr.newServer("").Handler)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + liveReloadPath
+	conn, err := websocket.Dial(wsURL, "", server.URL)
+	if err != nil {
+		t.Fatalf("handshake with the live reload endpoint: %v", err)
+	}
+	defer conn.Close()
+
+	// The page is registered once the handler runs, after the handshake
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lr.hub.mu.Lock()
+		connected := len(lr.hub.clients)
+		lr.hub.mu.Unlock()
+		if connected == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d connected pages, want 1", connected)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, want := range []string{reloadCSSMessage, reloadPageMessage} {
+		lr.hub.broadcast(want)
+		if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if err := websocket.Message.Receive(conn, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("got message %q, want %q", got, want)
+		}
+	}
+
+	// Closing the hub disconnects the page
+	lr.hub.close()
+	var message string
+	if err := websocket.Message.Receive(conn, &message); err == nil {
+		t.Errorf("got message %q after the hub was closed, want the connection closed", message)
+	}
+}
+
+// writeTestFile writes a file of a test site, creating its directory
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,9 +1,15 @@
 package anna
 
 import (
+	"fmt"
 	"log"
+	"os"
 	"runtime"
+	"text/tabwriter"
 	"time"
+
+	"github.com/anna-ssg/anna/v3/pkg/engine"
+	"github.com/anna-ssg/anna/v3/pkg/parser"
 )
 
 func (cmd *Cmd) PrintStats(elapsedTime time.Duration) {
@@ -30,3 +36,30 @@ func (cmd *Cmd) PrintStats(elapsedTime time.Duration) {
 	function := runtime.FuncForPC(pc)
 	log.Printf("Function with Highest CPU Usage: %s", function.Name())
 }
+
+// PrintSlowestPages prints the pages that took the longest to render along with the size of their output
+func (cmd *Cmd) PrintSlowestPages(pages []engine.PageRenderTime) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "PAGE\tRENDER TIME\tSIZE")
+	for _, page := range pages {
+		fmt.Fprintf(writer, "%s\t%s\t%.1f KB\n", page.PagePath, page.Duration.Round(time.Microsecond), float64(page.Size)/1024)
+	}
+
+	if err := writer.Flush(); err != nil {
+		cmd.ErrorLogger.Fatal(err)
+	}
+}
+
+// PrintOrphanNotes prints the notes that neither link to nor are linked from any other page
+func (cmd *Cmd) PrintOrphanNotes(notes []parser.TemplateData) {
+	if len(notes) == 0 {
+		cmd.InfoLogger.Println("No orphan notes")
+		return
+	}
+
+	fmt.Printf("%d orphan note(s):\n", len(notes))
+	for _, note := range notes {
+		fmt.Printf("  %s (%s)\n", note.CompleteURL, note.Frontmatter.Title)
+	}
+}
@@ -0,0 +1,70 @@
+package anna
+
+import (
+	"io"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// Path of the WebSocket endpoint the live reload script of the pages connects to
+const liveReloadPath = "/_anna/livereload"
+
+// Messages sent to the open pages after a rebuild
+const (
+	// Reloads the page
+	reloadPageMessage = "reload"
+	// Reloads the stylesheets of the page without reloading it
+	reloadCSSMessage = "css"
+)
+
+// reloadHub tracks the pages connected to the live reload endpoint and notifies them of rebuilds
+type reloadHub struct {
+	mu      sync.Mutex
+	clients map[*websocket.Conn]bool
+}
+
+func newReloadHub() *reloadHub {
+	return &reloadHub{clients: make(map[*websocket.Conn]bool)}
+}
+
+// handler returns the handler of the live reload endpoint, which holds the connection of a page open until it is closed
+func (h *reloadHub) handler() websocket.Handler {
+	return func(conn *websocket.Conn) {
+		h.mu.Lock()
+		h.clients[conn] = true
+		h.mu.Unlock()
+
+		// Pages send no messages, reading only waits for the connection to be closed
+		_, _ = io.Copy(io.Discard, conn)
+
+		h.mu.Lock()
+		delete(h.clients, conn)
+		h.mu.Unlock()
+		_ = conn.Close()
+	}
+}
+
+// broadcast sends a message to every connected page, pages that cannot be reached are disconnected
+func (h *reloadHub) broadcast(message string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for conn := range h.clients {
+		if err := websocket.Message.Send(conn, message); err != nil {
+			delete(h.clients, conn)
+			_ = conn.Close()
+		}
+	}
+}
+
+// close disconnects every page, as the connections are not closed by the shutdown of the server
+func (h *reloadHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for conn := range h.clients {
+		delete(h.clients, conn)
+		_ = conn.Close()
+	}
+}
@@ -0,0 +1,97 @@
+package anna
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anna-ssg/anna/v3/pkg/engine"
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
+)
+
+/*
+RenderFile renders a single markdown file with the layouts and config of the site at siteDirPath and writes the HTML to out
+No other content is parsed, so site-wide data such as tags, collections and posts only contain the rendered file
+The file is rendered even if it is a draft
+*/
+func (cmd *Cmd) RenderFile(siteDirPath string, filePath string, out io.Writer) {
+	p := cmd.newParser(siteDirPath)
+	p.RenderDrafts = true
+	p.RenderExpired = true
+
+	p.ParseConfig(siteDirPath + "layout/config.json")
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		cmd.ErrorLogger.Fatal(err)
+	}
+
+	frontmatter, body, markdownContent, parseSuccess := p.ParseMarkdownContent(string(content), filePath)
+	if !parseSuccess {
+		cmd.ErrorLogger.Fatal("Unable to parse file: ", filePath)
+	}
+
+	// Files outside the content directories are rendered as if they were placed at the root of the first one
+	roots := p.ContentRoots()
+	contentDirPath, relPath := roots[0].Path, filepath.Base(filePath)
+	for _, root := range roots {
+		if rootRelPath, err := filepath.Rel(root.Path, filePath); err == nil && !strings.HasPrefix(rootRelPath, "..") {
+			contentDirPath, relPath = root.Path, rootRelPath
+			break
+		}
+	}
+	p.AddFile(contentDirPath, filepath.ToSlash(relPath), frontmatter, markdownContent, body)
+
+	templ := p.ParseLayoutFiles()
+
+	e := engine.Engine{
+		SiteDataPath: siteDirPath,
+		ErrorLogger:  cmd.ErrorLogger,
+		Warnings:     p.Warnings,
+	}
+	e.DeepDataMerge.Templates = p.Templates
+	e.DeepDataMerge.Posts = p.Posts
+	e.DeepDataMerge.TagsMap = p.TagsMap
+	e.DeepDataMerge.CollectionsMap = p.CollectionsMap
+	e.DeepDataMerge.CollectionsSubPageLayouts = p.CollectionsSubPageLayouts
+	e.DeepDataMerge.DisplayNames = p.DisplayNames
+	e.DeepDataMerge.TaxonomyMap = p.TaxonomyMap
+	e.DeepDataMerge.LayoutConfig = p.LayoutConfig
+	e.DeepDataMerge.Env = engine.EnvProduction
+	e.DeepDataMerge.Version = cmd.Version
+	e.DeepDataMerge.BuildTime, err = helpers.BuildTime()
+	if err != nil {
+		cmd.ErrorLogger.Fatal("Invalid SOURCE_DATE_EPOCH: ", err)
+	}
+	e.DeepDataMerge.LayoutConfig.Copyright = p.LayoutConfig.CopyrightNotice(e.DeepDataMerge.BuildTime)
+
+	for pageURL, page := range p.Templates {
+		_, err = out.Write(e.ExecutePage(pageURL, templ, page.Frontmatter.LayoutName()))
+		if err != nil {
+			cmd.ErrorLogger.Fatal(err)
+		}
+	}
+}
+
+// RenderFileManager renders a single file to outFilePath, or to stdout if it is empty
+func (cmd *Cmd) RenderFileManager(filePath string, outFilePath string) {
+	siteDirPath := cmd.siteDir()
+	if outFilePath == "" {
+		cmd.RenderFile(siteDirPath, filePath, os.Stdout)
+		return
+	}
+
+	outFile, err := os.Create(outFilePath)
+	if err != nil {
+		cmd.ErrorLogger.Fatal(err)
+	}
+	defer func() {
+		err = outFile.Close()
+		if err != nil {
+			cmd.ErrorLogger.Fatal(err)
+		}
+	}()
+
+	cmd.RenderFile(siteDirPath, filePath, outFile)
+}
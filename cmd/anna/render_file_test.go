@@ -0,0 +1,80 @@
+package anna
+
+import (
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRenderFileManager(t *testing.T) {
+	siteDataPath := t.TempDir() + "/"
+	writeTestFile(t, siteDataPath+"layout/config.json", `{"baseURL": "https://example.org", "siteTitle": "Test Site"}`)
+	writeTestFile(t, siteDataPath+"layout/page.html",
+		`{{define "page"}}{{.PageURL}}|{{.DeepDataMerge.LayoutConfig.SiteTitle}}|{{with index .DeepDataMerge.Templates .PageURL}}{{.Frontmatter.Title}}|{{.Body}}{{end}}{{end}}`)
+	writeTestFile(t, siteDataPath+"layout/partials/head.html", `{{define "head"}}{{end}}`)
+	writeTestFile(t, siteDataPath+"content/index.md", "---\ntitle: Home\n---\nWelcome home")
+	writeTestFile(t, siteDataPath+"content/posts/hello.md", "---\ntitle: Hello\n---\nHello world")
+	writeTestFile(t, siteDataPath+"content/posts/wip.md", "---\ntitle: Work in progress\ndraft: true\n---\nNot done yet")
+	// A file outside the content directory, such as one being drafted elsewhere
+	outsidePath := t.TempDir() + "/outside.md"
+	writeTestFile(t, outsidePath, "---\ntitle: Outside\n---\nFrom elsewhere")
+
+	tests := []struct {
+		name     string
+		siteDir  string
+		filePath string
+		want     []string
+	}{
+		{
+			name:     "index page of the content directory",
+			siteDir:  siteDataPath,
+			filePath: siteDataPath + "content/index.md",
+			want:     []string{"index.html|", "|Test Site|", "|Home|", "<p>Welcome home</p>"},
+		},
+		{
+			name:     "page in a subdirectory keeps its URL",
+			siteDir:  siteDataPath,
+			filePath: siteDataPath + "content/posts/hello.md",
+			want:     []string{"posts/hello.html|", "|Hello|", "<p>Hello world</p>"},
+		},
+		{
+			name:     "drafts are rendered",
+			siteDir:  siteDataPath,
+			filePath: siteDataPath + "content/posts/wip.md",
+			want:     []string{"posts/wip.html|", "|Work in progress|", "<p>Not done yet</p>"},
+		},
+		{
+			name:     "file outside the content directory is rendered at its root",
+			siteDir:  siteDataPath,
+			filePath: outsidePath,
+			want:     []string{"outside.html|", "|Outside|", "<p>From elsewhere</p>"},
+		},
+		{
+			name:     "site directory without a trailing slash",
+			siteDir:  strings.TrimSuffix(siteDataPath, "/"),
+			filePath: siteDataPath + "content/index.md",
+			want:     []string{"index.html|", "|Test Site|", "|Home|"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := Cmd{
+				RenderSpecificSite: tt.siteDir,
+				ErrorLogger:        log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			outFilePath := t.TempDir() + "/out.html"
+			cmd.RenderFileManager(tt.filePath, outFilePath)
+
+			got, err := os.ReadFile(outFilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("got output without %q\n%s", want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package anna
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+func init() {
+	// The system MIME tables differ across platforms, register the types
+	// emitted by anna so that local previews match production hosts
+	mimeTypes := map[string]string{
+		".html":        "text/html; charset=utf-8",
+		".css":         "text/css; charset=utf-8",
+		".js":          "text/javascript; charset=utf-8",
+		".xml":         "application/xml; charset=utf-8",
+		".xsl":         "application/xml; charset=utf-8",
+		".json":        "application/json; charset=utf-8",
+		".webmanifest": "application/manifest+json; charset=utf-8",
+		".txt":         "text/plain; charset=utf-8",
+		".svg":         "image/svg+xml",
+		".webp":        "image/webp",
+		".avif":        "image/avif",
+		".ico":         "image/x-icon",
+	}
+	for ext, mimeType := range mimeTypes {
+		_ = mime.AddExtensionType(ext, mimeType)
+	}
+}
+
+// siteFileServer serves the rendered site, resolving pretty URLs and
+// falling back to the generated 404.html for unknown paths
+// Sites hosted under a subpath are served under their base path, as in production
+type siteFileServer struct {
+	root     string
+	basePath string
+	// URL style of the pages, extensionless pages are served as HTML
+	urlStyle string
+	// Files copied verbatim from public/ are not pages, whatever the URL style
+	publicRoot string
+
+	etagsMu sync.Mutex
+	etags   map[string]fileETag
+}
+
+// fileETag stores the ETag of a file along with the modification time and size it was computed for
+type fileETag struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+func newSiteFileServer(root string, publicRoot string, basePath string, urlStyle string) *siteFileServer {
+	return &siteFileServer{root: root, publicRoot: publicRoot, basePath: basePath, urlStyle: urlStyle, etags: make(map[string]fileETag)}
+}
+
+func (fs *siteFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	urlPath := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") && urlPath != "/" {
+		urlPath += "/"
+	}
+
+	if fs.basePath != "" {
+		if urlPath == "/" {
+			http.Redirect(w, r, fs.basePath+"/", http.StatusFound)
+			return
+		}
+		// Pages outside the base path are not served, so that links missing the prefix fail as in production
+		if urlPath == fs.basePath {
+			urlPath = "/"
+		} else if rest, ok := strings.CutPrefix(urlPath, fs.basePath+"/"); ok {
+			urlPath = "/" + rest
+		} else {
+			fs.serveNotFound(w, r)
+			return
+		}
+	}
+
+	if filePath, ok := fs.resolve(urlPath); ok {
+		// Directories requested without a trailing slash are redirected like on production hosts,
+		// so that the relative links of the page resolve against the directory
+		if !strings.HasSuffix(urlPath, "/") && filepath.Base(filePath) == "index.html" && path.Base(urlPath) != "index.html" {
+			target := r.URL.Path + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		fs.serveFile(w, r, filePath, http.StatusOK)
+		return
+	}
+	fs.serveNotFound(w, r)
+}
+
+// serveNotFound serves the generated 404.html, or a plain not found response if the site has none
+func (fs *siteFileServer) serveNotFound(w http.ResponseWriter, r *http.Request) {
+	notFoundPath := filepath.Join(fs.root, "404.html")
+	if info, err := os.Stat(notFoundPath); err == nil && !info.IsDir() {
+		fs.serveFile(w, r, notFoundPath, http.StatusNotFound)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// resolve maps a request path to a file in the rendered directory
+// "/about/" resolves to "about/index.html" or "about.html"
+// "/about" resolves to "about", "about.html" or "about/index.html"
+func (fs *siteFileServer) resolve(urlPath string) (string, bool) {
+	relPath := filepath.FromSlash(strings.TrimPrefix(urlPath, "/"))
+
+	var candidates []string
+	if strings.HasSuffix(urlPath, "/") {
+		trimmed := strings.TrimSuffix(relPath, string(filepath.Separator))
+		candidates = append(candidates, filepath.Join(relPath, "index.html"))
+		if trimmed != "" {
+			candidates = append(candidates, trimmed+".html")
+		}
+	} else {
+		candidates = append(candidates, relPath, relPath+".html", filepath.Join(relPath, "index.html"))
+	}
+
+	for _, candidate := range candidates {
+		filePath := filepath.Join(fs.root, candidate)
+		info, err := os.Stat(filePath)
+		if err == nil && !info.IsDir() {
+			return filePath, true
+		}
+	}
+	return "", false
+}
+
+func (fs *siteFileServer) serveFile(w http.ResponseWriter, r *http.Request, filePath string, status int) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Pages rendered with the extensionless URL style are HTML, as on hosts configured for clean URLs
+	// Other files without an extension, such as CNAME or _redirects from public/, have their type sniffed
+	if fs.isExtensionlessPage(filePath) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+
+	if status == http.StatusOK {
+		etag, err := fs.etag(filePath, info, file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// http.ServeContent answers conditional requests with 304 Not Modified using the ETag
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+		return
+	}
+
+	// http.ServeContent always replies with 200, write the not-found page manually
+	if contentType := mime.TypeByExtension(filepath.Ext(filePath)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, file)
+	}
+}
+
+// isExtensionlessPage reports whether a file of the rendered directory is a page rendered with the extensionless URL style
+func (fs *siteFileServer) isExtensionlessPage(filePath string) bool {
+	if fs.urlStyle != parser.URLStyleExtensionless || filepath.Ext(filePath) != "" {
+		return false
+	}
+	if fs.publicRoot == "" {
+		return true
+	}
+	relPath, err := filepath.Rel(fs.root, filePath)
+	if err != nil {
+		return true
+	}
+	_, err = os.Stat(filepath.Join(fs.publicRoot, relPath))
+	return err != nil
+}
+
+/*
+etag returns the strong ETag of a file, a hash of its content computed on the first request and cached
+The hash is computed again once the modification time or size of the file changes, so files rewritten with
+the same content by a rebuild keep their ETag and are not downloaded again by the browser
+*/
+func (fs *siteFileServer) etag(filePath string, info os.FileInfo, file io.ReadSeeker) (string, error) {
+	fs.etagsMu.Lock()
+	cached, ok := fs.etags[filePath]
+	fs.etagsMu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.etag, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+
+	fs.etagsMu.Lock()
+	fs.etags[filePath] = fileETag{modTime: info.ModTime(), size: info.Size(), etag: etag}
+	fs.etagsMu.Unlock()
+	return etag, nil
+}
@@ -0,0 +1,162 @@
+package anna
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+// newTestSite writes the rendered files of a test site and returns its directory
+func newTestSite(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		writeTestFile(t, root+"/"+name, content)
+	}
+	return root
+}
+
+func TestSiteFileServer(t *testing.T) {
+	root := newTestSite(t, map[string]string{
+		"index.html":           "home",
+		"about/index.html":     "about",
+		"posts/hello.html":     "hello",
+		"notes/first":          "first note",
+		"CNAME":                "example.org",
+		"static/style.css":     "body {}",
+		"static/app.js":        "let a",
+		"feed.xml":             "<feed/>",
+		"manifest.webmanifest": "{}",
+		"404.html":             "not found",
+	})
+	publicRoot := newTestSite(t, map[string]string{"CNAME": "example.org"})
+
+	tests := []struct {
+		name            string
+		basePath        string
+		urlStyle        string
+		path            string
+		wantStatus      int
+		wantBody        string
+		wantContentType string
+		wantLocation    string
+	}{
+		{name: "index page", path: "/", wantStatus: http.StatusOK, wantBody: "home", wantContentType: "text/html; charset=utf-8"},
+		{name: "pretty URL of a directory", path: "/about/", wantStatus: http.StatusOK, wantBody: "about", wantContentType: "text/html; charset=utf-8"},
+		{name: "directory without a trailing slash redirects", path: "/about", wantStatus: http.StatusMovedPermanently, wantLocation: "/about/"},
+		{name: "redirect keeps the query string", path: "/about?ref=home", wantStatus: http.StatusMovedPermanently, wantLocation: "/about/?ref=home"},
+		{name: "index file of a directory", path: "/about/index.html", wantStatus: http.StatusOK, wantBody: "about"},
+		{name: "pretty URL of an HTML file", path: "/posts/hello", wantStatus: http.StatusOK, wantBody: "hello", wantContentType: "text/html; charset=utf-8"},
+		{name: "extensionless page", urlStyle: parser.URLStyleExtensionless, path: "/notes/first", wantStatus: http.StatusOK, wantBody: "first note", wantContentType: "text/html; charset=utf-8"},
+		{name: "public file without an extension is sniffed", urlStyle: parser.URLStyleExtensionless, path: "/CNAME", wantStatus: http.StatusOK, wantBody: "example.org", wantContentType: "text/plain; charset=utf-8"},
+		{name: "file without an extension is sniffed with other URL styles", path: "/CNAME", wantStatus: http.StatusOK, wantBody: "example.org", wantContentType: "text/plain; charset=utf-8"},
+		{name: "stylesheet", path: "/static/style.css", wantStatus: http.StatusOK, wantBody: "body {}", wantContentType: "text/css; charset=utf-8"},
+		{name: "script", path: "/static/app.js", wantStatus: http.StatusOK, wantBody: "let a", wantContentType: "text/javascript; charset=utf-8"},
+		{name: "feed", path: "/feed.xml", wantStatus: http.StatusOK, wantBody: "<feed/>", wantContentType: "application/xml; charset=utf-8"},
+		{name: "web manifest", path: "/manifest.webmanifest", wantStatus: http.StatusOK, wantBody: "{}", wantContentType: "application/manifest+json; charset=utf-8"},
+		{name: "unknown path falls back to the 404 page", path: "/missing/", wantStatus: http.StatusNotFound, wantBody: "not found", wantContentType: "text/html; charset=utf-8"},
+		{name: "path escaping the site falls back to the 404 page", path: "/../../etc/passwd", wantStatus: http.StatusNotFound, wantBody: "not found"},
+		{name: "root redirects to the base path", basePath: "/blog", path: "/", wantStatus: http.StatusFound, wantLocation: "/blog/"},
+		{name: "base path serves the index page", basePath: "/blog", path: "/blog/", wantStatus: http.StatusOK, wantBody: "home"},
+		{name: "page under the base path", basePath: "/blog", path: "/blog/about/", wantStatus: http.StatusOK, wantBody: "about"},
+		{name: "directory under the base path redirects", basePath: "/blog", path: "/blog/about", wantStatus: http.StatusMovedPermanently, wantLocation: "/blog/about/"},
+		{name: "page outside the base path falls back to the 404 page", basePath: "/blog", path: "/elsewhere/", wantStatus: http.StatusNotFound, wantBody: "not found"},
+		{name: "page requested without the base path falls back to the 404 page", basePath: "/blog", path: "/about/", wantStatus: http.StatusNotFound, wantBody: "not found"},
+		{name: "asset requested without the base path falls back to the 404 page", basePath: "/blog", path: "/static/style.css", wantStatus: http.StatusNotFound, wantBody: "not found"},
+		{name: "path sharing the prefix of the base path falls back to the 404 page", basePath: "/blog", path: "/blogroll", wantStatus: http.StatusNotFound, wantBody: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			newSiteFileServer(root, publicRoot, tt.basePath, tt.urlStyle).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && recorder.Body.String() != tt.wantBody {
+				t.Errorf("got body %q, want %q", recorder.Body.String(), tt.wantBody)
+			}
+			if got := recorder.Header().Get("Content-Type"); tt.wantContentType != "" && got != tt.wantContentType {
+				t.Errorf("got Content-Type %q, want %q", got, tt.wantContentType)
+			}
+			if got := recorder.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("got Location %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestSiteFileServerWithout404Page(t *testing.T) {
+	root := newTestSite(t, map[string]string{"index.html": "home"})
+
+	recorder := httptest.NewRecorder()
+	newSiteFileServer(root, "", "", "").ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestSiteFileServerETag(t *testing.T) {
+	root := newTestSite(t, map[string]string{"index.html": "home", "404.html": "not found"})
+	fs := newSiteFileServer(root, "", "", "")
+	get := func(path string, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			request.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		recorder := httptest.NewRecorder()
+		fs.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	first := get("/", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("got status %d and ETag %q, want 200 with an ETag", first.Code, etag)
+	}
+	if again := get("/", "").Header().Get("ETag"); again != etag {
+		t.Errorf("got ETag %q on the second request, want %q", again, etag)
+	}
+
+	// The browser revalidates with the ETag and gets no body back
+	notModified := get("/", etag)
+	if notModified.Code != http.StatusNotModified {
+		t.Errorf("got status %d with a matching If-None-Match, want %d", notModified.Code, http.StatusNotModified)
+	}
+	if notModified.Body.Len() != 0 {
+		t.Errorf("got body %q with a 304, want none", notModified.Body.String())
+	}
+
+	// A rebuild rewriting the file with the same content keeps its ETag
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(root+"/index.html", later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := get("/", etag); got.Code != http.StatusNotModified {
+		t.Errorf("got status %d after rewriting the same content, want %d", got.Code, http.StatusNotModified)
+	}
+
+	// A rebuild changing the file invalidates the cached ETag
+	writeTestFile(t, root+"/index.html", "home, edited")
+	later = later.Add(time.Minute)
+	if err := os.Chtimes(root+"/index.html", later, later); err != nil {
+		t.Fatal(err)
+	}
+	changed := get("/", etag)
+	if changed.Code != http.StatusOK || changed.Body.String() != "home, edited" {
+		t.Errorf("got status %d and body %q after the file changed, want 200 with the new content", changed.Code, changed.Body.String())
+	}
+	if newETag := changed.Header().Get("ETag"); newETag == "" || newETag == etag {
+		t.Errorf("got ETag %q after the file changed, want a new ETag other than %q", newETag, etag)
+	}
+
+	// The 404 page is not cached by the browser
+	if missing := get("/missing", ""); missing.Header().Get("ETag") != "" {
+		t.Errorf("got ETag %q for the 404 page, want none", missing.Header().Get("ETag"))
+	}
+}
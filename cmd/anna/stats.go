@@ -0,0 +1,194 @@
+package anna
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+// Number of tags listed in the most used tags
+const topTagsCount = 10
+
+// SiteStats stores aggregate statistics about the content of a site
+type SiteStats struct {
+	// Number of pages of each type, such as post, page and note
+	Types              map[string]int `json:"types"`
+	Words              int            `json:"words"`
+	AverageReadingTime float64        `json:"averageReadingTimeMinutes"`
+	TopTags            []TagCount     `json:"topTags"`
+	PostsPerMonth      []MonthCount   `json:"postsPerMonth"`
+	InternalLinks      int            `json:"internalLinks"`
+	ExternalLinks      int            `json:"externalLinks"`
+	// Images without an alt attribute, as "<page>: <src>"
+	ImagesWithoutAlt []string `json:"imagesWithoutAlt"`
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type MonthCount struct {
+	// Month in the YYYY-MM format, posts without a date are counted under "undated"
+	Month string `json:"month"`
+	Count int    `json:"count"`
+}
+
+// StatsManager parses the content of the site without rendering it and prints its statistics as a table, or as JSON
+func (cmd *Cmd) StatsManager(asJSON bool) {
+	siteDirPath := cmd.siteDir()
+	p := cmd.newParser(siteDirPath)
+	p.ParseConfig(siteDirPath + "layout/config.json")
+	p.ParseContentDirs()
+
+	stats := CollectStats(p.Templates)
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(stats); err != nil {
+			cmd.ErrorLogger.Fatal(err)
+		}
+		return
+	}
+
+	if err := stats.WriteTable(os.Stdout); err != nil {
+		cmd.ErrorLogger.Fatal(err)
+	}
+}
+
+// CollectStats computes the statistics of the given pages
+func CollectStats(templates map[template.URL]parser.TemplateData) SiteStats {
+	stats := SiteStats{
+		Types:            make(map[string]int),
+		TopTags:          []TagCount{},
+		PostsPerMonth:    []MonthCount{},
+		ImagesWithoutAlt: []string{},
+	}
+	tagCounts := make(map[string]int)
+	monthCounts := make(map[string]int)
+
+	pageURLs := make([]string, 0, len(templates))
+	for pageURL := range templates {
+		pageURLs = append(pageURLs, string(pageURL))
+	}
+	sort.Strings(pageURLs)
+
+	for _, pageURL := range pageURLs {
+		page := templates[template.URL(pageURL)]
+
+		stats.Types[page.Frontmatter.Type]++
+		stats.Words += helpers.WordCount(string(page.Body))
+
+		for _, tag := range page.Frontmatter.Tags {
+			tagCounts[tag]++
+		}
+
+		if page.Frontmatter.Type == "post" {
+			month := "undated"
+			if page.Frontmatter.Date != "" {
+				month = time.Unix(page.Date, 0).UTC().Format("2006-01")
+			}
+			monthCounts[month]++
+		}
+
+		document, err := goquery.NewDocumentFromReader(strings.NewReader(string(page.Body)))
+		if err != nil {
+			continue
+		}
+		document.Find("a[href]").Each(func(_ int, link *goquery.Selection) {
+			href, _ := link.Attr("href")
+			switch {
+			case href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "mailto:") || strings.HasPrefix(href, "tel:"):
+			case strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") || strings.HasPrefix(href, "//"):
+				stats.ExternalLinks++
+			default:
+				stats.InternalLinks++
+			}
+		})
+		// Decorative images are marked with an empty alt="", as with the image-alt rule of anna -l
+		document.Find("img").Each(func(_ int, image *goquery.Selection) {
+			if _, ok := image.Attr("alt"); !ok {
+				src, _ := image.Attr("src")
+				stats.ImagesWithoutAlt = append(stats.ImagesWithoutAlt, pageURL+": "+src)
+			}
+		})
+	}
+
+	if len(templates) > 0 {
+		stats.AverageReadingTime = float64(stats.Words) / float64(len(templates)) / helpers.WordsPerMinute
+	}
+
+	for tag, count := range tagCounts {
+		stats.TopTags = append(stats.TopTags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(stats.TopTags, func(i, j int) bool {
+		if stats.TopTags[i].Count != stats.TopTags[j].Count {
+			return stats.TopTags[i].Count > stats.TopTags[j].Count
+		}
+		return stats.TopTags[i].Tag < stats.TopTags[j].Tag
+	})
+	if len(stats.TopTags) > topTagsCount {
+		stats.TopTags = stats.TopTags[:topTagsCount]
+	}
+
+	// Months sort chronologically in the YYYY-MM format, with undated posts last
+	for month, count := range monthCounts {
+		stats.PostsPerMonth = append(stats.PostsPerMonth, MonthCount{Month: month, Count: count})
+	}
+	sort.Slice(stats.PostsPerMonth, func(i, j int) bool {
+		return stats.PostsPerMonth[i].Month < stats.PostsPerMonth[j].Month
+	})
+
+	return stats
+}
+
+// WriteTable writes the statistics as aligned tables
+func (stats SiteStats) WriteTable(out io.Writer) error {
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	types := make([]string, 0, len(stats.Types))
+	for pageType := range stats.Types {
+		types = append(types, pageType)
+	}
+	sort.Strings(types)
+
+	fmt.Fprintln(writer, "CONTENT\tCOUNT")
+	for _, pageType := range types {
+		fmt.Fprintf(writer, "%s\t%d\n", pageType, stats.Types[pageType])
+	}
+	fmt.Fprintf(writer, "words\t%d\n", stats.Words)
+	fmt.Fprintf(writer, "average reading time\t%.1f min\n", stats.AverageReadingTime)
+	fmt.Fprintf(writer, "internal links\t%d\n", stats.InternalLinks)
+	fmt.Fprintf(writer, "external links\t%d\n", stats.ExternalLinks)
+	fmt.Fprintf(writer, "images without alt text\t%d\n", len(stats.ImagesWithoutAlt))
+
+	fmt.Fprintln(writer, "\nTAG\tPAGES")
+	for _, tag := range stats.TopTags {
+		fmt.Fprintf(writer, "%s\t%d\n", tag.Tag, tag.Count)
+	}
+
+	fmt.Fprintln(writer, "\nMONTH\tPOSTS")
+	for _, month := range stats.PostsPerMonth {
+		fmt.Fprintf(writer, "%s\t%d\n", month.Month, month.Count)
+	}
+
+	if len(stats.ImagesWithoutAlt) > 0 {
+		fmt.Fprintln(writer, "\nIMAGES WITHOUT ALT TEXT")
+		for _, image := range stats.ImagesWithoutAlt {
+			fmt.Fprintln(writer, image)
+		}
+	}
+
+	return writer.Flush()
+}
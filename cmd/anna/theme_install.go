@@ -0,0 +1,251 @@
+package anna
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+// Name of the file recording the source and version of an installed theme, in the directory of the theme
+const themeLockFile = ".anna-theme.json"
+
+// Time after which downloading or cloning a theme is given up
+const themeFetchTimeout = 5 * time.Minute
+
+// ThemeSource is the source of a theme installed with anna theme install
+type ThemeSource struct {
+	// URL of a git repository or of a zip archive, or the path to a local zip archive
+	URL string `json:"url"`
+	// Branch, tag or commit of a git repository, the default branch is installed when empty
+	Ref string `json:"ref,omitempty"`
+	// Expected SHA-256 checksum of a zip archive in hex, which is not verified when empty
+	SHA256 string `json:"sha256,omitempty"`
+	// Name of the directory in themes/, defaults to the last element of the URL without its extension
+	Name string `json:"name"`
+}
+
+// ThemeLock is the content of the lock file of an installed theme, the resolved commit of git themes and the checksum of archives pin its version
+type ThemeLock struct {
+	ThemeSource
+	Commit string `json:"commit,omitempty"`
+}
+
+// isArchive reports whether the source is a zip archive rather than a git repository
+func (s ThemeSource) isArchive() bool {
+	archivePath, _, _ := strings.Cut(s.URL, "?")
+	return strings.HasSuffix(strings.ToLower(archivePath), ".zip")
+}
+
+// ThemeName returns the name of the directory a theme is installed to, such as "hugo-like" for https://github.com/user/hugo-like.git
+func ThemeName(themeURL string) string {
+	themeURL, _, _ = strings.Cut(themeURL, "?")
+	name := path.Base(strings.TrimSuffix(filepath.ToSlash(themeURL), "/"))
+	name = strings.TrimSuffix(name, ".git")
+	return strings.TrimSuffix(name, ".zip")
+}
+
+// ThemeInstallManager installs a theme into the themes/ directory of the site, replacing the installed version of the theme
+func (cmd *Cmd) ThemeInstallManager(source ThemeSource) {
+	siteDirPath := cmd.siteDir()
+	lock, err := InstallTheme(siteDirPath, source)
+	if err != nil {
+		cmd.ErrorLogger.Fatal(err)
+	}
+
+	version := lock.Commit
+	if version == "" {
+		version = lock.SHA256
+	}
+	cmd.InfoLogger.Printf("Installed theme %q at %s to %s%s%s", lock.Name, version, siteDirPath, parser.ThemesDir, lock.Name)
+	cmd.InfoLogger.Printf("Set \"theme\": %q in layout/config.json to build the site with it", lock.Name)
+}
+
+/*
+InstallTheme fetches a theme from a git repository or a zip archive into themes/<name>/ of the site and records its
+source and version in its lock file. The previously installed version is only replaced once the theme is fetched,
+so a failed install leaves it untouched
+*/
+func InstallTheme(siteDirPath string, source ThemeSource) (ThemeLock, error) {
+	if source.URL == "" {
+		return ThemeLock{}, errors.New("theme install: missing the URL of the theme")
+	}
+	if source.Name == "" {
+		source.Name = ThemeName(source.URL)
+	}
+	if source.Name == "" || source.Name == "." || source.Name == ".." || strings.ContainsAny(source.Name, `/\`) {
+		return ThemeLock{}, fmt.Errorf("theme install: invalid theme name %q, set one with --name", source.Name)
+	}
+	// Refs are passed to git, which would read a ref starting with - as an option
+	if strings.HasPrefix(source.Ref, "-") {
+		return ThemeLock{}, fmt.Errorf("theme install: invalid ref %q, expected a branch, tag or commit", source.Ref)
+	}
+	if source.SHA256 != "" && !source.isArchive() {
+		return ThemeLock{}, errors.New("theme install: checksums are verified for zip archives, pin a git theme to a commit with --ref instead")
+	}
+
+	themesDirPath := siteDirPath + parser.ThemesDir
+	if err := os.MkdirAll(themesDirPath, 0750); err != nil {
+		return ThemeLock{}, err
+	}
+	tempDirPath, err := os.MkdirTemp(themesDirPath, ".install-")
+	if err != nil {
+		return ThemeLock{}, err
+	}
+	defer os.RemoveAll(tempDirPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), themeFetchTimeout)
+	defer cancel()
+
+	lock := ThemeLock{ThemeSource: source}
+	var themeRootPath string
+	if source.isArchive() {
+		themeRootPath, lock.SHA256, err = fetchThemeArchive(ctx, source, tempDirPath)
+	} else {
+		themeRootPath, lock.Commit, err = cloneTheme(ctx, source, tempDirPath)
+	}
+	if err != nil {
+		return ThemeLock{}, fmt.Errorf("theme install: %s: %w", source.URL, err)
+	}
+	if _, err := os.Stat(themeRootPath + "/layout"); err != nil {
+		return ThemeLock{}, fmt.Errorf("theme install: %s has no layout/ directory, it is not an anna theme", source.URL)
+	}
+
+	lockJSON, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return ThemeLock{}, err
+	}
+	if err := os.WriteFile(themeRootPath+"/"+themeLockFile, append(lockJSON, '\n'), 0640); err != nil {
+		return ThemeLock{}, err
+	}
+
+	themePath := themesDirPath + source.Name
+	if err := os.RemoveAll(themePath); err != nil {
+		return ThemeLock{}, err
+	}
+	if err := os.Rename(themeRootPath, themePath); err != nil {
+		return ThemeLock{}, err
+	}
+	return lock, nil
+}
+
+// fetchThemeArchive downloads or copies a zip archive, verifies its checksum and extracts it, returning the root of the theme and the checksum
+func fetchThemeArchive(ctx context.Context, source ThemeSource, tempDirPath string) (string, string, error) {
+	archivePath := tempDirPath + "/theme.zip"
+	if strings.HasPrefix(source.URL, "http://") || strings.HasPrefix(source.URL, "https://") {
+		if err := downloadThemeArchive(ctx, source.URL, archivePath); err != nil {
+			return "", "", err
+		}
+	} else {
+		archivePath = source.URL
+	}
+
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return "", "", err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(hash, archive)
+	archive.Close()
+	if err != nil {
+		return "", "", err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if source.SHA256 != "" && !strings.EqualFold(source.SHA256, checksum) {
+		return "", "", fmt.Errorf("checksum mismatch, got sha256 %s, want %s", checksum, source.SHA256)
+	}
+
+	extractedPath := tempDirPath + "/extracted"
+	if err := unzip(archivePath, extractedPath); err != nil {
+		return "", "", err
+	}
+
+	// Archives of repositories, such as those of GitHub releases, hold the theme in a single top-level directory
+	if _, err := os.Stat(extractedPath + "/layout"); err == nil {
+		return extractedPath, checksum, nil
+	}
+	entries, err := os.ReadDir(extractedPath)
+	if err != nil {
+		return "", "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return extractedPath + "/" + entries[0].Name(), checksum, nil
+	}
+	return extractedPath, checksum, nil
+}
+
+// downloadThemeArchive downloads a zip archive, failing on responses other than 200 OK
+func downloadThemeArchive(ctx context.Context, archiveURL string, archivePath string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveURL, nil)
+	if err != nil {
+		return err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed with %s", response.Status)
+	}
+
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	_, err = io.Copy(archive, response.Body)
+	return err
+}
+
+// cloneTheme clones a git repository at a ref, returning the root of the theme without its .git/ directory and the resolved commit
+func cloneTheme(ctx context.Context, source ThemeSource, tempDirPath string) (string, string, error) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return "", "", errors.New("git is required to install themes from repositories, install it or use a zip archive")
+	}
+
+	clonePath := tempDirPath + "/repo"
+	// Commands run in the working directory when dir is empty, as the paths of the site and of local repositories are relative to it
+	git := func(dir string, args ...string) (string, error) {
+		command := exec.CommandContext(ctx, gitPath, args...)
+		command.Dir = dir
+		output, err := command.CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("git %s: %w\n%s", args[0], err, strings.TrimSpace(string(output)))
+		}
+		return strings.TrimSpace(string(output)), nil
+	}
+
+	// Commits cannot be cloned directly, so the repository is cloned before checking out the ref
+	// The arguments after -- are never read as options, even for a URL starting with -
+	if _, err := git("", "clone", "--quiet", "--", source.URL, clonePath); err != nil {
+		return "", "", err
+	}
+	if source.Ref != "" {
+		if _, err := git(clonePath, "checkout", "--quiet", "--detach", source.Ref, "--"); err != nil {
+			return "", "", err
+		}
+	}
+	commit, err := git(clonePath, "rev-parse", "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := os.RemoveAll(clonePath + "/.git"); err != nil {
+		return "", "", err
+	}
+	return clonePath, commit, nil
+}
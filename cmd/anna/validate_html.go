@@ -1,50 +1,149 @@
 package anna
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
+	"github.com/anna-ssg/anna/v3/pkg/parser"
 )
 
-func (cmd *Cmd) ValidateHTMLContent(siteDataPath string) {
-	root, err := filepath.Abs(siteDataPath + "rendered")
-	if err != nil {
-		log.Fatalf("Error getting absolute path: %v", err)
+// LintFinding is a problem found while validating a site
+// Line and Column are 1-based positions in File and are omitted when they are not known
+type LintFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (f LintFinding) String() string {
+	position := f.File
+	if f.Line > 0 {
+		position += fmt.Sprintf(":%d", f.Line)
+	}
+	if f.Column > 0 {
+		position += fmt.Sprintf(":%d", f.Column)
+	}
+	return fmt.Sprintf("%s: %s (%s)", position, f.Message, f.Rule)
+}
+
+// Severities of lint findings, errors prevent the site from being rendered
+const (
+	lintSeverityError   = "error"
+	lintSeverityWarning = "warning"
+)
+
+/*
+ValidateHTMLContent validates the frontmatter of the markdown files of a site and, when it is valid, the rendered pages of the site
+The site is rendered before its pages are validated
+*/
+func (cmd *Cmd) ValidateHTMLContent(siteDataPath string) []LintFinding {
+	findings := cmd.lintFrontmatter(siteDataPath)
+	if len(findings) > 0 {
+		return findings
 	}
-	fmt.Println("Walking directory at path:", root)
 
-	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	cmd.VanillaRender(siteDataPath)
+
+	renderedPath := siteDataPath + "rendered/"
+	cmd.InfoLogger.Println("Walking directory at path:", renderedPath)
+
+	err := filepath.WalkDir(renderedPath, func(path string, dir fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if info.IsDir() {
-			return nil
-		}
-
-		if filepath.Ext(path) == ".html" {
+		if !dir.IsDir() && filepath.Ext(path) == ".html" {
 			// Parse HTML file
-			if err := parseHTMLFile(path); err != nil {
-				fmt.Printf("Error parsing %s: %v\n", path, err)
+			fileFindings, err := parseHTMLFile(path)
+			if err != nil {
+				cmd.ErrorLogger.Printf("Error parsing %s: %v\n", path, err)
 			}
+			findings = append(findings, fileFindings...)
 		}
 
 		return nil
 	})
+	if err != nil {
+		cmd.ErrorLogger.Printf("Error walking the directory: %v\n", err)
+	}
 
+	return findings
+}
+
+// lintFrontmatter reports the errors in the frontmatter of every markdown file of the site
+func (cmd *Cmd) lintFrontmatter(siteDataPath string) []LintFinding {
+	var findings []LintFinding
+
+	p := cmd.newParser(siteDataPath)
+	p.ParseConfig(siteDataPath + "layout/config.json")
+
+	for _, root := range p.ContentRoots() {
+		findings = append(findings, lintContentDir(root.Path, p.LayoutConfig)...)
+	}
+	return findings
+}
+
+// lintContentDir reports the errors in the frontmatter of every markdown file of a content directory, excluded directories are skipped
+func lintContentDir(contentPath string, config parser.LayoutConfig) []LintFinding {
+	var findings []LintFinding
+	err := filepath.WalkDir(contentPath, func(path string, dir fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if dir.IsDir() && dir.Name() == ".obsidian" {
+			return filepath.SkipDir
+		}
+		if relPath, _ := filepath.Rel(contentPath, path); dir.IsDir() && relPath != "." && config.IsExcludedDir(filepath.ToSlash(relPath)) {
+			return filepath.SkipDir
+		}
+		if dir.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		_, _, errs := parser.ParseFrontmatter(string(content), path)
+		for _, frontmatterErr := range errs {
+			findings = append(findings, LintFinding{
+				File:     frontmatterErr.Path,
+				Line:     frontmatterErr.Line,
+				Column:   frontmatterErr.Column,
+				Rule:     "frontmatter",
+				Severity: lintSeverityError,
+				Message:  frontmatterErr.Message,
+			})
+		}
+		return nil
+	})
 	if err != nil {
-		fmt.Printf("Error walking the directory: %v\n", err)
+		findings = append(findings, LintFinding{
+			File:     contentPath,
+			Rule:     "content",
+			Severity: lintSeverityError,
+			Message:  err.Error(),
+		})
 	}
+
+	return findings
 }
 
-func parseHTMLFile(path string) error {
+func parseHTMLFile(path string) ([]LintFinding, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
 		err = file.Close()
@@ -56,9 +155,11 @@ func parseHTMLFile(path string) error {
 	// Load the HTML content into a GoQuery document
 	doc, err := goquery.NewDocumentFromReader(file)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	var findings []LintFinding
+
 	// Checking for semantic elements
 	semanticElements := []string{"header", "nav", "article", "footer"}
 	missingElements := make([]string, 0)
@@ -70,10 +171,87 @@ func parseHTMLFile(path string) error {
 	}
 
 	if len(missingElements) > 0 {
-		fmt.Printf("File %s is missing the following semantic elements: %s\n", path, strings.Join(missingElements, ", "))
-	} else {
-		fmt.Printf("File %s has all the required semantic elements\n", path)
+		findings = append(findings, LintFinding{
+			File:     path,
+			Rule:     "semantic-elements",
+			Severity: lintSeverityWarning,
+			Message:  "missing the following semantic elements: " + strings.Join(missingElements, ", "),
+		})
+	}
+
+	// Checking for the image of link previews
+	if content, _ := doc.Find(`meta[property="og:image"], meta[name="twitter:image"]`).First().Attr("content"); content == "" {
+		findings = append(findings, LintFinding{
+			File:     path,
+			Rule:     "preview-image",
+			Severity: lintSeverityWarning,
+			Message:  "no preview image, set a previewimage or cover in its frontmatter or the defaultPreviewImage config",
+		})
+	}
+
+	// Checking for images without alt text and links without discernible text, including those of the layouts
+	html, err := goquery.OuterHtml(doc.Selection)
+	if err != nil {
+		return nil, err
+	}
+	issues, err := helpers.AccessibilityIssues(html)
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range issues {
+		findings = append(findings, LintFinding{
+			File:     path,
+			Rule:     issue.Rule,
+			Severity: lintSeverityWarning,
+			Message:  issue.Message,
+		})
 	}
 
-	return nil
+	return findings, nil
+}
+
+/*
+reportFindings prints the findings in the configured lint format
+The build fails if any finding is an error, or if there are any findings in strict mode
+*/
+func (cmd *Cmd) reportFindings(findings []LintFinding) {
+	errorCount := 0
+	for _, finding := range findings {
+		if finding.Severity == lintSeverityError {
+			errorCount++
+		}
+	}
+
+	switch cmd.LintFormat {
+	case "", "text":
+		warnings := helpers.NewWarningCollector()
+		for _, finding := range findings {
+			if finding.Severity == lintSeverityError {
+				cmd.ErrorLogger.Println(finding)
+			} else {
+				warnings.Warn(finding)
+			}
+		}
+
+		if errorCount > 0 {
+			cmd.ErrorLogger.Fatalf("Validation failed with %d error(s)", errorCount)
+		}
+		cmd.checkWarnings(warnings)
+	case "json":
+		if findings == nil {
+			findings = []LintFinding{}
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(findings); err != nil {
+			cmd.ErrorLogger.Fatal(err)
+		}
+
+		if errorCount > 0 || (cmd.Strict && len(findings) > 0) {
+			cmd.ErrorLogger.Fatalf("Validation failed with %d finding(s)", len(findings))
+		}
+	default:
+		cmd.ErrorLogger.Fatalf("Unknown lint format %q, expected text or json", cmd.LintFormat)
+	}
 }
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
 	"time"
 
@@ -10,14 +13,36 @@ import (
 )
 
 func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// liveReloadManager serves a site for the serve command, replaced in tests so that no server is started
+var liveReloadManager = (*anna.Cmd).LiveReloadManager
+
+// newRootCmd returns the anna command along with its subcommands
+func newRootCmd() *cobra.Command {
 	var addr string
 	var prof bool
 	var renderDrafts bool
+	var renderExpired bool
+	var strict bool
+	var strictConfig bool
+	var noAnalytics bool
+	var profileRender int
+	var reportOrphans bool
+	var jobs int
+	var onlyTypes []string
+	var skipTypes []string
 	var serve string
 	var webconsole bool
 	var version bool
 	var validateHTMLLayouts bool
+	var lintFormat string
 	var renderSpecificSite string
+	var quiet bool
+	var basePath string
 
 	Version := "v3.0.0" // to be set at build time $(git describe --tags)
 
@@ -25,18 +50,39 @@ func main() {
 		Use:   "anna",
 		Short: "Static Site Generator",
 		Run: func(cmd *cobra.Command, args []string) {
+			// Errors and warnings are written to their own loggers, so quiet mode only discards the info logger
+			var infoOutput io.Writer = os.Stderr
+			if quiet {
+				infoOutput = io.Discard
+			}
+
 			annaCmd := anna.Cmd{
 				RenderDrafts:       renderDrafts,
+				RenderExpired:      renderExpired,
+				Strict:             strict,
+				StrictConfig:       strictConfig,
+				NoAnalytics:        noAnalytics,
+				ProfileRender:      profileRender,
+				ReportOrphans:      reportOrphans,
+				Jobs:               jobs,
+				Version:            Version,
+				OnlyTypes:          onlyTypes,
+				SkipTypes:          skipTypes,
+				LintFormat:         lintFormat,
 				Addr:               addr,
 				RenderSpecificSite: renderSpecificSite,
 				ServeSpecificSite:  serve,
+				BasePath:           basePath,
+				Quiet:              quiet,
 				ErrorLogger:        log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
-				InfoLogger:         log.New(os.Stderr, "LOG\t", log.Ldate|log.Ltime),
+				InfoLogger:         log.New(infoOutput, "LOG\t", log.Ldate|log.Ltime),
 			}
 
+			// Serving with the root command is kept for existing scripts, anna serve has its own flags
 			if serve != "" {
 				annaCmd.LiveReload = true
 				annaCmd.LiveReloadManager()
+				return
 			}
 
 			if prof {
@@ -46,8 +92,9 @@ func main() {
 				annaCmd.PrintStats(elapsedTime)
 			}
 
+			// The version is the output of the command rather than a log, and is printed in quiet mode
 			if version {
-				annaCmd.InfoLogger.Println("Current version:", Version)
+				fmt.Println("Current version:", Version)
 			}
 
 			if validateHTMLLayouts {
@@ -62,23 +109,235 @@ func main() {
 					annaCmd.InfoLogger.Println(err)
 				}
 				annaCmd.LiveReloadManager()
+				return
 			}
 
 			annaCmd.VanillaRenderManager()
 		},
 	}
 
-	rootCmd.Flags().StringVarP(&addr, "addr", "a", "8000", "specify port to serve rendered content to")
+	rootCmd.Flags().StringVarP(&addr, "addr", "a", "8000", "specify the port or host:port to serve rendered content at")
 	rootCmd.Flags().BoolVarP(&renderDrafts, "draft", "d", false, "renders draft posts")
+	rootCmd.Flags().BoolVar(&renderExpired, "expired", false, "renders pages past their expiry date")
 	rootCmd.Flags().BoolVarP(&validateHTMLLayouts, "layout", "l", false, "validates html layouts")
+	rootCmd.Flags().StringVar(&lintFormat, "format", "text", "output format of the validation findings, text or json")
 	// Do not set default values for string flags
 	rootCmd.Flags().StringVarP(&renderSpecificSite, "render-site", "r", "", "specify the specific site directory to render")
+	rootCmd.Flags().StringVar(&basePath, "base-path", "", "overrides the basePath of the site config, the path prefixed to links and assets")
+	rootCmd.Flags().BoolVar(&noAnalytics, "no-analytics", false, "skips the analytics script configured in the site config")
+	rootCmd.Flags().IntVarP(&jobs, "jobs", "j", 0, "number of markdown files parsed and static files copied concurrently, defaults to the number of CPUs")
+	rootCmd.Flags().StringSliceVar(&onlyTypes, "only", nil, "renders only the pages of the given types, such as post,page (partial build, not for deploys)")
+	rootCmd.Flags().StringSliceVar(&skipTypes, "skip", nil, "skips the pages of the given types, such as note (partial build, not for deploys)")
 	rootCmd.Flags().BoolVarP(&prof, "prof", "p", false, "enable profiling")
+	rootCmd.Flags().IntVar(&profileRender, "profile-render", 0, "prints the given number of pages that took the longest to render")
+	rootCmd.Flags().Lookup("profile-render").NoOptDefVal = "10"
+	rootCmd.Flags().BoolVar(&reportOrphans, "report-orphans", false, "prints the notes that neither link to nor are linked from any other page")
 	rootCmd.Flags().StringVarP(&serve, "serve", "s", "", "specify the specific site directory to serve")
+	rootCmd.Flags().BoolVar(&strict, "strict", false, "treats warnings as errors and fails the build")
+	rootCmd.Flags().BoolVar(&strictConfig, "strict-config", false, "fails the build on unknown keys and invalid required fields in the site config")
+	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "prints only warnings and errors")
 	rootCmd.Flags().BoolVarP(&version, "version", "v", false, "prints current version number")
 	rootCmd.Flags().BoolVarP(&webconsole, "webconsole", "w", false, "wizard to setup anna")
+	_ = rootCmd.Flags().MarkDeprecated("serve", "use anna serve instead")
+	_ = rootCmd.Flags().MarkDeprecated("addr", "use anna serve --port instead")
 
-	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+	var renderOutput string
+	renderCmd := &cobra.Command{
+		Use:   "render [file]",
+		Short: "Renders a single markdown file using the layouts and config of the site",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			annaCmd := anna.Cmd{
+				RenderSpecificSite: renderSpecificSite,
+				ErrorLogger:        log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				InfoLogger:         log.New(os.Stderr, "LOG\t", log.Ldate|log.Ltime),
+			}
+			annaCmd.RenderFileManager(args[0], renderOutput)
+		},
+	}
+	renderCmd.Flags().StringVarP(&renderSpecificSite, "render-site", "r", "", "specify the site directory whose layouts and config are used")
+	renderCmd.Flags().StringVarP(&renderOutput, "output", "o", "", "specify the file to write the rendered page to instead of stdout")
+	rootCmd.AddCommand(renderCmd)
+
+	var statsJSON bool
+	var statsDrafts bool
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Prints statistics about the content of the site without rendering it",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			annaCmd := anna.Cmd{
+				RenderDrafts:       statsDrafts,
+				RenderSpecificSite: renderSpecificSite,
+				ErrorLogger:        log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				InfoLogger:         log.New(os.Stderr, "LOG\t", log.Ldate|log.Ltime),
+			}
+			annaCmd.StatsManager(statsJSON)
+		},
+	}
+	statsCmd.Flags().StringVarP(&renderSpecificSite, "render-site", "r", "", "specify the site directory to collect statistics of")
+	statsCmd.Flags().BoolVarP(&statsDrafts, "draft", "d", false, "includes draft posts")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "prints the statistics as JSON")
+	rootCmd.AddCommand(statsCmd)
+
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Checks the site for common problems and prints a health report",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			annaCmd := anna.Cmd{
+				Version:            Version,
+				RenderSpecificSite: renderSpecificSite,
+				ErrorLogger:        log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				InfoLogger:         log.New(os.Stderr, "LOG\t", log.Ldate|log.Ltime),
+			}
+			annaCmd.DoctorManager()
+		},
+	}
+	doctorCmd.Flags().StringVarP(&renderSpecificSite, "render-site", "r", "", "specify the site directory to check")
+	rootCmd.AddCommand(doctorCmd)
+
+	var configFormat string
+	var configVerbose bool
+	var configBaseURL string
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Prints the effective config of the site after environment variables and flags are applied, without building it",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			annaCmd := anna.Cmd{
+				RenderSpecificSite: renderSpecificSite,
+				StrictConfig:       strictConfig,
+				BaseURL:            configBaseURL,
+				BasePath:           basePath,
+				ErrorLogger:        log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				InfoLogger:         log.New(os.Stderr, "LOG\t", log.Ldate|log.Ltime),
+			}
+			annaCmd.ConfigManager(configFormat, configVerbose)
+		},
+	}
+	configCmd.Flags().StringVarP(&renderSpecificSite, "render-site", "r", "", "specify the site directory whose config is printed")
+	configCmd.Flags().StringVar(&configFormat, "format", "json", "output format of the config, json or yaml")
+	configCmd.Flags().BoolVarP(&configVerbose, "verbose", "V", false, "prints where every value comes from, the defaults, config.json, an environment variable or a flag")
+	configCmd.Flags().StringVar(&configBaseURL, "base-url", "", "overrides the baseURL of the site config")
+	configCmd.Flags().StringVar(&basePath, "base-path", "", "overrides the basePath of the site config")
+	configCmd.Flags().BoolVar(&strictConfig, "strict-config", false, "fails on unknown keys and invalid required fields in the site config")
+	rootCmd.AddCommand(configCmd)
+
+	var themeSource anna.ThemeSource
+	themeCmd := &cobra.Command{
+		Use:   "theme",
+		Short: "Manages the themes installed to the themes/ directory of the site",
+	}
+	themeInstallCmd := &cobra.Command{
+		Use:   "install [url]",
+		Short: "Installs a theme from a git repository or a zip archive, replacing its installed version",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			themeSource.URL = args[0]
+			annaCmd := anna.Cmd{
+				RenderSpecificSite: renderSpecificSite,
+				ErrorLogger:        log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				InfoLogger:         log.New(os.Stderr, "LOG\t", log.Ldate|log.Ltime),
+			}
+			annaCmd.ThemeInstallManager(themeSource)
+		},
 	}
+	themeInstallCmd.Flags().StringVarP(&renderSpecificSite, "render-site", "r", "", "specify the site directory to install the theme to")
+	themeInstallCmd.Flags().StringVar(&themeSource.Ref, "ref", "", "branch, tag or commit of a git repository to install")
+	themeInstallCmd.Flags().StringVar(&themeSource.SHA256, "sha256", "", "expected SHA-256 checksum of a zip archive")
+	themeInstallCmd.Flags().StringVar(&themeSource.Name, "name", "", "name of the theme, defaults to the last element of the URL")
+	themeCmd.AddCommand(themeInstallCmd)
+	rootCmd.AddCommand(themeCmd)
+
+	var serveHost string
+	var servePort string
+	var serveNoReload bool
+	var serveBaseURL string
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Renders the site and serves it locally, re-rendering it when its content changes",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var infoOutput io.Writer = os.Stderr
+			if quiet {
+				infoOutput = io.Discard
+			}
+
+			annaCmd := anna.Cmd{
+				RenderDrafts:      renderDrafts,
+				RenderExpired:     renderExpired,
+				Version:           Version,
+				OnlyTypes:         onlyTypes,
+				SkipTypes:         skipTypes,
+				Addr:              net.JoinHostPort(serveHost, servePort),
+				LiveReload:        true,
+				NoReload:          serveNoReload,
+				BaseURL:           serveBaseURL,
+				BasePath:          basePath,
+				ServeSpecificSite: renderSpecificSite,
+				Quiet:             quiet,
+				ErrorLogger:       log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				InfoLogger:        log.New(infoOutput, "LOG\t", log.Ldate|log.Ltime),
+			}
+			liveReloadManager(&annaCmd)
+		},
+	}
+	serveCmd.Flags().StringVarP(&renderSpecificSite, "render-site", "r", "", "specify the site directory to serve")
+	serveCmd.Flags().StringVar(&serveHost, "host", "localhost", "host to serve the site at")
+	serveCmd.Flags().StringVar(&servePort, "port", "8000", "port to serve the site at")
+	serveCmd.Flags().BoolVar(&serveNoReload, "no-reload", false, "serves the site without re-rendering it when its content changes")
+	serveCmd.Flags().BoolVarP(&renderDrafts, "drafts", "d", false, "renders draft posts")
+	serveCmd.Flags().BoolVar(&renderDrafts, "draft", false, "renders draft posts, same as --drafts")
+	_ = serveCmd.Flags().MarkHidden("draft")
+	serveCmd.Flags().BoolVar(&renderExpired, "expired", false, "renders pages past their expiry date")
+	serveCmd.Flags().StringVar(&serveBaseURL, "base-url", "", "overrides the baseURL of the site config")
+	serveCmd.Flags().StringVar(&basePath, "base-path", "", "overrides the basePath of the site config, the path the site is served under")
+	serveCmd.Flags().StringSliceVar(&onlyTypes, "only", nil, "renders only the pages of the given types, such as post,page")
+	serveCmd.Flags().StringSliceVar(&skipTypes, "skip", nil, "skips the pages of the given types, such as note")
+	serveCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "prints only warnings and errors")
+	rootCmd.AddCommand(serveCmd)
+
+	// Rebuilds of anna serve run in a child process, so that a failed build does not stop the server
+	var rebuildBaseURL string
+	rebuildCmd := &cobra.Command{
+		Use:    anna.RebuildCommand + " [site]",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var infoOutput io.Writer = os.Stderr
+			if quiet {
+				infoOutput = io.Discard
+			}
+
+			annaCmd := anna.Cmd{
+				RenderDrafts:  renderDrafts,
+				RenderExpired: renderExpired,
+				StrictConfig:  strictConfig,
+				Jobs:          jobs,
+				Version:       Version,
+				OnlyTypes:     onlyTypes,
+				SkipTypes:     skipTypes,
+				LiveReload:    true,
+				BaseURL:       rebuildBaseURL,
+				BasePath:      basePath,
+				Quiet:         quiet,
+				ErrorLogger:   log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				InfoLogger:    log.New(infoOutput, "LOG\t", log.Ldate|log.Ltime),
+			}
+			annaCmd.VanillaRender(args[0])
+		},
+	}
+	rebuildCmd.Flags().BoolVar(&renderDrafts, "draft", false, "")
+	rebuildCmd.Flags().BoolVar(&renderExpired, "expired", false, "")
+	rebuildCmd.Flags().BoolVar(&strictConfig, "strict-config", false, "")
+	rebuildCmd.Flags().BoolVar(&quiet, "quiet", false, "")
+	rebuildCmd.Flags().IntVar(&jobs, "jobs", 0, "")
+	rebuildCmd.Flags().StringSliceVar(&onlyTypes, "only", nil, "")
+	rebuildCmd.Flags().StringSliceVar(&skipTypes, "skip", nil, "")
+	rebuildCmd.Flags().StringVar(&rebuildBaseURL, "base-url", "", "")
+	rebuildCmd.Flags().StringVar(&basePath, "base-path", "", "")
+	rootCmd.AddCommand(rebuildCmd)
+
+	return rootCmd
 }
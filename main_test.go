@@ -1,9 +1,21 @@
 package main_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/anna-ssg/anna/v3/cmd/anna"
+	"github.com/anna-ssg/anna/v3/pkg/parser"
 )
 
 func BenchmarkMain(b *testing.B) {
@@ -14,3 +26,316 @@ func BenchmarkMain(b *testing.B) {
 		annaCmd.VanillaRenderManager()
 	}
 }
+
+func TestReproducibleBuild(t *testing.T) {
+	// The build time is written to the feed, reproducible builds set it with SOURCE_DATE_EPOCH
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	annaCmd := anna.Cmd{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		InfoLogger:  log.New(os.Stderr, "TEST LOG\t", log.Ldate|log.Ltime),
+	}
+
+	annaCmd.VanillaRender("site/")
+	firstBuild := readRenderedFiles(t, "site/rendered/")
+
+	annaCmd.VanillaRender("site/")
+	secondBuild := readRenderedFiles(t, "site/rendered/")
+
+	if len(firstBuild) != len(secondBuild) {
+		t.Fatalf("got %v files in the second build, want %v", len(secondBuild), len(firstBuild))
+	}
+	for path, content := range firstBuild {
+		if !slices.Equal(content, secondBuild[path]) {
+			t.Errorf("%v differs between consecutive builds", path)
+		}
+	}
+}
+
+// readRenderedFiles returns the contents of every file in the rendered directory by their path
+func readRenderedFiles(t *testing.T, renderedPath string) map[string][]byte {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(renderedPath, func(path string, dir fs.DirEntry, err error) error {
+		if err != nil || dir.IsDir() {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[path] = content
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestDoctor(t *testing.T) {
+	annaCmd := anna.Cmd{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		InfoLogger:  log.New(os.Stderr, "TEST LOG\t", log.Ldate|log.Ltime),
+	}
+
+	// writeSite creates a site with the given files, such as layout/page.html, relative to the site directory
+	writeSite := func(t *testing.T, files map[string]string) string {
+		siteDirPath := t.TempDir() + "/"
+		for name, content := range files {
+			if err := os.MkdirAll(filepath.Dir(siteDirPath+name), 0750); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(siteDirPath+name, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.MkdirAll(siteDirPath+"static/", 0750); err != nil {
+			t.Fatal(err)
+		}
+		return siteDirPath
+	}
+
+	layouts := map[string]string{
+		"layout/config.json":               `{"baseURL": "https://example.org", "siteTitle": "Example"}`,
+		"layout/page.html":                 `{{define "page"}}{{(index .DeepDataMerge.Templates .PageURL).Body}}{{end}}`,
+		"layout/tags.html":                 `{{define "all-tags"}}{{end}}`,
+		"layout/tag-subpage.html":          `{{define "tag-subpage"}}{{end}}`,
+		"layout/collections.html":          `{{define "all-collections"}}{{end}}`,
+		"layout/collection-subpage.html":   `{{define "collection-subpage"}}{{end}}`,
+		"layout/partials/placeholder.html": `{{define "placeholder"}}{{end}}`,
+	}
+
+	statuses := func(checks []anna.DoctorCheck) map[string]string {
+		got := make(map[string]string)
+		for _, check := range checks {
+			got[check.Name] = check.Status()
+		}
+		return got
+	}
+
+	t.Run("broken internal links are reported", func(t *testing.T) {
+		files := map[string]string{
+			"content/index.md": "---\ntitle: Home\n---\n[about](/about.html) [missing](/missing.html) [external](https://example.com/missing.html)\n",
+			"content/about.md": "---\ntitle: About\n---\n[home](/)\n",
+		}
+		for name, content := range layouts {
+			files[name] = content
+		}
+
+		checks := annaCmd.Doctor(writeSite(t, files))
+
+		want := map[string]string{"config": "ok", "frontmatter": "ok", "layouts": "ok", "pages": "ok", "links": "warn"}
+		if got := statuses(checks); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if findings := checks[len(checks)-1].Findings; len(findings) != 1 || findings[0].Message != "links to missing /missing.html" {
+			t.Errorf("got %v, want the link to /missing.html", findings)
+		}
+	})
+
+	t.Run("missing layouts skip the build", func(t *testing.T) {
+		files := map[string]string{
+			"content/index.md":  "---\ntitle: Home\nlayout: landing\ntags: [go]\n---\n",
+			"content/humans.md": "---\ntitle: Humans\noutputFormat: txt\n---\n",
+		}
+		for name, content := range layouts {
+			files[name] = content
+		}
+		delete(files, "layout/tag-subpage.html")
+
+		checks := annaCmd.Doctor(writeSite(t, files))
+
+		want := map[string]string{"config": "ok", "frontmatter": "ok", "layouts": "fail", "pages": "skip", "links": "skip"}
+		if got := statuses(checks); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		var messages []string
+		for _, finding := range checks[2].Findings {
+			messages = append(messages, finding.Message)
+		}
+		wantMessages := []string{
+			`humans.txt: the "page.txt" layout is not defined by any file in layout/`,
+			`index.html: the "landing" layout is not defined by any file in layout/`,
+			`tags/: the "tag-subpage" layout is not defined by any file in layout/`,
+		}
+		if !slices.Equal(messages, wantMessages) {
+			t.Errorf("got %q, want %q", messages, wantMessages)
+		}
+	})
+
+	t.Run("layouts of tags and collections are only required when the site has some", func(t *testing.T) {
+		files := map[string]string{"content/index.md": "---\ntitle: Home\n---\n"}
+		for name, content := range layouts {
+			files[name] = content
+		}
+		delete(files, "layout/tag-subpage.html")
+		delete(files, "layout/collection-subpage.html")
+
+		checks := annaCmd.Doctor(writeSite(t, files))
+
+		if status := checks[2].Status(); status != "ok" {
+			t.Errorf("got layouts %s with findings %v, want ok", status, checks[2].Findings)
+		}
+	})
+}
+
+func TestNormalizeAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{"8000", "localhost:8000", false},
+		{":8000", "localhost:8000", false},
+		{"localhost:8000", "localhost:8000", false},
+		{"0.0.0.0:3000", "0.0.0.0:3000", false},
+		{"[::1]:8000", "[::1]:8000", false},
+		{" 8000 ", "localhost:8000", false},
+		{"", "", true},
+		{"localhost", "", true},
+		{"localhost:", "", true},
+		{"localhost:http", "", true},
+		{"70000", "", true},
+		{"0", "", true},
+		{"::1:8000", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got, err := anna.NormalizeAddr(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	config := parser.LayoutConfig{BaseURL: "https://example.org", SiteTitle: "ssg", Navbar: []map[string]string{{"Docs": "docs.html"}}}
+	sources := map[string]string{"baseURL": "flag --base-url", "siteTitle": "config.json"}
+
+	var gotYAML bytes.Buffer
+	if err := anna.WriteConfig(&gotYAML, config, "yaml", sources); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"navbar:\n  - Docs: docs.html\n",
+		"baseURL: https://example.org # flag --base-url\n",
+		"siteTitle: ssg # config.json\n",
+	} {
+		if !strings.Contains(gotYAML.String(), want) {
+			t.Errorf("YAML config is missing %q in\n%s", want, gotYAML.String())
+		}
+	}
+
+	var gotJSON bytes.Buffer
+	if err := anna.WriteConfig(&gotJSON, config, "json", sources); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Config  parser.LayoutConfig `json:"config"`
+		Sources map[string]string   `json:"sources"`
+	}
+	if err := json.Unmarshal(gotJSON.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Config.BaseURL != config.BaseURL || !reflect.DeepEqual(decoded.Sources, sources) {
+		t.Errorf("got %+v, want the config along with its sources", decoded)
+	}
+
+	if err := anna.WriteConfig(&gotJSON, config, "toml", nil); err == nil {
+		t.Errorf("got no error for an unknown format")
+	}
+}
+
+func TestInstallTheme(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	archivePath := t.TempDir() + "/base.zip"
+	writeThemeArchive(t, archivePath, map[string]string{
+		"base-1.0/layout/page.html": `{{define "page"}}v1{{end}}`,
+		"base-1.0/static/style.css": "body {}",
+	})
+
+	lock, err := anna.InstallTheme(siteDirPath, anna.ThemeSource{URL: archivePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.Name != "base" || len(lock.SHA256) != 64 {
+		t.Errorf("got lock %+v, want the base theme with the checksum of its archive", lock)
+	}
+
+	// The single top-level directory of the archive is the root of the theme
+	for _, file := range []string{"layout/page.html", "static/style.css", ".anna-theme.json"} {
+		if _, err := os.Stat(siteDirPath + "themes/base/" + file); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+
+	// A failed install leaves the installed version untouched
+	writeThemeArchive(t, archivePath, map[string]string{"layout/page.html": `{{define "page"}}v2{{end}}`})
+	if _, err := anna.InstallTheme(siteDirPath, anna.ThemeSource{URL: archivePath, SHA256: lock.SHA256}); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("got error %v, want a checksum mismatch", err)
+	}
+	if page, _ := os.ReadFile(siteDirPath + "themes/base/layout/page.html"); string(page) != `{{define "page"}}v1{{end}}` {
+		t.Errorf("got page layout %q after a failed install, want the installed version", page)
+	}
+
+	if _, err := anna.InstallTheme(siteDirPath, anna.ThemeSource{URL: archivePath}); err != nil {
+		t.Fatal(err)
+	}
+	if page, _ := os.ReadFile(siteDirPath + "themes/base/layout/page.html"); string(page) != `{{define "page"}}v2{{end}}` {
+		t.Errorf("got page layout %q, want the updated version", page)
+	}
+
+	if _, err := anna.InstallTheme(siteDirPath, anna.ThemeSource{URL: "https://example.org/base.git", SHA256: lock.SHA256}); err == nil {
+		t.Errorf("got no error for a checksum of a git theme")
+	}
+
+	// Names and refs are rejected before git is run, as they could escape themes/ or be read as options
+	for _, source := range []anna.ThemeSource{
+		{URL: "https://example.org/base.git", Name: ".."},
+		{URL: "https://example.org/base.git", Ref: "--upload-pack=touch pwned"},
+	} {
+		if _, err := anna.InstallTheme(siteDirPath, source); err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("got error %v for %+v, want it to be invalid", err, source)
+		}
+	}
+}
+
+func writeThemeArchive(t *testing.T, archivePath string, files map[string]string) {
+	t.Helper()
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	writer := zip.NewWriter(archive)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectStatsImagesWithoutAlt(t *testing.T) {
+	templates := map[template.URL]parser.TemplateData{
+		"index.html": {Body: `<img src="/static/logo.png" alt="Logo"><img src="/static/divider.png" alt=""><img src="/static/chart.png">`},
+	}
+
+	got := anna.CollectStats(templates).ImagesWithoutAlt
+	want := []string{"index.html: /static/chart.png"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
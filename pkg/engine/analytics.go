@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"html/template"
+	"net/url"
+)
+
+// Default tracking scripts of the hosted analytics providers
+const (
+	plausibleScriptURL = "https://plausible.io/js/script.js"
+	umamiScriptURL     = "https://cloud.umami.is/script.js"
+)
+
+// analyticsSnippet returns the tracking script of the configured analytics provider
+func (e *Engine) analyticsSnippet() string {
+	analytics := e.DeepDataMerge.LayoutConfig.Analytics
+	if analytics == nil || e.DisableAnalytics {
+		return ""
+	}
+
+	switch analytics.Provider {
+	case "ga4":
+		if analytics.SiteID == "" {
+			e.Warnings.Warn("analytics: ga4 requires a siteID, skipping the analytics script")
+			return ""
+		}
+		return "<script async src=\"https://www.googletagmanager.com/gtag/js?id=" + url.QueryEscape(analytics.SiteID) + "\"></script>\n" +
+			"<script>window.dataLayer = window.dataLayer || []; function gtag(){dataLayer.push(arguments);} gtag('js', new Date()); gtag('config', '" + template.JSEscapeString(analytics.SiteID) + "');</script>\n"
+
+	case "plausible":
+		domain := analytics.Domain
+		if domain == "" {
+			if baseURL, err := url.Parse(e.DeepDataMerge.LayoutConfig.BaseURL); err == nil {
+				domain = baseURL.Host
+			}
+		}
+		if domain == "" {
+			e.Warnings.Warn("analytics: plausible requires a domain or a baseURL, skipping the analytics script")
+			return ""
+		}
+		scriptURL := analytics.ScriptURL
+		if scriptURL == "" {
+			scriptURL = plausibleScriptURL
+		}
+		return "<script defer data-domain=\"" + template.HTMLEscapeString(domain) + "\" src=\"" + template.HTMLEscapeString(scriptURL) + "\"></script>\n"
+
+	case "umami":
+		if analytics.SiteID == "" {
+			e.Warnings.Warn("analytics: umami requires a siteID, skipping the analytics script")
+			return ""
+		}
+		scriptURL := analytics.ScriptURL
+		if scriptURL == "" {
+			scriptURL = umamiScriptURL
+		}
+		return "<script defer src=\"" + template.HTMLEscapeString(scriptURL) + "\" data-website-id=\"" + template.HTMLEscapeString(analytics.SiteID) + "\"></script>\n"
+
+	default:
+		e.Warnings.Warnf("analytics: unknown provider %q, expected ga4, plausible or umami", analytics.Provider)
+		return ""
+	}
+}
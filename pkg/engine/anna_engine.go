@@ -6,21 +6,38 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"html/template"
+	"mime"
 	"os"
+	"path"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
 	"github.com/anna-ssg/anna/v3/pkg/parser"
 )
 
+// Fields which can be included in the search index through the jsonIndex config
+var jsonIndexFieldNames = []string{"title", "url", "tags", "date", "description", "excerpt", "body"}
+
+const defaultExcerptLength = 200
+
+// TermCount is a tag or collection along with the number of pages under it
+type TermCount struct {
+	Name  string
+	URL   template.URL
+	Count int
+}
+
 type TagRootTemplateData struct {
 	DeepDataMerge DeepDataMerge
 	PageURL       template.URL
 	TemplateData  parser.TemplateData
 	TagNames      []string
+	Tags          []TermCount
 }
 
 type CollectionRootTemplateData struct {
@@ -28,6 +45,7 @@ type CollectionRootTemplateData struct {
 	PageURL         template.URL
 	TemplateData    parser.TemplateData
 	CollectionNames []string
+	Collections     []TermCount
 }
 
 func (e *Engine) RenderTags(fileOutPath string, templ *template.Template) {
@@ -44,12 +62,18 @@ func (e *Engine) RenderTags(fileOutPath string, templ *template.Template) {
 	})
 
 	tagNames := make([]string, 0, len(tags))
+	tagCounts := make([]TermCount, 0, len(tags))
 	for _, tag := range tags {
 		tagString := string(tag)
 		tagString, _ = strings.CutPrefix(tagString, "tags/")
 		tagString, _ = strings.CutSuffix(tagString, ".html")
 
 		tagNames = append(tagNames, tagString)
+		tagCounts = append(tagCounts, TermCount{
+			Name:  e.displayName(tag, "tags/"),
+			URL:   tag,
+			Count: len(e.DeepDataMerge.TagsMap[tag]),
+		})
 	}
 
 	tagRootTemplataData := parser.TemplateData{
@@ -61,18 +85,27 @@ func (e *Engine) RenderTags(fileOutPath string, templ *template.Template) {
 		PageURL:       "tags.html",
 		TemplateData:  tagRootTemplataData,
 		TagNames:      tagNames,
+		Tags:          tagCounts,
 	}
 
-	// Rendering the page displaying all tags
-	err := templ.ExecuteTemplate(&tagsBuffer, "all-tags", tagTemplateData)
-	if err != nil {
-		e.ErrorLogger.Fatal(err)
+	if !e.collidesWithPage("tags", tagTemplateData.PageURL) {
+		// Rendering the page displaying all tags
+		err := templ.ExecuteTemplate(&tagsBuffer, "all-tags", tagTemplateData)
+		if err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
+
+		// Flushing 'tags.html' to the disk
+		err = os.WriteFile(fileOutPath+"rendered/tags.html", e.postProcess("tags.html", parser.TemplateData{}, tagsBuffer.Bytes()), 0666)
+		if err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
 	}
 
-	// Flushing 'tags.html' to the disk
-	err = os.WriteFile(fileOutPath+"rendered/tags.html", tagsBuffer.Bytes(), 0666)
-	if err != nil {
-		e.ErrorLogger.Fatal(err)
+	// Rendering 'tags/index.html' with the page count of every tag
+	tagTemplateData.PageURL = "tags/index.html"
+	if !e.collidesWithPage("tags", tagTemplateData.PageURL) {
+		e.renderIndexPage(fileOutPath, templ, "tags-index", tagTemplateData.PageURL, tagTemplateData)
 	}
 
 	// Create a wait group to wait for all goroutines to finish
@@ -81,22 +114,20 @@ func (e *Engine) RenderTags(fileOutPath string, templ *template.Template) {
 	e.DeepDataMerge.Tags = make(map[template.URL]parser.TemplateData)
 
 	for tag := range e.DeepDataMerge.TagsMap {
-		slices.SortFunc(e.DeepDataMerge.TagsMap[tag], func(a, b parser.TemplateData) int {
-			return cmp.Compare(b.Date, a.Date)
-		})
-		tagString := string(tag)
-		tagString, _ = strings.CutPrefix(tagString, "tags/")
-		tagString, _ = strings.CutSuffix(tagString, ".html")
+		e.SortPages(e.DeepDataMerge.TagsMap[tag])
 
 		e.DeepDataMerge.Tags[tag] = parser.TemplateData{
 			Frontmatter: parser.Frontmatter{
-				Title: tagString,
+				Title: e.displayName(tag, "tags/"),
 			},
 		}
 	}
 
 	// Rendering the subpages with merged tagged posts
 	for tag, taggedTemplates := range e.DeepDataMerge.TagsMap {
+		if e.collidesWithPage("tags", tag) {
+			continue
+		}
 		wg.Add(1)
 		go func(tag template.URL, taggedTemplates []parser.TemplateData) {
 			defer wg.Done()
@@ -123,12 +154,18 @@ func (e *Engine) RenderCollections(fileOutPath string, templ *template.Template)
 	})
 
 	collectionNames := make([]string, 0, len(collections))
+	collectionCounts := make([]TermCount, 0, len(collections))
 	for _, collection := range collections {
 		collectionString := string(collection)
 		collectionString, _ = strings.CutPrefix(collectionString, "collections/")
 		collectionString, _ = strings.CutSuffix(collectionString, ".html")
 
 		collectionNames = append(collectionNames, string(collectionString))
+		collectionCounts = append(collectionCounts, TermCount{
+			Name:  e.displayName(collection, "collections/"),
+			URL:   collection,
+			Count: len(e.DeepDataMerge.CollectionsMap[collection]),
+		})
 	}
 
 	collectionRootTemplataData := parser.TemplateData{
@@ -140,18 +177,27 @@ func (e *Engine) RenderCollections(fileOutPath string, templ *template.Template)
 		PageURL:         "collections.html",
 		TemplateData:    collectionRootTemplataData,
 		CollectionNames: collectionNames,
+		Collections:     collectionCounts,
 	}
 
-	// Rendering the page displaying all collections
-	err := templ.ExecuteTemplate(&collectionsBuffer, "all-collections", collectionTemplateData)
-	if err != nil {
-		e.ErrorLogger.Fatal(err)
+	if !e.collidesWithPage("collections", collectionTemplateData.PageURL) {
+		// Rendering the page displaying all collections
+		err := templ.ExecuteTemplate(&collectionsBuffer, "all-collections", collectionTemplateData)
+		if err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
+
+		// Flushing 'collections.html' to the disk
+		err = os.WriteFile(fileOutPath+"rendered/collections.html", e.postProcess("collections.html", parser.TemplateData{}, collectionsBuffer.Bytes()), 0666)
+		if err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
 	}
 
-	// Flushing 'collections.html' to the disk
-	err = os.WriteFile(fileOutPath+"rendered/collections.html", collectionsBuffer.Bytes(), 0666)
-	if err != nil {
-		e.ErrorLogger.Fatal(err)
+	// Rendering 'collections/index.html' with the page count of every collection
+	collectionTemplateData.PageURL = "collections/index.html"
+	if !e.collidesWithPage("collections", collectionTemplateData.PageURL) {
+		e.renderIndexPage(fileOutPath, templ, "collections-index", collectionTemplateData.PageURL, collectionTemplateData)
 	}
 
 	// Create a wait group to wait for all goroutines to finish
@@ -160,33 +206,32 @@ func (e *Engine) RenderCollections(fileOutPath string, templ *template.Template)
 	e.DeepDataMerge.Collections = make(map[template.URL]parser.TemplateData)
 
 	for collection := range e.DeepDataMerge.CollectionsMap {
-		slices.SortFunc(e.DeepDataMerge.CollectionsMap[collection], func(a, b parser.TemplateData) int {
-			return cmp.Compare(b.Date, a.Date)
-		})
-
-		collectionString := string(collection)
-		collectionString, _ = strings.CutPrefix(collectionString, "collections/")
-		collectionString, _ = strings.CutSuffix(collectionString, ".html")
+		e.SortPages(e.DeepDataMerge.CollectionsMap[collection])
 
-		e.DeepDataMerge.Collections[collection] = parser.TemplateData{
-			Frontmatter: parser.Frontmatter{
-				Title: collectionString,
-			},
+		// Collections without a metadata file are titled by their name
+		collectionData, ok := e.DeepDataMerge.CollectionsMetadata[collection]
+		if !ok {
+			collectionData = parser.TemplateData{
+				CompleteURL: collection,
+			}
+		}
+		if collectionData.Frontmatter.Title == "" {
+			collectionData.Frontmatter.Title = e.displayName(collection, "collections/")
 		}
+
+		e.DeepDataMerge.Collections[collection] = collectionData
 	}
 
 	// Rendering the subpages with merged tagged posts
 	for collection, collectionTemplates := range e.DeepDataMerge.CollectionsMap {
+		if e.collidesWithPage("collections", collection) {
+			continue
+		}
 		wg.Add(1)
 		go func(collection template.URL, collectionTemplates []parser.TemplateData) {
 			defer wg.Done()
 
-			layoutName := e.DeepDataMerge.CollectionsSubPageLayouts[collection]
-			if layoutName == "" {
-				layoutName = "collection-subpage"
-			}
-
-			e.RenderPage(fileOutPath, collection, templ, layoutName)
+			e.RenderPage(fileOutPath, collection, templ, e.collectionLayout(collection))
 		}(collection, collectionTemplates)
 	}
 
@@ -194,25 +239,107 @@ func (e *Engine) RenderCollections(fileOutPath string, templ *template.Template)
 	wg.Wait()
 }
 
+// collectionLayout returns the name of the layout of the page of a collection
+// The layout of the metadata file takes precedence over the collectionLayouts config
+func (e *Engine) collectionLayout(collection template.URL) string {
+	layoutName := e.DeepDataMerge.CollectionsMetadata[collection].Frontmatter.Layout
+	if layoutName == "" {
+		layoutName = e.DeepDataMerge.CollectionsSubPageLayouts[collection]
+	}
+	if layoutName == "" {
+		layoutName = "collection-subpage"
+	}
+	return layoutName
+}
+
+// displayName returns the display name of a tag or collection, falling back to the name in the key of its page
+func (e *Engine) displayName(key template.URL, prefix string) string {
+	if name, ok := e.DeepDataMerge.DisplayNames[key]; ok {
+		return name
+	}
+
+	name, _ := strings.CutPrefix(string(key), prefix)
+	name, _ = strings.CutSuffix(name, ".html")
+	return name
+}
+
+// renderIndexPage renders the listing of all tags or collections to the index page of their directory
+// Layouts without the index template are skipped with a warning
+func (e *Engine) renderIndexPage(fileOutPath string, templ *template.Template, layoutName string, pageURL template.URL, data any) {
+	if templ.Lookup(layoutName) == nil {
+		e.Warnings.Warnf("%s: the %q layout is not defined, skipping the page", pageURL, layoutName)
+		return
+	}
+
+	var buffer bytes.Buffer
+	err := templ.ExecuteTemplate(&buffer, layoutName, data)
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+
+	e.writePage(fileOutPath, pageURL, e.postProcess(pageURL, parser.TemplateData{}, buffer.Bytes()))
+}
+
+/*
+SortPages sorts pages in place according to the postSort config
+The key defaults to date, the direction defaults to desc for dates and asc for titles and weights
+The sort is stable, pages with equal keys retain their order
+*/
+func (e *Engine) SortPages(pages []parser.TemplateData) {
+	key := e.DeepDataMerge.LayoutConfig.PostSort.Key
+	direction := e.DeepDataMerge.LayoutConfig.PostSort.Direction
+	if direction == "" {
+		direction = "asc"
+		if key == "" || key == "date" {
+			direction = "desc"
+		}
+	}
+
+	slices.SortStableFunc(pages, func(a, b parser.TemplateData) int {
+		var order int
+		switch key {
+		case "title":
+			order = cmp.Compare(strings.ToLower(a.Frontmatter.Title), strings.ToLower(b.Frontmatter.Title))
+		case "weight":
+			order = cmp.Compare(a.Frontmatter.Weight, b.Frontmatter.Weight)
+		default:
+			order = cmp.Compare(a.Date, b.Date)
+		}
+
+		if direction == "desc" {
+			return -order
+		}
+		return order
+	})
+}
+
 func (e *Engine) GenerateJSONIndex(outFilePath string) {
 	// This function creates an index of the site for search
 	// It extracts data from the e.Templates slice
 	// The index.json file is created during every VanillaRender()
 
-	jsonFile, err := os.Create(outFilePath + "rendered/static/index.json")
-	if err != nil {
-		e.ErrorLogger.Fatal(err)
-	}
-	defer func() {
-		err = jsonFile.Close()
-		if err != nil {
-			e.ErrorLogger.Fatal(err)
+	jsonIndexTemplate, jsonIndex := e.buildJSONIndex(e.DeepDataMerge.Templates)
+	e.DeepDataMerge.JSONIndex = jsonIndexTemplate
+	e.writeJSONIndex(outFilePath+"rendered/static/index.json", jsonIndex)
+}
+
+/*
+buildJSONIndex returns the search index of the pages, along with the index that is written to the disk
+The complete frontmatter of every page is indexed unless specific fields are configured
+*/
+func (e *Engine) buildJSONIndex(pages map[template.URL]parser.TemplateData) (map[template.URL]JSONIndexTemplate, any) {
+	// Pages excluded from search, protected pages and pages in other output formats than HTML are not indexed
+	indexedPages := make(map[template.URL]parser.TemplateData, len(pages))
+	for templateURL, templateData := range pages {
+		if templateData.Frontmatter.SearchExclude || templateData.Protected || !templateData.Frontmatter.IsHTML() {
+			continue
 		}
-	}()
+		indexedPages[templateURL] = templateData
+	}
 
 	// Copying contents from e.Templates to new JsonMerged struct
-	jsonIndexTemplate := make(map[template.URL]JSONIndexTemplate)
-	for templateURL, templateData := range e.DeepDataMerge.Templates {
+	jsonIndexTemplate := make(map[template.URL]JSONIndexTemplate, len(indexedPages))
+	for templateURL, templateData := range indexedPages {
 		jsonIndexTemplate[templateURL] = JSONIndexTemplate{
 			CompleteURL: templateData.CompleteURL,
 			Frontmatter: templateData.Frontmatter,
@@ -220,10 +347,27 @@ func (e *Engine) GenerateJSONIndex(outFilePath string) {
 		}
 	}
 
-	e.DeepDataMerge.JSONIndex = jsonIndexTemplate
+	if len(e.DeepDataMerge.LayoutConfig.JSONIndex.Fields) > 0 {
+		return jsonIndexTemplate, e.jsonIndexFields(indexedPages)
+	}
+	return jsonIndexTemplate, jsonIndexTemplate
+}
+
+// writeJSONIndex writes a search index to the file at jsonFilePath
+func (e *Engine) writeJSONIndex(jsonFilePath string, jsonIndex any) {
+	jsonFile, err := os.Create(jsonFilePath)
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+	defer func() {
+		err = jsonFile.Close()
+		if err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
+	}()
 
 	// Marshal the contents of jsonMergedData
-	jsonMergedMarshaledData, err := json.Marshal(jsonIndexTemplate)
+	jsonMergedMarshaledData, err := json.Marshal(jsonIndex)
 	if err != nil {
 		e.ErrorLogger.Fatal(err)
 	}
@@ -234,31 +378,72 @@ func (e *Engine) GenerateJSONIndex(outFilePath string) {
 	}
 }
 
+// jsonIndexFields builds a search index of the indexed pages containing only the fields set in the jsonIndex config
+func (e *Engine) jsonIndexFields(pages map[template.URL]parser.TemplateData) map[template.URL]map[string]any {
+	indexConfig := e.DeepDataMerge.LayoutConfig.JSONIndex
+	excerptLength := indexConfig.ExcerptLength
+	if excerptLength <= 0 {
+		excerptLength = defaultExcerptLength
+	}
+
+	for _, field := range indexConfig.Fields {
+		if !slices.Contains(jsonIndexFieldNames, field) {
+			e.Warnings.Warn("Unknown field in the jsonIndex config: ", field)
+		}
+	}
+
+	jsonIndex := make(map[template.URL]map[string]any)
+	for templateURL, templateData := range pages {
+		entry := make(map[string]any, len(indexConfig.Fields))
+		for _, field := range indexConfig.Fields {
+			switch field {
+			case "title":
+				entry[field] = templateData.Frontmatter.Title
+			case "url":
+				entry[field] = templateData.CompleteURL
+			case "tags":
+				entry[field] = templateData.Frontmatter.Tags
+			case "date":
+				entry[field] = templateData.Frontmatter.Date
+			case "description":
+				entry[field] = templateData.Frontmatter.Description
+			case "excerpt":
+				entry[field] = helpers.Excerpt(string(templateData.Body), excerptLength)
+			case "body":
+				entry[field] = helpers.PlainText(string(templateData.Body))
+			}
+		}
+		jsonIndex[templateURL] = entry
+	}
+
+	return jsonIndex
+}
+
 func (e *Engine) GenerateSitemap(outFilePath string) {
 	var buffer bytes.Buffer
 	buffer.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
 	buffer.WriteString("<urlset xmlns=\"http://www.sitemaps.org/schemas/sitemap/0.9\">\n")
 
-	// Sorting templates by key
-	keys := make([]string, 0, len(e.DeepDataMerge.Templates))
-	for k := range e.DeepDataMerge.Templates {
-		keys = append(keys, string(k))
+	// The posts listing is not a page of the content, it is listed by its own key and last modified with its newest post
+	keys := e.sitemapKeys()
+	listingKey := e.DeepDataMerge.LayoutConfig.PostsListingKey()
+	if _, ok := e.DeepDataMerge.Templates[listingKey]; e.DeepDataMerge.LayoutConfig.PostsListing && !ok {
+		keys = append(keys, string(listingKey))
+		sort.Strings(keys)
 	}
-	sort.Strings(keys)
 
-	tempTemplates := make(map[template.URL]parser.TemplateData)
+	// Pages are listed in the order of their keys, as the iteration order of maps differs between builds
 	for _, templateURL := range keys {
-		tempTemplates[template.URL(templateURL)] = e.DeepDataMerge.Templates[template.URL(templateURL)]
-	}
-
-	e.DeepDataMerge.Templates = tempTemplates
-
-	// Iterate over parsed markdown files
-	for _, templateData := range e.DeepDataMerge.Templates {
+		templateData, ok := e.DeepDataMerge.Templates[template.URL(templateURL)]
+		if !ok && template.URL(templateURL) == listingKey {
+			templateData = e.postsListingSitemapEntry()
+		}
 		url := e.DeepDataMerge.LayoutConfig.BaseURL + "/" + string(templateData.CompleteURL)
 		buffer.WriteString("\t<url>\n")
 		buffer.WriteString("\t\t<loc>" + url + "</loc>\n")
-		buffer.WriteString("\t\t<lastmod>" + templateData.Frontmatter.Date + "</lastmod>\n")
+		if lastmod := sitemapLastmod(templateData); lastmod != "" {
+			buffer.WriteString("\t\t<lastmod>" + lastmod + "</lastmod>\n")
+		}
 		buffer.WriteString("\t</url>\n")
 	}
 	buffer.WriteString("</urlset>\n")
@@ -281,18 +466,79 @@ func (e *Engine) GenerateSitemap(outFilePath string) {
 	}
 }
 
+/*
+sitemapLastmod returns the date a page was last modified in the W3C datetime format of sitemaps, empty for undated pages
+Pages modified at midnight UTC, such as those dated with a day, are dated with the day only
+*/
+func sitemapLastmod(page parser.TemplateData) string {
+	lastmod := cmp.Or(page.Updated, page.Date)
+	if lastmod == 0 {
+		// Pages built without being parsed only have the date of their frontmatter
+		return page.Frontmatter.Date
+	}
+
+	date := time.Unix(lastmod, 0).UTC()
+	if date.Equal(date.Truncate(24 * time.Hour)) {
+		return date.Format("2006-01-02")
+	}
+	return date.Format(time.RFC3339)
+}
+
+// feedDate formats a Unix time in the RFC 822 format of RSS feeds with a four-digit year, in UTC so that feeds do not depend on the time zone of the build
+func feedDate(date int64) string {
+	return time.Unix(date, 0).UTC().Format(time.RFC1123Z)
+}
+
 func (e *Engine) GenerateFeed() {
+	// Collecting pages in the order of their URLs so that the stable sort preserves it for equal keys
+	templateURLs := make([]string, 0, len(e.DeepDataMerge.Templates))
+	for templateURL := range e.DeepDataMerge.Templates {
+		templateURLs = append(templateURLs, string(templateURL))
+	}
+	sort.Strings(templateURLs)
+
+	var posts []parser.TemplateData
+	for _, templateURL := range templateURLs {
+		templateData := e.DeepDataMerge.Templates[template.URL(templateURL)]
+		if isFeedPage(templateData) {
+			posts = append(posts, templateData)
+		}
+	}
+
+	// The feed of the site links to the posts listing when it is rendered
+	var link template.URL
+	if e.DeepDataMerge.LayoutConfig.PostsListing {
+		link = e.DeepDataMerge.LayoutConfig.PostsListingURL()
+	}
+	e.writeFeed("feed.xml", e.DeepDataMerge.LayoutConfig.SiteTitle, link, posts)
+}
+
+// isFeedPage reports whether a page is listed in feeds, drafts, hidden and protected pages and pages in other output formats are not
+func isFeedPage(page parser.TemplateData) bool {
+	return !page.Frontmatter.Draft && !page.Frontmatter.Hidden && !page.Protected && page.Frontmatter.IsHTML()
+}
+
+/*
+writeFeed writes the RSS feed of the pages to feedPath, relative to the rendered directory, and registers it for the OPML file
+The channel links to link, relative to the root of the site, which is the root itself when empty
+*/
+func (e *Engine) writeFeed(feedPath string, title string, link template.URL, posts []parser.TemplateData) {
 	var buffer bytes.Buffer
 	buffer.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\" standalone=\"yes\"?>\n")
-	buffer.WriteString("<?xml-stylesheet href=\"/static/styles/feed.xsl\" type=\"text/xsl\"?>\n")
-	buffer.WriteString("<rss version=\"2.0\" xmlns:atom=\"http://www.w3.org/2005/Atom\">\n")
+	buffer.WriteString("<?xml-stylesheet href=\"" + e.DeepDataMerge.LayoutConfig.BasePath() + "/static/styles/feed.xsl\" type=\"text/xsl\"?>\n")
+	podcast := e.DeepDataMerge.LayoutConfig.Podcast
+	if podcast != nil {
+		buffer.WriteString("<rss version=\"2.0\" xmlns:atom=\"http://www.w3.org/2005/Atom\" xmlns:itunes=\"http://www.itunes.com/dtds/podcast-1.0.dtd\">\n")
+	} else {
+		buffer.WriteString("<rss version=\"2.0\" xmlns:atom=\"http://www.w3.org/2005/Atom\">\n")
+	}
 	buffer.WriteString("  <channel>\n")
 	buffer.WriteString("   <title>")
-	xml.EscapeText(&buffer, []byte(e.DeepDataMerge.LayoutConfig.SiteTitle))
+	xml.EscapeText(&buffer, []byte(title))
 	buffer.WriteString("</title>\n")
-	buffer.WriteString("   <link>" + e.DeepDataMerge.LayoutConfig.BaseURL + "/" + "</link>\n")
+	buffer.WriteString("   <link>" + e.DeepDataMerge.LayoutConfig.BaseURL + "/" + string(link) + "</link>\n")
 	buffer.WriteString("   <description>Recent content on ")
-	xml.EscapeText(&buffer, []byte(e.DeepDataMerge.LayoutConfig.SiteTitle))
+	xml.EscapeText(&buffer, []byte(title))
 	buffer.WriteString("</description>\n")
 	buffer.WriteString("   <language>en-IN</language>\n")
 	buffer.WriteString("   <webMaster>")
@@ -301,20 +547,28 @@ func (e *Engine) GenerateFeed() {
 	buffer.WriteString("   <copyright>")
 	xml.EscapeText(&buffer, []byte(e.DeepDataMerge.LayoutConfig.Copyright))
 	buffer.WriteString("</copyright>\n")
-	buffer.WriteString("   <lastBuildDate>" + time.Now().Format(time.RFC1123Z) + "</lastBuildDate>\n")
-	buffer.WriteString("   <atom:link href=\"" + e.DeepDataMerge.LayoutConfig.BaseURL + "/feed.xml\" rel=\"self\" type=\"application/rss+xml\" />\n")
+	buffer.WriteString("   <atom:link href=\"" + e.DeepDataMerge.LayoutConfig.BaseURL + "/" + feedPath + "\" rel=\"self\" type=\"application/rss+xml\" />\n")
+	if hub := e.DeepDataMerge.LayoutConfig.Feed.Hub; hub != "" {
+		buffer.WriteString("   <atom:link href=\"")
+		xml.EscapeText(&buffer, []byte(hub))
+		buffer.WriteString("\" rel=\"hub\" />\n")
+	}
+	if podcast != nil {
+		e.writePodcastChannel(&buffer, *podcast)
+	}
 
-	var posts []parser.TemplateData
-	for _, templateData := range e.DeepDataMerge.Templates {
-		if !templateData.Frontmatter.Draft {
-			posts = append(posts, templateData)
+	e.SortPages(posts)
+
+	// The build date is the build time of the site, which is SOURCE_DATE_EPOCH for reproducible builds
+	buildTime := e.DeepDataMerge.BuildTime
+	if buildTime.IsZero() {
+		var err error
+		buildTime, err = helpers.BuildTime()
+		if err != nil {
+			e.ErrorLogger.Fatal("Invalid SOURCE_DATE_EPOCH: ", err)
 		}
 	}
-
-	// sort by publication date
-	slices.SortFunc(posts, func(a, b parser.TemplateData) int {
-		return cmp.Compare(b.Date, a.Date) // assuming Date is Unix timestamp
-	})
+	buffer.WriteString("   <lastBuildDate>" + feedDate(buildTime.Unix()) + "</lastBuildDate>\n")
 
 	// Iterate over sorted posts
 	for _, templateData := range posts {
@@ -322,8 +576,10 @@ func (e *Engine) GenerateFeed() {
 		buffer.WriteString("      <title>")
 		xml.EscapeText(&buffer, []byte(templateData.Frontmatter.Title))
 		buffer.WriteString("</title>\n")
-		buffer.WriteString("      <link>" + e.DeepDataMerge.LayoutConfig.BaseURL + "/" + string(templateData.CompleteURL) + "/</link>\n")
-		buffer.WriteString("      <pubDate>" + time.Unix(templateData.Date, 0).Format(time.RFC1123Z) + "</pubDate>\n")
+		buffer.WriteString("      <link>" + e.DeepDataMerge.LayoutConfig.BaseURL + "/" + string(templateData.CompleteURL) + "</link>\n")
+		if templateData.Date != 0 {
+			buffer.WriteString("      <pubDate>" + feedDate(templateData.Date) + "</pubDate>\n")
+		}
 		buffer.WriteString("      <author>")
 		xml.EscapeText(&buffer, []byte(e.DeepDataMerge.LayoutConfig.Author))
 		buffer.WriteString("</author>\n")
@@ -331,13 +587,16 @@ func (e *Engine) GenerateFeed() {
 		buffer.WriteString("      <description>")
 		xml.EscapeText(&buffer, []byte(templateData.Body))
 		buffer.WriteString("</description>\n")
+		if templateData.Frontmatter.Enclosure != nil && templateData.EnclosureURL != "" {
+			e.writeEnclosure(&buffer, templateData, podcast)
+		}
 		buffer.WriteString("    </item>\n")
 	}
 
 	buffer.WriteString("  </channel>\n")
 	buffer.WriteString("</rss>\n")
 
-	outputFile, err := os.Create(e.SiteDataPath + "rendered/feed.xml")
+	outputFile, err := os.Create(e.SiteDataPath + "rendered/" + feedPath)
 	if err != nil {
 		e.ErrorLogger.Fatal(err)
 	}
@@ -352,4 +611,66 @@ func (e *Engine) GenerateFeed() {
 	if err != nil {
 		e.ErrorLogger.Fatal(err)
 	}
+
+	e.feeds = append(e.feeds, feed{title: title, path: feedPath})
+}
+
+// writePodcastChannel writes the iTunes fields of the channel of a podcast feed
+func (e *Engine) writePodcastChannel(buffer *bytes.Buffer, podcast parser.PodcastConfig) {
+	buffer.WriteString("   <itunes:author>")
+	xml.EscapeText(buffer, []byte(e.DeepDataMerge.LayoutConfig.Author))
+	buffer.WriteString("</itunes:author>\n")
+	if image := e.DeepDataMerge.LayoutConfig.PodcastImageURL(); image != "" {
+		buffer.WriteString("   <itunes:image href=\"")
+		xml.EscapeText(buffer, []byte(image))
+		buffer.WriteString("\" />\n")
+	}
+	if podcast.Category != "" {
+		buffer.WriteString("   <itunes:category text=\"")
+		xml.EscapeText(buffer, []byte(podcast.Category))
+		buffer.WriteString("\" />\n")
+	}
+	buffer.WriteString("   <itunes:explicit>" + strconv.FormatBool(podcast.Explicit) + "</itunes:explicit>\n")
+	if podcast.Email != "" {
+		buffer.WriteString("   <itunes:owner>\n")
+		buffer.WriteString("     <itunes:name>")
+		xml.EscapeText(buffer, []byte(e.DeepDataMerge.LayoutConfig.Author))
+		buffer.WriteString("</itunes:name>\n")
+		buffer.WriteString("     <itunes:email>")
+		xml.EscapeText(buffer, []byte(podcast.Email))
+		buffer.WriteString("</itunes:email>\n")
+		buffer.WriteString("   </itunes:owner>\n")
+	}
+}
+
+// writeEnclosure writes the enclosure of a post, along with the iTunes fields of the episode in podcast feeds
+func (e *Engine) writeEnclosure(buffer *bytes.Buffer, templateData parser.TemplateData, podcast *parser.PodcastConfig) {
+	enclosure := templateData.Frontmatter.Enclosure
+	mimeType := enclosure.Type
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(path.Ext(string(templateData.EnclosureURL)))
+	}
+
+	buffer.WriteString("      <enclosure url=\"")
+	xml.EscapeText(buffer, []byte(templateData.EnclosureURL))
+	buffer.WriteString("\" length=\"" + strconv.FormatInt(enclosure.Length, 10) + "\" type=\"")
+	xml.EscapeText(buffer, []byte(mimeType))
+	buffer.WriteString("\" />\n")
+
+	if podcast == nil {
+		return
+	}
+	if enclosure.Duration != "" {
+		buffer.WriteString("      <itunes:duration>")
+		xml.EscapeText(buffer, []byte(enclosure.Duration))
+		buffer.WriteString("</itunes:duration>\n")
+	}
+	if enclosure.Episode > 0 {
+		buffer.WriteString("      <itunes:episode>" + strconv.Itoa(enclosure.Episode) + "</itunes:episode>\n")
+	}
+	explicit := podcast.Explicit
+	if enclosure.Explicit != nil {
+		explicit = *enclosure.Explicit
+	}
+	buffer.WriteString("      <itunes:explicit>" + strconv.FormatBool(explicit) + "</itunes:explicit>\n")
 }
@@ -2,14 +2,22 @@ package engine_test
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"html/template"
+	"image"
+	_ "image/png"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/anna-ssg/anna/v3/pkg/engine"
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
 	"github.com/anna-ssg/anna/v3/pkg/parser"
 )
 
@@ -57,7 +65,7 @@ func TestRenderTags(t *testing.T) {
 		},
 	}
 
-	templ, err := template.ParseFiles(TestDirPath+"render_tags/tags_template.html", TestDirPath+"render_tags/tags_subpage_template.html")
+	templ, err := template.ParseFiles(TestDirPath+"render_tags/tags_template.html", TestDirPath+"render_tags/tags_subpage_template.html", TestDirPath+"render_tags/tags_index_template.html")
 	if err != nil {
 		t.Errorf("%v", err)
 	}
@@ -111,6 +119,22 @@ func TestRenderTags(t *testing.T) {
 			t.Errorf("The expected and generated tech.html tag-subpage can be found in test/engine/render_tags/rendered/tags/")
 		}
 	})
+
+	t.Run("render tags/index.html with tag counts", func(t *testing.T) {
+		gotIndexFile, err := os.ReadFile(TestDirPath + "render_tags/rendered/tags/index.html")
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		wantIndexFile, err := os.ReadFile(TestDirPath + "render_tags/want_tags_index.html")
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		if !slices.Equal(gotIndexFile, wantIndexFile) {
+			t.Errorf("The expected and generated tags/index.html can be found in test/engine/render_tags/rendered/tags/")
+		}
+	})
 }
 
 func TestGenerateMergedJson(t *testing.T) {
@@ -151,6 +175,56 @@ func TestGenerateMergedJson(t *testing.T) {
 			t.Errorf("The expected and generated json can be found in test/engine/json_index_test")
 		}
 	})
+
+	t.Run("test json creation with configured fields", func(t *testing.T) {
+		e := engine.Engine{
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		e.DeepDataMerge.Templates = make(map[template.URL]parser.TemplateData)
+		e.DeepDataMerge.LayoutConfig.JSONIndex = parser.JSONIndexConfig{
+			Fields:        []string{"title", "url", "excerpt"},
+			ExcerptLength: 20,
+		}
+
+		e.DeepDataMerge.Templates["docs.html"] = parser.TemplateData{
+			CompleteURL: "docs.html",
+			Body:        "<h1>Anna</h1><p>A static site generator in go</p>",
+			Frontmatter: parser.Frontmatter{
+				Title: "Anna Documentation",
+			},
+		}
+		e.DeepDataMerge.Templates["secret.html"] = parser.TemplateData{
+			CompleteURL: "secret.html",
+			Frontmatter: parser.Frontmatter{
+				Title:         "Secret",
+				SearchExclude: true,
+			},
+		}
+		e.DeepDataMerge.Templates["private.html"] = parser.TemplateData{
+			CompleteURL: "private.html",
+			Protected:   true,
+			Frontmatter: parser.Frontmatter{Title: "Private"},
+		}
+		e.DeepDataMerge.Templates["humans.txt"] = parser.TemplateData{
+			CompleteURL: "humans.txt",
+			Frontmatter: parser.Frontmatter{
+				Title:        "Humans",
+				OutputFormat: "txt",
+			},
+		}
+
+		e.GenerateJSONIndex(TestDirPath + "json_index_test/")
+
+		gotJson, err := os.ReadFile(TestDirPath + "/json_index_test/rendered/static/index.json")
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		wantJson := `{"docs.html":{"excerpt":"Anna A static site…","title":"Anna Documentation","url":"docs.html"}}`
+		if string(gotJson) != wantJson {
+			t.Errorf("got %s, want %s", gotJson, wantJson)
+		}
+	})
 }
 
 func TestGenerateSitemap(t *testing.T) {
@@ -214,3 +288,1400 @@ func TestGenerateSitemap(t *testing.T) {
 		}
 	})
 }
+
+func TestSortPages(t *testing.T) {
+	pages := []parser.TemplateData{
+		{CompleteURL: "b.html", Date: 2, Frontmatter: parser.Frontmatter{Title: "Beta", Weight: 1}},
+		{CompleteURL: "a.html", Date: 1, Frontmatter: parser.Frontmatter{Title: "alpha", Weight: 2}},
+		{CompleteURL: "c.html", Date: 2, Frontmatter: parser.Frontmatter{Title: "Gamma", Weight: 1}},
+	}
+
+	tests := []struct {
+		name     string
+		postSort parser.PostSort
+		want     []template.URL
+	}{
+		{"default sorts by date descending", parser.PostSort{}, []template.URL{"b.html", "c.html", "a.html"}},
+		{"date ascending", parser.PostSort{Key: "date", Direction: "asc"}, []template.URL{"a.html", "b.html", "c.html"}},
+		{"title defaults to ascending", parser.PostSort{Key: "title"}, []template.URL{"a.html", "b.html", "c.html"}},
+		{"title descending", parser.PostSort{Key: "title", Direction: "desc"}, []template.URL{"c.html", "b.html", "a.html"}},
+		{"weight is stable for equal keys", parser.PostSort{Key: "weight"}, []template.URL{"b.html", "c.html", "a.html"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := engine.Engine{
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			e.DeepDataMerge.LayoutConfig.PostSort = tt.postSort
+
+			sortedPages := slices.Clone(pages)
+			e.SortPages(sortedPages)
+
+			got := make([]template.URL, 0, len(sortedPages))
+			for _, page := range sortedPages {
+				got = append(got, page.CompleteURL)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateManifest(t *testing.T) {
+	if err := os.MkdirAll(TestDirPath+"manifest/rendered", 0750); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	t.Run("render manifest.webmanifest", func(t *testing.T) {
+		e := engine.Engine{
+			SiteDataPath: TestDirPath + "manifest/",
+			ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		e.DeepDataMerge.LayoutConfig.SiteTitle = "anna"
+		e.DeepDataMerge.LayoutConfig.PWA = &parser.PWAConfig{
+			ShortName:       "anna",
+			ThemeColor:      "#000000",
+			BackgroundColor: "#ffffff",
+			Icons: []parser.PWAIcon{
+				{Src: "static/icons/icon-192.png", Sizes: "192x192", Type: "image/png"},
+			},
+		}
+
+		e.GenerateManifest(TestDirPath + "manifest/rendered/manifest.webmanifest")
+
+		gotManifest, err := os.ReadFile(TestDirPath + "manifest/rendered/manifest.webmanifest")
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		wantManifest, err := os.ReadFile(TestDirPath + "manifest/want_manifest.webmanifest")
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		if !slices.Equal(gotManifest, wantManifest) {
+			t.Errorf("The expected and generated manifest can be found in test/engine/manifest/")
+		}
+	})
+
+	t.Run("inject manifest link and theme color into the head", func(t *testing.T) {
+		e := engine.Engine{
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		e.DeepDataMerge.LayoutConfig.PWA = &parser.PWAConfig{ThemeColor: "#000000"}
+
+		got := string(e.InjectHead([]byte("<html><head><title>anna</title></head><body></body></html>")))
+		want := "<html><head><title>anna</title><link rel=\"manifest\" href=\"/manifest.webmanifest\" />\n<meta name=\"theme-color\" content=\"#000000\" />\n</head><body></body></html>"
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+}
+
+func TestGenerateServiceWorker(t *testing.T) {
+	renderedFiles := map[string]string{
+		"index.html":       "<h1>Index</h1>",
+		"about/index.html": "<h1>About</h1>",
+		"posts.html":       "<h1>Posts</h1>",
+		"static/style.css": "body {}",
+	}
+	for renderedFile, content := range renderedFiles {
+		filePath := TestDirPath + "service_worker/rendered/" + renderedFile
+		if err := os.MkdirAll(filepath.Dir(filePath), 0750); err != nil {
+			t.Errorf("%v", err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0666); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		precacheLimit int
+		wantURLs      string
+	}{
+		{"precache all pages and assets", 0, `["/about/","/","/posts.html","/static/style.css"]`},
+		{"precache pages before assets", 3, `["/about/","/","/posts.html"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := engine.Engine{
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			e.DeepDataMerge.LayoutConfig.PWA = &parser.PWAConfig{
+				ServiceWorker: true,
+				PrecacheLimit: tt.precacheLimit,
+			}
+
+			e.GenerateServiceWorker(TestDirPath + "service_worker/")
+
+			gotServiceWorker, err := os.ReadFile(TestDirPath + "service_worker/rendered/sw.js")
+			if err != nil {
+				t.Errorf("%v", err)
+			}
+
+			if !strings.Contains(string(gotServiceWorker), "const PRECACHE_URLS = "+tt.wantURLs+";") {
+				t.Errorf("precached urls %s missing from the generated service worker", tt.wantURLs)
+			}
+			if !strings.Contains(string(gotServiceWorker), `const CACHE_NAME = "anna-`) {
+				t.Errorf("cache name missing from the generated service worker")
+			}
+		})
+	}
+}
+
+func TestGenerateFavicons(t *testing.T) {
+	if err := os.MkdirAll(TestDirPath+"favicon/rendered", 0750); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	t.Run("resize the source image to the configured sizes", func(t *testing.T) {
+		e := engine.Engine{
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		e.DeepDataMerge.LayoutConfig.Favicon.Sizes = []int{16, 32}
+
+		e.GenerateFavicons(TestDirPath + "favicon/")
+
+		wantSizes := map[string]int{
+			"favicon-16x16.png":    16,
+			"favicon-32x32.png":    32,
+			"apple-touch-icon.png": 180,
+		}
+		for iconPath, wantSize := range wantSizes {
+			iconFile, err := os.Open(TestDirPath + "favicon/rendered/" + iconPath)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			iconConfig, _, err := image.DecodeConfig(iconFile)
+			iconFile.Close()
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if iconConfig.Width != wantSize || iconConfig.Height != wantSize {
+				t.Errorf("got %s of size %dx%d, want %dx%d", iconPath, iconConfig.Width, iconConfig.Height, wantSize, wantSize)
+			}
+		}
+
+		gotHead := string(e.InjectHead([]byte("<head></head>")))
+		wantLink := `<link rel="apple-touch-icon" type="image/png" sizes="180x180" href="/apple-touch-icon.png" />`
+		if !strings.Contains(gotHead, wantLink) {
+			t.Errorf("got %s, want it to contain %s", gotHead, wantLink)
+		}
+	})
+
+	t.Run("skip generation without a source image", func(t *testing.T) {
+		e := engine.Engine{
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		e.DeepDataMerge.LayoutConfig.Favicon.Source = "static/missing.png"
+
+		e.GenerateFavicons(TestDirPath + "favicon/")
+
+		if got := string(e.InjectHead([]byte("<head></head>"))); got != "<head></head>" {
+			t.Errorf("got %s, want no favicon links", got)
+		}
+	})
+}
+
+func TestInjectAnalytics(t *testing.T) {
+	testCases := []struct {
+		name             string
+		analytics        *parser.AnalyticsConfig
+		disableAnalytics bool
+		want             string
+	}{
+		{
+			name:      "ga4",
+			analytics: &parser.AnalyticsConfig{Provider: "ga4", SiteID: "G-TEST"},
+			want:      "<head><script async src=\"https://www.googletagmanager.com/gtag/js?id=G-TEST\"></script>\n<script>window.dataLayer = window.dataLayer || []; function gtag(){dataLayer.push(arguments);} gtag('js', new Date()); gtag('config', 'G-TEST');</script>\n</head>",
+		},
+		{
+			name:      "plausible with the domain taken from the base URL",
+			analytics: &parser.AnalyticsConfig{Provider: "plausible"},
+			want:      "<head><script defer data-domain=\"example.org\" src=\"https://plausible.io/js/script.js\"></script>\n</head>",
+		},
+		{
+			name:      "self-hosted umami",
+			analytics: &parser.AnalyticsConfig{Provider: "umami", SiteID: "1234", ScriptURL: "https://stats.example.org/script.js"},
+			want:      "<head><script defer src=\"https://stats.example.org/script.js\" data-website-id=\"1234\"></script>\n</head>",
+		},
+		{
+			name:             "disabled while serving",
+			analytics:        &parser.AnalyticsConfig{Provider: "ga4", SiteID: "G-TEST"},
+			disableAnalytics: true,
+			want:             "<head></head>",
+		},
+		{
+			name:      "unknown provider",
+			analytics: &parser.AnalyticsConfig{Provider: "matomo", SiteID: "1"},
+			want:      "<head></head>",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := engine.Engine{
+				ErrorLogger:      log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				DisableAnalytics: tc.disableAnalytics,
+			}
+			e.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+			e.DeepDataMerge.LayoutConfig.Analytics = tc.analytics
+
+			if got := string(e.InjectHead([]byte("<head></head>"))); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerateOPML(t *testing.T) {
+	if err := os.MkdirAll(TestDirPath+"opml/rendered", 0750); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	t.Run("render feeds.opml", func(t *testing.T) {
+		e := engine.Engine{
+			SiteDataPath: TestDirPath + "opml/",
+			ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		e.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+		e.DeepDataMerge.LayoutConfig.SiteTitle = "anna & friends"
+
+		e.GenerateFeed()
+		e.GenerateOPML(TestDirPath + "opml/rendered/feeds.opml")
+
+		gotOPML, err := os.ReadFile(TestDirPath + "opml/rendered/feeds.opml")
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		wantOPML, err := os.ReadFile(TestDirPath + "opml/want_feeds.opml")
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		if !slices.Equal(gotOPML, wantOPML) {
+			t.Errorf("The expected and generated OPML can be found in test/engine/opml/")
+		}
+	})
+}
+
+func TestHTMLSitemapSections(t *testing.T) {
+	e := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"about.html":        {CompleteURL: "about.html", Frontmatter: parser.Frontmatter{Title: "about"}},
+		"docs/index.html":   {CompleteURL: "docs/", Frontmatter: parser.Frontmatter{Title: "Documentation"}},
+		"docs/install.html": {CompleteURL: "docs/install.html", Frontmatter: parser.Frontmatter{Title: "install"}},
+		"docs/data.json":    {CompleteURL: "docs/data.json", Frontmatter: parser.Frontmatter{Title: "data", OutputFormat: "json"}},
+		"index.html":        {CompleteURL: "", Frontmatter: parser.Frontmatter{Title: "home"}},
+		"a/secret.html":     {CompleteURL: "a/secret.html", Frontmatter: parser.Frontmatter{Title: "secret"}, Protected: true},
+		"posts/draft.html":  {CompleteURL: "posts/draft.html", Frontmatter: parser.Frontmatter{Title: "draft", Draft: true}},
+		"posts/first.html":  {CompleteURL: "posts/first.html", Frontmatter: parser.Frontmatter{Title: "first"}},
+		"posts/hidden.html": {CompleteURL: "posts/hidden.html", Frontmatter: parser.Frontmatter{Title: "hidden", Hidden: true}},
+		"posts/2024/a.html": {CompleteURL: "posts/2024/a.html", Frontmatter: parser.Frontmatter{Title: "a"}},
+		"posts/second.html": {CompleteURL: "posts/second.html", Frontmatter: parser.Frontmatter{Title: "second"}},
+	}
+
+	type section struct {
+		Title string
+		URL   template.URL
+		Pages []template.URL
+	}
+	want := []section{
+		{"", "", []template.URL{"about.html", ""}},
+		{"Documentation", "docs/", []template.URL{"docs/install.html"}},
+		{"posts", "posts/", []template.URL{"posts/2024/a.html", "posts/first.html", "posts/second.html"}},
+	}
+
+	var got []section
+	for _, s := range e.HTMLSitemapSections() {
+		pages := make([]template.URL, 0, len(s.Pages))
+		for _, page := range s.Pages {
+			pages = append(pages, page.CompleteURL)
+		}
+		got = append(got, section{s.Title, s.URL, pages})
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGenerateFeedHub(t *testing.T) {
+	if err := os.MkdirAll(TestDirPath+"feed/rendered", 0750); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	for _, hub := range []string{"", "https://pubsubhubbub.appspot.com/"} {
+		t.Run("hub "+hub, func(t *testing.T) {
+			e := engine.Engine{
+				SiteDataPath: TestDirPath + "feed/",
+				ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			e.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+			e.DeepDataMerge.LayoutConfig.Feed.Hub = hub
+
+			e.GenerateFeed()
+
+			gotFeed, err := os.ReadFile(TestDirPath + "feed/rendered/feed.xml")
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			selfLink := `<atom:link href="https://example.org/feed.xml" rel="self" type="application/rss+xml" />`
+			if !bytes.Contains(gotFeed, []byte(selfLink)) {
+				t.Errorf("feed is missing the self link %s", selfLink)
+			}
+
+			hubLink := `<atom:link href="` + hub + `" rel="hub" />`
+			if got := bytes.Contains(gotFeed, []byte(`rel="hub"`)); got != (hub != "") {
+				t.Errorf("got hub link %v, want %v", got, hub != "")
+			}
+			if hub != "" && !bytes.Contains(gotFeed, []byte(hubLink)) {
+				t.Errorf("feed is missing the hub link %s", hubLink)
+			}
+		})
+	}
+}
+
+func TestGenerateFeedSourceDateEpoch(t *testing.T) {
+	if err := os.MkdirAll(TestDirPath+"feed/rendered", 0750); err != nil {
+		t.Errorf("%v", err)
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	e := engine.Engine{
+		SiteDataPath: TestDirPath + "feed/",
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"posts/first.html": {CompleteURL: "posts/first.html", Date: 1600000000, Frontmatter: parser.Frontmatter{Title: "first", Type: "post"}},
+	}
+
+	e.GenerateFeed()
+
+	gotFeed, err := os.ReadFile(TestDirPath + "feed/rendered/feed.xml")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	lastBuildDate := "<lastBuildDate>Tue, 14 Nov 2023 22:13:20 +0000</lastBuildDate>"
+	if !bytes.Contains(gotFeed, []byte(lastBuildDate)) {
+		t.Errorf("feed is missing %s", lastBuildDate)
+	}
+}
+
+func TestGenerateFeedEnclosure(t *testing.T) {
+	if err := os.MkdirAll(TestDirPath+"feed/rendered", 0750); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	explicit := true
+	enclosure := &parser.Enclosure{URL: "static/episode-1.mp3", Length: 1024, Duration: "12:34", Episode: 1, Explicit: &explicit}
+
+	for _, podcast := range []*parser.PodcastConfig{nil, {Category: "Technology", Email: "host@example.org"}} {
+		t.Run(fmt.Sprintf("podcast %v", podcast != nil), func(t *testing.T) {
+			e := engine.Engine{
+				SiteDataPath: TestDirPath + "feed/",
+				ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			e.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+			e.DeepDataMerge.LayoutConfig.Podcast = podcast
+			e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+				"posts/episode-1.html": {
+					CompleteURL:  "posts/episode-1.html",
+					Date:         1600000000,
+					Frontmatter:  parser.Frontmatter{Title: "episode 1", Type: "post", Enclosure: enclosure},
+					EnclosureURL: "https://example.org/static/episode-1.mp3",
+				},
+			}
+
+			e.GenerateFeed()
+
+			gotFeed, err := os.ReadFile(TestDirPath + "feed/rendered/feed.xml")
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			wantEnclosure := `<enclosure url="https://example.org/static/episode-1.mp3" length="1024" type="audio/mpeg" />`
+			if !bytes.Contains(gotFeed, []byte(wantEnclosure)) {
+				t.Errorf("feed is missing the enclosure %s", wantEnclosure)
+			}
+
+			for _, field := range []string{
+				`xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"`,
+				`<itunes:category text="Technology" />`,
+				`<itunes:email>host@example.org</itunes:email>`,
+				`<itunes:duration>12:34</itunes:duration>`,
+				`<itunes:episode>1</itunes:episode>`,
+				`<itunes:explicit>true</itunes:explicit>`,
+			} {
+				if got := bytes.Contains(gotFeed, []byte(field)); got != (podcast != nil) {
+					t.Errorf("got %s in the feed %v, want %v", field, got, podcast != nil)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateCollectionOutputs(t *testing.T) {
+	e := engine.Engine{
+		SiteDataPath: TestDirPath + "collection_outputs/",
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+
+	post := parser.TemplateData{CompleteURL: "posts/first.html", Date: 1600000000, Frontmatter: parser.Frontmatter{Title: "first", Type: "post", OutputFormat: "html"}}
+	page := parser.TemplateData{CompleteURL: "about.html", Frontmatter: parser.Frontmatter{Title: "about", Type: "page", OutputFormat: "html"}}
+	e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"posts/first.html": post,
+		"about.html":       page,
+	}
+	e.DeepDataMerge.CollectionsMap = map[template.URL][]parser.TemplateData{
+		"collections/tech.html":  {post, page},
+		"collections/notes.html": {post, page},
+	}
+	e.DeepDataMerge.CollectionsMetadata = map[template.URL]parser.TemplateData{
+		"collections/tech.html":  {Frontmatter: parser.Frontmatter{Title: "Tech", Outputs: &parser.CollectionOutputs{Feed: true, JSONIndex: true, Types: []string{"post"}}}},
+		"collections/notes.html": {Frontmatter: parser.Frontmatter{Title: "Notes"}},
+	}
+
+	if err := os.RemoveAll(TestDirPath + "collection_outputs/rendered/"); err != nil {
+		t.Fatal(err)
+	}
+	e.GenerateCollectionOutputs()
+
+	gotFeed, err := os.ReadFile(TestDirPath + "collection_outputs/rendered/collections/tech/feed.xml")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, want := range []string{
+		`<atom:link href="https://example.org/collections/tech/feed.xml" rel="self" type="application/rss+xml" />`,
+		"<link>https://example.org/posts/first.html</link>",
+	} {
+		if !bytes.Contains(gotFeed, []byte(want)) {
+			t.Errorf("feed is missing %s", want)
+		}
+	}
+	if bytes.Contains(gotFeed, []byte("about.html")) {
+		t.Errorf("feed lists a page that is not of the post type")
+	}
+
+	gotIndex, err := os.ReadFile(TestDirPath + "collection_outputs/rendered/collections/tech/index.json")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var index map[string]any
+	if err := json.Unmarshal(gotIndex, &index); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := index["posts/first.html"]; !ok || len(index) != 1 {
+		t.Errorf("got index of %v, want only posts/first.html", index)
+	}
+
+	if _, err := os.Stat(TestDirPath + "collection_outputs/rendered/collections/notes/"); !os.IsNotExist(err) {
+		t.Errorf("outputs were generated for a collection without outputs")
+	}
+}
+
+func TestGenerateAPI(t *testing.T) {
+	e := engine.Engine{
+		SiteDataPath: TestDirPath + "api/",
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+	e.DeepDataMerge.LayoutConfig.PostsDir = "posts"
+
+	post := parser.TemplateData{
+		CompleteURL: "posts/first.html",
+		Date:        1704153600,
+		Body:        "<p>first</p>",
+		CoverURL:    "static/cover.png",
+		Frontmatter: parser.Frontmatter{Title: "first", Type: "post", Tags: []string{"go"}},
+	}
+	protected := parser.TemplateData{
+		CompleteURL: "posts/secret.html",
+		Protected:   true,
+		Frontmatter: parser.Frontmatter{Title: "secret", Type: "post", Tags: []string{"go"}},
+	}
+	e.DeepDataMerge.Posts = []parser.TemplateData{post, protected}
+	e.DeepDataMerge.TagsMap = map[template.URL][]parser.TemplateData{
+		"tags/go.html": {post},
+	}
+
+	if err := os.RemoveAll(TestDirPath + "api/rendered/"); err != nil {
+		t.Fatal(err)
+	}
+	e.GenerateAPI(TestDirPath + "api/")
+
+	var gotPosts engine.APIPostList
+	readJSON(t, TestDirPath+"api/rendered/api/posts.json", &gotPosts)
+	if gotPosts.Version != engine.APIVersion || len(gotPosts.Posts) != 1 {
+		t.Fatalf("got posts %v, want only the unprotected post", gotPosts)
+	}
+	wantPost := engine.APIPost{
+		Slug:        "first",
+		URL:         "https://example.org/posts/first.html",
+		Title:       "first",
+		Date:        "2024-01-02T00:00:00Z",
+		Tags:        []string{"go"},
+		Collections: []string{},
+		Cover:       "https://example.org/static/cover.png",
+		HTML:        "<p>first</p>",
+	}
+	if !reflect.DeepEqual(gotPosts.Posts[0], wantPost) {
+		t.Errorf("got post %v, want %v", gotPosts.Posts[0], wantPost)
+	}
+
+	var gotPost engine.APIPostResponse
+	readJSON(t, TestDirPath+"api/rendered/api/posts/first.json", &gotPost)
+	if !reflect.DeepEqual(gotPost.Post, wantPost) {
+		t.Errorf("got post %v, want %v", gotPost.Post, wantPost)
+	}
+	if _, err := os.Stat(TestDirPath + "api/rendered/api/posts/secret.json"); !os.IsNotExist(err) {
+		t.Errorf("a protected post was written to the API")
+	}
+
+	var gotTags engine.APITagList
+	readJSON(t, TestDirPath+"api/rendered/api/tags.json", &gotTags)
+	if len(gotTags.Tags) != 1 || gotTags.Tags[0].Slug != "go" || gotTags.Tags[0].Count != 1 || gotTags.Tags[0].Pages != nil {
+		t.Errorf("got tags %v, want the go tag without its pages", gotTags.Tags)
+	}
+
+	var gotTag engine.APITagResponse
+	readJSON(t, TestDirPath+"api/rendered/api/tags/go.json", &gotTag)
+	if len(gotTag.Tag.Pages) != 1 || gotTag.Tag.Pages[0].URL != wantPost.URL {
+		t.Errorf("got pages %v of the go tag, want the first post", gotTag.Tag.Pages)
+	}
+}
+
+func readJSON(t *testing.T, filePath string, value any) {
+	t.Helper()
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildMenus(t *testing.T) {
+	e := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.LayoutConfig.Menus = map[string][]parser.MenuEntry{
+		"main": {
+			{Name: "Home", URL: "/", Weight: 0},
+			{Name: "Documentation", URL: "/docs.html", Weight: 2},
+			{Name: "GitHub", URL: "https://github.com/anna-ssg/anna", Weight: 5},
+		},
+	}
+	e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"docs.html":   {CompleteURL: "docs.html", Frontmatter: parser.Frontmatter{Title: "docs", Weight: 9, Menu: []string{"main"}, OutputFormat: "html"}},
+		"blog.html":   {CompleteURL: "blog.html", Frontmatter: parser.Frontmatter{Title: "blog", Weight: 2, Menu: []string{"main", "footer"}, OutputFormat: "html"}},
+		"about.html":  {CompleteURL: "about.html", Frontmatter: parser.Frontmatter{Title: "about", Weight: 2, Menu: []string{"main"}, OutputFormat: "html"}},
+		"hidden.html": {CompleteURL: "hidden.html", Frontmatter: parser.Frontmatter{Title: "hidden", Menu: []string{"main"}, Hidden: true, OutputFormat: "html"}},
+		"feed.json":   {CompleteURL: "feed.json", Frontmatter: parser.Frontmatter{Title: "feed", Menu: []string{"footer"}, OutputFormat: "json"}},
+	}
+
+	e.BuildMenus()
+
+	names := func(menu string) []string {
+		var got []string
+		for _, entry := range e.DeepDataMerge.Menus[menu] {
+			got = append(got, entry.Name)
+		}
+		return got
+	}
+
+	// The entry of the config for docs.html takes precedence over the page, pages of the same weight are ordered by title
+	if got, want := names("main"), []string{"Home", "Documentation", "about", "blog", "GitHub"}; !slices.Equal(got, want) {
+		t.Errorf("got main menu %v, want %v", got, want)
+	}
+	if got, want := names("footer"), []string{"blog"}; !slices.Equal(got, want) {
+		t.Errorf("got footer menu %v, want %v", got, want)
+	}
+	if got := e.DeepDataMerge.Menus["footer"][0].URL; got != "blog.html" {
+		t.Errorf("got URL %q of the blog entry, want blog.html", got)
+	}
+	if got := e.DeepDataMerge.LayoutConfig.Menus["main"]; len(got) != 3 {
+		t.Errorf("the menus config was modified, got %v", got)
+	}
+}
+
+func TestBuildSections(t *testing.T) {
+	e := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.LayoutConfig.PostSort = parser.PostSort{Key: "weight"}
+	e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"index.html":             {CompleteURL: "", Frontmatter: parser.Frontmatter{Title: "home", OutputFormat: "html"}},
+		"about.html":             {CompleteURL: "about.html", Frontmatter: parser.Frontmatter{Title: "about", OutputFormat: "html"}},
+		"docs/index.html":        {CompleteURL: "docs/", Frontmatter: parser.Frontmatter{Title: "docs", OutputFormat: "html"}},
+		"docs/install.html":      {CompleteURL: "docs/install.html", Frontmatter: parser.Frontmatter{Title: "install", Weight: 2, OutputFormat: "html"}},
+		"docs/intro.html":        {CompleteURL: "docs/intro.html", Frontmatter: parser.Frontmatter{Title: "intro", Weight: 1, OutputFormat: "html"}},
+		"docs/guides/index.html": {CompleteURL: "docs/guides/", Frontmatter: parser.Frontmatter{Title: "guides", Weight: 3, OutputFormat: "html"}},
+		"docs/guides/setup.html": {CompleteURL: "docs/guides/setup.html", Frontmatter: parser.Frontmatter{Title: "setup", OutputFormat: "html"}},
+		"docs/data.json":         {CompleteURL: "docs/data.json", Frontmatter: parser.Frontmatter{Title: "data", OutputFormat: "json"}},
+		"notes/orphan.html":      {CompleteURL: "notes/orphan.html", Frontmatter: parser.Frontmatter{Title: "orphan", OutputFormat: "html"}},
+	}
+
+	e.BuildSections()
+
+	childTitles := func(key template.URL) []string {
+		var titles []string
+		for _, child := range e.DeepDataMerge.Templates[key].Children {
+			titles = append(titles, child.Frontmatter.Title)
+		}
+		return titles
+	}
+
+	tests := []struct {
+		key  template.URL
+		want []string
+	}{
+		{"index.html", []string{"about", "docs"}},
+		{"docs/index.html", []string{"intro", "install", "guides"}},
+		{"docs/guides/index.html", []string{"setup"}},
+		{"about.html", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run("children of "+string(tt.key), func(t *testing.T) {
+			if got := childTitles(tt.key); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildArchive(t *testing.T) {
+	date := func(s string) int64 {
+		parsed, _ := time.Parse("2006-01-02", s)
+		return parsed.Unix()
+	}
+
+	posts := []parser.TemplateData{
+		{CompleteURL: "posts/c.html", Date: date("2024-03-02"), Frontmatter: parser.Frontmatter{Title: "c", Date: "2024-03-02"}},
+		{CompleteURL: "posts/b.html", Date: date("2024-03-01"), Frontmatter: parser.Frontmatter{Title: "b", Date: "2024-03-01"}},
+		{CompleteURL: "posts/a.html", Date: date("2023-12-31"), Frontmatter: parser.Frontmatter{Title: "a", Date: "2023-12-31"}},
+		{CompleteURL: "posts/undated.html", Frontmatter: parser.Frontmatter{Title: "undated"}},
+	}
+
+	for _, includeUndated := range []bool{false, true} {
+		e := engine.Engine{
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		e.DeepDataMerge.Posts = posts
+		e.DeepDataMerge.LayoutConfig.Archive = &parser.ArchiveConfig{IncludeUndated: includeUndated}
+
+		e.BuildArchive()
+
+		wantYears := []engine.ArchiveYear{
+			{Year: "2024", URL: "archive/2024/", Count: 2, Months: []engine.ArchiveMonth{{Month: "March", URL: "archive/2024/03/", Count: 2}}},
+			{Year: "2023", URL: "archive/2023/", Count: 1, Months: []engine.ArchiveMonth{{Month: "December", URL: "archive/2023/12/", Count: 1}}},
+		}
+		if !reflect.DeepEqual(e.DeepDataMerge.ArchiveYears, wantYears) {
+			t.Errorf("got %v, want %v", e.DeepDataMerge.ArchiveYears, wantYears)
+		}
+
+		if got := e.DeepDataMerge.ArchiveMap["archive/2024/03/index.html"]; len(got) != 2 || got[0].Frontmatter.Title != "c" {
+			t.Errorf("got %v, want posts c and b in the order of the posts", got)
+		}
+		if got := e.DeepDataMerge.Archive["archive/2024/03/index.html"].Frontmatter.Title; got != "March 2024" {
+			t.Errorf("got %v, want March 2024", got)
+		}
+
+		_, gotUndated := e.DeepDataMerge.ArchiveMap["archive/undated/index.html"]
+		if gotUndated != includeUndated {
+			t.Errorf("got undated archive %v, want %v", gotUndated, includeUndated)
+		}
+	}
+}
+
+func TestGenerateSitemapLastmod(t *testing.T) {
+	if err := os.MkdirAll(TestDirPath+"sitemap/rendered", 0750); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	updated, _ := time.Parse("2006-01-02", "2024-03-04")
+	testEngine := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	testEngine.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+	testEngine.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"index.html": {
+			CompleteURL: "index.html",
+			Updated:     updated.Unix(),
+			Frontmatter: parser.Frontmatter{Date: "2024-01-02", Updated: "2024-03-04"},
+		},
+	}
+
+	testEngine.GenerateSitemap(TestDirPath + "sitemap/rendered/sitemap.xml")
+
+	gotSitemap, err := os.ReadFile(TestDirPath + "sitemap/rendered/sitemap.xml")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !bytes.Contains(gotSitemap, []byte("<lastmod>2024-03-04</lastmod>")) {
+		t.Errorf("got %s, want the updated date as the lastmod", gotSitemap)
+	}
+}
+
+func TestGenerateFeedAndSitemapDates(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	if err := os.MkdirAll(siteDirPath+"rendered/", 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dates of the frontmatter are in UTC unless they set a time zone
+	timed := time.Date(2024, 1, 2, 15, 4, 5, 0, time.FixedZone("IST", 5*3600+1800))
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	e := engine.Engine{
+		SiteDataPath: siteDirPath,
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+	e.DeepDataMerge.BuildTime = time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"timed.html":   {CompleteURL: "timed.html", Date: timed.Unix(), Updated: timed.Unix(), Frontmatter: parser.Frontmatter{Title: "timed", Type: "post", Date: "2024-01-02T15:04:05+05:30"}},
+		"day.html":     {CompleteURL: "day.html", Date: day.Unix(), Updated: day.Unix(), Frontmatter: parser.Frontmatter{Title: "day", Type: "post", Date: "2024-03-04"}},
+		"undated.html": {CompleteURL: "undated.html", Frontmatter: parser.Frontmatter{Title: "undated", Type: "page"}},
+	}
+
+	e.GenerateSitemap(siteDirPath + "rendered/sitemap.xml")
+	e.GenerateFeed()
+
+	sitemap, err := os.ReadFile(siteDirPath + "rendered/sitemap.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<loc>https://example.org/timed.html</loc>\n\t\t<lastmod>2024-01-02T09:34:05Z</lastmod>",
+		"<loc>https://example.org/day.html</loc>\n\t\t<lastmod>2024-03-04</lastmod>",
+		"<loc>https://example.org/undated.html</loc>\n\t</url>",
+	} {
+		if !strings.Contains(string(sitemap), want) {
+			t.Errorf("sitemap %s is missing %q", sitemap, want)
+		}
+	}
+
+	feed, err := os.ReadFile(siteDirPath + "rendered/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<lastBuildDate>Mon, 06 May 2024 07:08:09 +0000</lastBuildDate>",
+		"<pubDate>Tue, 02 Jan 2024 09:34:05 +0000</pubDate>",
+		"<pubDate>Mon, 04 Mar 2024 00:00:00 +0000</pubDate>",
+	} {
+		if !strings.Contains(string(feed), want) {
+			t.Errorf("feed %s is missing %q", feed, want)
+		}
+	}
+	if count := strings.Count(string(feed), "<pubDate>"); count != 2 {
+		t.Errorf("got %d pubDates, want only those of the dated posts", count)
+	}
+}
+
+func TestBuildRelatedNotes(t *testing.T) {
+	newEngine := func(limit int) *engine.Engine {
+		e := &engine.Engine{
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		e.DeepDataMerge.LayoutConfig.RelatedNotes = limit
+		e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+			"hub.html":         {CompleteURL: "hub.html", Frontmatter: parser.Frontmatter{Title: "hub"}},
+			"notes/a.html":     {CompleteURL: "notes/a.html", Body: `<a href="/hub">hub</a>`, Frontmatter: parser.Frontmatter{Title: "a", Type: "note", Tags: []string{"go"}}},
+			"notes/b.html":     {CompleteURL: "notes/b.html", Body: `<a href="/hub.html#top">hub</a>`, Frontmatter: parser.Frontmatter{Title: "b", Type: "note"}},
+			"notes/c.html":     {CompleteURL: "notes/c.html", Frontmatter: parser.Frontmatter{Title: "c", Type: "note", Tags: []string{"go"}}},
+			"notes/d.html":     {CompleteURL: "notes/d.html", Body: `<a href="/notes/a.html">a</a> <a href="/hub.html">hub</a>`, Frontmatter: parser.Frontmatter{Title: "d", Type: "note", Tags: []string{"go"}}},
+			"notes/e.html":     {CompleteURL: "notes/e.html", Body: `<a href="https://example.org/hub.html">hub</a>`, Frontmatter: parser.Frontmatter{Title: "e", Type: "note"}},
+			"posts/first.html": {CompleteURL: "posts/first.html", Body: `<a href="/hub.html">hub</a>`, Frontmatter: parser.Frontmatter{Title: "first", Type: "post", Tags: []string{"go"}}},
+		}
+		e.BuildRelatedNotes()
+		return e
+	}
+
+	relatedTitles := func(e *engine.Engine, key template.URL) []string {
+		var titles []string
+		for _, note := range e.DeepDataMerge.Templates[key].RelatedNotes {
+			titles = append(titles, note.Frontmatter.Title)
+		}
+		return titles
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		key   template.URL
+		want  []string
+	}{
+		{"shared links rank above shared tags", 0, "notes/a.html", []string{"b", "c"}},
+		{"related notes are capped", 1, "notes/a.html", []string{"b"}},
+		{"direct links are not listed", 0, "notes/d.html", []string{"b", "c"}},
+		{"notes without relations", 0, "notes/e.html", nil},
+		{"pages that are not notes", 0, "posts/first.html", nil},
+		{"negative limit disables related notes", -1, "notes/a.html", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := relatedTitles(newEngine(tt.limit), tt.key); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildOrphanNotes(t *testing.T) {
+	e := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"index.html":            {CompleteURL: "index.html", Body: `<a href="/notes/linked.html">linked</a>`, Frontmatter: parser.Frontmatter{Title: "home"}},
+		"notes/linked.html":     {CompleteURL: "notes/linked.html", Frontmatter: parser.Frontmatter{Title: "linked", Type: "note"}},
+		"notes/linking.html":    {CompleteURL: "notes/linking.html", Body: `<a href="/index.html">home</a>`, Frontmatter: parser.Frontmatter{Title: "linking", Type: "note"}},
+		"notes/orphan.html":     {CompleteURL: "notes/orphan.html", Body: `<a href="/missing.html">missing</a>`, Frontmatter: parser.Frontmatter{Title: "orphan", Type: "note"}},
+		"notes/unlisted.html":   {CompleteURL: "notes/unlisted.html", Frontmatter: parser.Frontmatter{Title: "unlisted", Type: "note", SearchExclude: true}},
+		"posts/unlinked.html":   {CompleteURL: "posts/unlinked.html", Frontmatter: parser.Frontmatter{Title: "unlinked post", Type: "post"}},
+		"notes/self-links.html": {CompleteURL: "notes/self-links.html", Body: `<a href="/notes/self-links.html">self</a>`, Frontmatter: parser.Frontmatter{Title: "self-links", Type: "note"}},
+	}
+
+	e.BuildOrphanNotes()
+
+	var got []string
+	for _, note := range e.DeepDataMerge.OrphanNotes {
+		got = append(got, note.Frontmatter.Title)
+	}
+	if want := []string{"orphan", "self-links"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBuildPostNavigation(t *testing.T) {
+	e := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	posts := []parser.TemplateData{
+		{CompleteURL: "posts/newest.html", Date: 300, Frontmatter: parser.Frontmatter{Title: "newest", Type: "post", Date: "2024-03-01"}},
+		{CompleteURL: "posts/oldest.html", Date: 100, Frontmatter: parser.Frontmatter{Title: "oldest", Type: "post", Date: "2024-01-01"}},
+		{CompleteURL: "posts/middle.html", Date: 200, Frontmatter: parser.Frontmatter{Title: "middle", Type: "post", Date: "2024-02-01"}},
+		{CompleteURL: "posts/undated.html", Frontmatter: parser.Frontmatter{Title: "undated", Type: "post"}},
+	}
+	e.DeepDataMerge.Posts = slices.Clone(posts)
+	e.DeepDataMerge.Templates = make(map[template.URL]parser.TemplateData)
+	for _, post := range posts {
+		e.DeepDataMerge.Templates[post.CompleteURL] = post
+	}
+
+	e.BuildPostNavigation()
+
+	title := func(link *parser.PostLink) string {
+		if link == nil {
+			return ""
+		}
+		return link.Title
+	}
+	want := map[template.URL][2]string{
+		"posts/oldest.html":  {"", "middle"},
+		"posts/middle.html":  {"oldest", "newest"},
+		"posts/newest.html":  {"middle", ""},
+		"posts/undated.html": {"", ""},
+	}
+	for key, wantLinks := range want {
+		page := e.DeepDataMerge.Templates[key]
+		if got := [2]string{title(page.PrevPost), title(page.NextPost)}; got != wantLinks {
+			t.Errorf("%s: got prev and next %v, want %v", key, got, wantLinks)
+		}
+	}
+	for _, post := range e.DeepDataMerge.Posts {
+		if got := [2]string{title(post.PrevPost), title(post.NextPost)}; got != want[post.CompleteURL] {
+			t.Errorf("%s in Posts: got prev and next %v, want %v", post.CompleteURL, got, want[post.CompleteURL])
+		}
+	}
+}
+
+func TestProtectedPagesExcluded(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	if err := os.MkdirAll(siteDirPath+"rendered/static/", 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	e := engine.Engine{
+		SiteDataPath: siteDirPath,
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"posts/public.html":  {CompleteURL: "posts/public.html", Frontmatter: parser.Frontmatter{Title: "public", Type: "post"}},
+		"posts/members.html": {CompleteURL: "posts/members.html", Protected: true, Frontmatter: parser.Frontmatter{Title: "members", Type: "post"}},
+	}
+
+	e.GenerateSitemap(siteDirPath + "rendered/sitemap.xml")
+	e.GenerateFeed()
+	e.GenerateJSONIndex(siteDirPath)
+
+	for _, output := range []string{"sitemap.xml", "feed.xml", "static/index.json"} {
+		t.Run("protected page left out of "+output, func(t *testing.T) {
+			got, err := os.ReadFile(siteDirPath + "rendered/" + output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(got), "posts/public.html") {
+				t.Errorf("public page missing from %s", output)
+			}
+			if strings.Contains(string(got), "posts/members.html") {
+				t.Errorf("protected page listed in %s", output)
+			}
+		})
+	}
+}
+
+func TestMissingLayouts(t *testing.T) {
+	templ := template.Must(template.New("layouts").Parse(`{{define "page"}}{{end}}{{define "all-tags"}}{{end}}{{define "all-collections"}}{{end}}`))
+
+	e := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"index.html": {Frontmatter: parser.Frontmatter{Layout: "page"}},
+		"fancy.html": {Frontmatter: parser.Frontmatter{Layout: "fancy"}},
+		"notes.txt":  {Frontmatter: parser.Frontmatter{Layout: "page", OutputFormat: "txt"}},
+	}
+	e.DeepDataMerge.TagsMap = map[template.URL][]parser.TemplateData{"tags/go.html": nil}
+	e.DeepDataMerge.CollectionsMap = map[template.URL][]parser.TemplateData{"collections/posts.html": nil}
+	e.DeepDataMerge.CollectionsSubPageLayouts = map[template.URL]string{"collections/posts.html": "all-posts"}
+	e.DeepDataMerge.LayoutConfig.PostsListing = true
+	e.DeepDataMerge.LayoutConfig.PostsPath = "blog/"
+	e.DeepDataMerge.LayoutConfig.Taxonomies = []parser.TaxonomyConfig{{Name: "categories"}, {Name: "series", Layout: "page"}, {Name: "empty"}}
+	e.DeepDataMerge.TaxonomyMap = map[string]map[template.URL][]parser.TemplateData{
+		"categories": {"categories/go.html": nil},
+		"series":     {"series/intro.html": nil},
+	}
+
+	want := []string{
+		`blog/index.html: the "posts" layout is not defined by any file in layout/`,
+		`categories/: the "taxonomy-subpage" layout is not defined by any file in layout/`,
+		`collections/posts.html: the "all-posts" layout is not defined by any file in layout/`,
+		`fancy.html: the "fancy" layout is not defined by any file in layout/`,
+		`notes.txt: the "page.txt" layout is not defined by any file in layout/`,
+		`tags/: the "tag-subpage" layout is not defined by any file in layout/`,
+	}
+	if got := e.MissingLayouts(templ); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	t.Run("archive layouts", func(t *testing.T) {
+		dated := parser.TemplateData{CompleteURL: "posts/dated.html", Frontmatter: parser.Frontmatter{Date: "2024-02-23"}}
+		undated := parser.TemplateData{CompleteURL: "posts/undated.html"}
+
+		tests := []struct {
+			name    string
+			archive *parser.ArchiveConfig
+			posts   []parser.TemplateData
+			want    []string
+		}{
+			{name: "archive disabled", posts: []parser.TemplateData{dated}},
+			{
+				name:    "archive with dated posts",
+				archive: &parser.ArchiveConfig{},
+				posts:   []parser.TemplateData{dated},
+				want: []string{
+					`archive/: the "archive-subpage" layout is not defined by any file in layout/`,
+					`archive/index.html: the "all-archive" layout is not defined by any file in layout/`,
+				},
+			},
+			{
+				name:    "archive without archive pages",
+				archive: &parser.ArchiveConfig{},
+				posts:   []parser.TemplateData{undated},
+				want:    []string{`archive/index.html: the "all-archive" layout is not defined by any file in layout/`},
+			},
+			{
+				name:    "archive listing undated posts",
+				archive: &parser.ArchiveConfig{IncludeUndated: true},
+				posts:   []parser.TemplateData{undated},
+				want: []string{
+					`archive/: the "archive-subpage" layout is not defined by any file in layout/`,
+					`archive/index.html: the "all-archive" layout is not defined by any file in layout/`,
+				},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				e := engine.Engine{
+					ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				}
+				e.DeepDataMerge.Posts = tt.posts
+				e.DeepDataMerge.LayoutConfig.Archive = tt.archive
+				if got := e.MissingLayouts(templ); !slices.Equal(got, tt.want) {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestHiddenPagesExcluded(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	if err := os.MkdirAll(siteDirPath+"rendered/", 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	e := engine.Engine{
+		SiteDataPath: siteDirPath,
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"blog/index.html":  {CompleteURL: "blog/index.html", Frontmatter: parser.Frontmatter{Title: "blog"}},
+		"blog/listed.html": {CompleteURL: "blog/listed.html", Frontmatter: parser.Frontmatter{Title: "listed", Type: "post"}},
+		"blog/hidden.html": {CompleteURL: "blog/hidden.html", Frontmatter: parser.Frontmatter{Title: "hidden", Type: "post", Hidden: true}},
+	}
+
+	e.GenerateSitemap(siteDirPath + "rendered/sitemap.xml")
+	e.GenerateFeed()
+	e.BuildSections()
+
+	t.Run("hidden page left out of the feed and sections", func(t *testing.T) {
+		feed, err := os.ReadFile(siteDirPath + "rendered/feed.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(feed), "blog/listed.html") || strings.Contains(string(feed), "blog/hidden.html") {
+			t.Errorf("got feed %s, want only the listed post", feed)
+		}
+
+		children := e.DeepDataMerge.Templates["blog/index.html"].Children
+		if len(children) != 1 || children[0].CompleteURL != "blog/listed.html" {
+			t.Errorf("got children %v, want only the listed post", children)
+		}
+	})
+
+	t.Run("hidden page kept in the sitemap", func(t *testing.T) {
+		sitemap, err := os.ReadFile(siteDirPath + "rendered/sitemap.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(sitemap), "blog/hidden.html") {
+			t.Errorf("hidden page missing from the sitemap %s", sitemap)
+		}
+	})
+}
+
+func TestRenderTemplateFiles(t *testing.T) {
+	siteDirPath := TestDirPath + "template_files/"
+	if err := os.RemoveAll(siteDirPath + "rendered/"); err != nil {
+		t.Fatal(err)
+	}
+
+	e := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+	e.DeepDataMerge.LayoutConfig.SiteTitle = "Tom & Jerry"
+	e.DeepDataMerge.Posts = []parser.TemplateData{
+		{Frontmatter: parser.Frontmatter{Title: "first"}},
+		{Frontmatter: parser.Frontmatter{Title: "second"}},
+	}
+
+	e.RenderTemplateFiles(siteDirPath)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"robots.txt", "User-agent: *\nAllow: /\n\nSitemap: https://example.org/sitemap.xml\n"},
+		{"humans.txt", "/* TEAM */\nSite: Tom & Jerry\nPost: first\nPost: second\n"},
+		{"nested/browserconfig.xml", "<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<browserconfig><msapplication><tile><square150x150logo src=\"https://example.org/static/tile.png?v=1&amp;s=150\"/></tile></msapplication></browserconfig>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run("rendering "+tt.path, func(t *testing.T) {
+			got, err := os.ReadFile(siteDirPath + "rendered/" + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateTextFiles(t *testing.T) {
+	siteDirPath := TestDirPath + "text_files/"
+	if err := os.RemoveAll(siteDirPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(siteDirPath+"rendered/", 0750); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(siteDirPath) })
+
+	e := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+	e.DeepDataMerge.LayoutConfig.SiteTitle = "ssg"
+	e.DeepDataMerge.LayoutConfig.Humans = &parser.HumansConfig{
+		Team: []parser.HumansMember{
+			{Name: "Ada", Role: "Writer", Contact: "ada@example.org", Location: "London"},
+			{Name: "Linus"},
+		},
+		Thanks: []string{"The Go team"},
+		Tech:   []string{"Go", "HTML5"},
+	}
+	e.DeepDataMerge.LayoutConfig.Security = &parser.SecurityConfig{
+		Contact:            []string{"security@example.org", "https://example.org/report"},
+		Expires:            "2030-01-01T00:00:00Z",
+		Policy:             "https://example.org/policy.html",
+		PreferredLanguages: []string{"en", "de"},
+	}
+
+	e.GenerateHumansTxt(siteDirPath + "rendered/humans.txt")
+	e.GenerateSecurityTxt(siteDirPath)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{
+			"humans.txt",
+			"/* TEAM */\n\tName: Ada\n\tRole: Writer\n\tContact: ada@example.org\n\tFrom: London\n\n\tName: Linus\n\n" +
+				"/* THANKS */\n\tName: The Go team\n\n" +
+				"/* SITE */\n\tTitle: ssg\n\tSoftware: anna\n\tComponents: Go, HTML5\n",
+		},
+		{
+			".well-known/security.txt",
+			"Contact: mailto:security@example.org\nContact: https://example.org/report\nExpires: 2030-01-01T00:00:00Z\n" +
+				"Preferred-Languages: en, de\nCanonical: https://example.org/.well-known/security.txt\nPolicy: https://example.org/policy.html\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("generating "+tt.path, func(t *testing.T) {
+			got, err := os.ReadFile(siteDirPath + "rendered/" + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPostsListing(t *testing.T) {
+	templ := template.Must(template.New("layouts").Parse(`{{define "blog"}}<html><head></head><body><h1>{{.TemplateData.Frontmatter.Title}}</h1>{{range .Posts}}<a href="/{{.CompleteURL}}">{{.Frontmatter.Title}}</a>{{end}}</body></html>{{end}}`))
+	first := parser.TemplateData{CompleteURL: "posts/first.html", Date: 1704067200, Frontmatter: parser.Frontmatter{Title: "first", Type: "post", Date: "2024-01-01"}}
+	second := parser.TemplateData{CompleteURL: "posts/second.html", Date: 1706745600, Frontmatter: parser.Frontmatter{Title: "second", Type: "post", Date: "2024-02-01"}}
+	hidden := parser.TemplateData{CompleteURL: "posts/hidden.html", Frontmatter: parser.Frontmatter{Title: "hidden", Type: "post", Hidden: true}}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantFile string
+		wantLink string
+	}{
+		{"default path", "", "posts.html", "https://example.org/posts.html"},
+		{"root of the site", "index.html", "index.html", "https://example.org/"},
+		{"directory", "/blog/", "blog/index.html", "https://example.org/blog/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			siteDirPath := t.TempDir() + "/"
+			if err := os.MkdirAll(siteDirPath+"rendered/", 0750); err != nil {
+				t.Fatal(err)
+			}
+
+			e := engine.Engine{
+				SiteDataPath: siteDirPath,
+				ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			e.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+			e.DeepDataMerge.LayoutConfig.PostsListing = true
+			e.DeepDataMerge.LayoutConfig.PostsTemplate = "blog"
+			e.DeepDataMerge.LayoutConfig.PostsPath = tt.path
+			e.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+				first.CompleteURL:  first,
+				second.CompleteURL: second,
+				hidden.CompleteURL: hidden,
+			}
+			e.DeepDataMerge.Posts = []parser.TemplateData{second, first, hidden}
+
+			e.RenderPostsListing(siteDirPath, templ)
+			e.GenerateSitemap(siteDirPath + "rendered/sitemap.xml")
+			e.GenerateFeed()
+
+			listing, err := os.ReadFile(siteDirPath + "rendered/" + tt.wantFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantListing := `<h1>Posts</h1><a href="/posts/second.html">second</a><a href="/posts/first.html">first</a>`
+			if !strings.Contains(string(listing), wantListing) {
+				t.Errorf("got listing %s, want %s", listing, wantListing)
+			}
+
+			sitemap, err := os.ReadFile(siteDirPath + "rendered/sitemap.xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantEntry := "<loc>" + tt.wantLink + "</loc>\n\t\t<lastmod>2024-02-01</lastmod>"
+			if !strings.Contains(string(sitemap), wantEntry) {
+				t.Errorf("sitemap %s is missing the listing %s", sitemap, wantEntry)
+			}
+
+			feed, err := os.ReadFile(siteDirPath + "rendered/feed.xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wantLink := "<link>" + tt.wantLink + "</link>"; !strings.Contains(string(feed), wantLink) {
+				t.Errorf("feed is missing the channel link %s", wantLink)
+			}
+		})
+	}
+}
+
+func TestGenerateTagsJSON(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	if err := os.MkdirAll(siteDirPath+"rendered/", 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	e := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+	page := parser.TemplateData{}
+	e.DeepDataMerge.TagsMap = map[template.URL][]parser.TemplateData{
+		"tags/web-dev.html": {page},
+		"tags/go.html":      {page, page, page},
+		"tags/css.html":     {page},
+	}
+	e.DeepDataMerge.DisplayNames = map[template.URL]string{"tags/web-dev.html": "Web Dev"}
+
+	e.GenerateTagsJSON(siteDirPath)
+
+	var got []engine.TagsJSONEntry
+	readJSON(t, siteDirPath+"rendered/tags.json", &got)
+	want := []engine.TagsJSONEntry{
+		{Name: "go", Slug: "go", URL: "https://example.org/tags/go.html", Count: 3},
+		{Name: "css", Slug: "css", URL: "https://example.org/tags/css.html", Count: 1},
+		{Name: "Web Dev", Slug: "web-dev", URL: "https://example.org/tags/web-dev.html", Count: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRenderTaxonomies(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	files := map[string]string{
+		"content/first.md":  "---\ntitle: first\ndate: 2024-01-01\ncategories: [Go, Web]\nseries: Intro\n---\n",
+		"content/second.md": "---\ntitle: second\ndate: 2024-02-01\ncategories: go\n---\n",
+		"content/third.md":  "---\ntitle: third\ntags: [Go]\ncollections: [Posts>Tech]\n---\n",
+		// The page of the Web category has the URL of this content page
+		"content/categories/web.md": "---\ntitle: web\n---\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(siteDirPath+name), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(siteDirPath+name, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(siteDirPath+"rendered/", 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	p := parser.Parser{
+		Templates:      make(map[template.URL]parser.TemplateData),
+		TagsMap:        make(map[template.URL][]parser.TemplateData),
+		CollectionsMap: make(map[template.URL][]parser.TemplateData),
+		SiteDataPath:   siteDirPath,
+		ErrorLogger:    log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:       helpers.NewWarningCollector(),
+	}
+	p.LayoutConfig.Taxonomies = []parser.TaxonomyConfig{
+		{Name: "categories"},
+		{Name: "series", Layout: "series-page", ListLayout: "all-series"},
+	}
+	p.ParseContentDirs()
+
+	templ := template.Must(template.New("layouts").Parse(`{{define "taxonomy"}}<h1>{{.Taxonomy.Name}}</h1>{{range .Terms}}<a href="/{{.URL}}">{{.Name}} ({{.Count}})</a>{{end}}{{end}}` +
+		`{{define "taxonomy-subpage"}}{{$term := index .DeepDataMerge.Terms .PageURL}}<h1>{{$term.Frontmatter.Title}}</h1>{{range .DeepDataMerge.TermPages .PageURL}}<a href="/{{.CompleteURL}}">{{.Frontmatter.Title}}</a>{{end}}{{end}}` +
+		`{{define "series-page"}}<h1>Series</h1>{{range .DeepDataMerge.TermPages .PageURL}}{{.Frontmatter.Title}}{{end}}{{end}}` +
+		`{{define "all-tags"}}{{range .Tags}}{{.Name}} ({{.Count}}){{end}}{{end}}{{define "tags-index"}}{{end}}` +
+		`{{define "tag-subpage"}}<h1>Tag</h1>{{range .DeepDataMerge.TermPages .PageURL}}{{.Frontmatter.Title}}{{end}}{{end}}` +
+		`{{define "all-collections"}}{{range .Collections}}{{.Name}} ({{.Count}}){{end}}{{end}}{{define "collections-index"}}{{end}}` +
+		`{{define "collection-subpage"}}<h1>Collection</h1>{{range index .DeepDataMerge.CollectionsMap .PageURL}}{{.Frontmatter.Title}}{{end}}{{end}}`))
+
+	e := engine.Engine{
+		SiteDataPath: siteDirPath,
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:     helpers.NewWarningCollector(),
+	}
+	e.DeepDataMerge.LayoutConfig = p.LayoutConfig
+	e.DeepDataMerge.Templates = p.Templates
+	e.DeepDataMerge.DisplayNames = p.DisplayNames
+	e.DeepDataMerge.TagsMap = p.TagsMap
+	e.DeepDataMerge.CollectionsMap = p.CollectionsMap
+	e.DeepDataMerge.TaxonomyMap = p.TaxonomyMap
+
+	e.RenderTaxonomies(siteDirPath, templ)
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"categories.html", `<h1>categories</h1><a href="/categories/go.html">Go (2)</a><a href="/categories/web.html">Web (1)</a>`},
+		{"categories/go.html", `<h1>Go</h1><a href="/second.html">second</a><a href="/first.html">first</a>`},
+		{"series/intro.html", `<h1>Series</h1>first`},
+		// Tags and collections are rendered as built-in taxonomies
+		{"tags.html", `Go (1)`},
+		{"tags/go.html", `<h1>Tag</h1>third`},
+		{"collections.html", `Posts (1)Posts/Tech (1)`},
+		{"collections/posts/tech.html", `<h1>Collection</h1>third`},
+	}
+	for _, tt := range tests {
+		got, err := os.ReadFile(siteDirPath + "rendered/" + tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(got), tt.want) {
+			t.Errorf("%s = %s, want %s", tt.file, got, tt.want)
+		}
+	}
+
+	if _, err := os.Stat(siteDirPath + "rendered/series.html"); err == nil {
+		t.Errorf("series.html was rendered without its all-series layout")
+	}
+	if _, err := os.Stat(siteDirPath + "rendered/categories/web.html"); err == nil {
+		t.Errorf("categories/web.html was rendered over the content page")
+	}
+	wantWarnings := []string{
+		"categories/web.html: the page of the categories taxonomy has the URL of a content page, skipping it",
+		`"all-series" layout is not defined`,
+	}
+	got := e.Warnings.Warnings()
+	if len(got) != len(wantWarnings) {
+		t.Fatalf("got warnings %q, want %q", got, wantWarnings)
+	}
+	for _, want := range wantWarnings {
+		if !slices.ContainsFunc(got, func(warning string) bool { return strings.Contains(warning, want) }) {
+			t.Errorf("got warnings %q, want %q", got, want)
+		}
+	}
+	if len(p.TaxonomyMap["tags"]["tags/go.html"]) != 1 || len(p.TaxonomyMap["collections"]["collections/posts.html"]) != 1 {
+		t.Errorf("got taxonomies %v, want the tags and collections", p.TaxonomyMap)
+	}
+}
@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"html/template"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// APIVersion is the version of the schema of the JSON API, increased on changes that break its consumers
+const APIVersion = 1
+
+// APIPost is a post in the JSON API, with absolute URLs and dates in the RFC 3339 format
+type APIPost struct {
+	Slug        string   `json:"slug"`
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Date        string   `json:"date,omitempty"`
+	Updated     string   `json:"updated,omitempty"`
+	Authors     []string `json:"authors,omitempty"`
+	Tags        []string `json:"tags"`
+	Collections []string `json:"collections"`
+	Excerpt     string   `json:"excerpt,omitempty"`
+	ReadingTime int      `json:"readingTime"`
+	Cover       string   `json:"cover,omitempty"`
+	// Rendered body of the post, its internal links are relative to the root of the site
+	HTML string `json:"html"`
+}
+
+// APIPageSummary is a page listed by a tag in the JSON API
+type APIPageSummary struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Type  string `json:"type,omitempty"`
+	Date  string `json:"date,omitempty"`
+}
+
+// APITag is a tag in the JSON API, the pages are only included in the file of the tag
+type APITag struct {
+	Name  string           `json:"name"`
+	Slug  string           `json:"slug"`
+	URL   string           `json:"url"`
+	Count int              `json:"count"`
+	Pages []APIPageSummary `json:"pages,omitempty"`
+}
+
+// APIPostList is the content of api/posts.json
+type APIPostList struct {
+	Version int       `json:"version"`
+	Posts   []APIPost `json:"posts"`
+}
+
+// APIPostResponse is the content of api/posts/<slug>.json
+type APIPostResponse struct {
+	Version int     `json:"version"`
+	Post    APIPost `json:"post"`
+}
+
+// APITagList is the content of api/tags.json
+type APITagList struct {
+	Version int      `json:"version"`
+	Tags    []APITag `json:"tags"`
+}
+
+// APITagResponse is the content of api/tags/<slug>.json
+type APITagResponse struct {
+	Version int    `json:"version"`
+	Tag     APITag `json:"tag"`
+}
+
+/*
+GenerateAPI writes the JSON API of the site for headless use, with the list of posts to api/posts.json, every post
+to api/posts/<slug>.json, the list of tags to api/tags.json and the pages of every tag to api/tags/<slug>.json
+
+Posts are listed in the order of the postSort config, protected posts and posts in other output formats are left out
+*/
+func (e *Engine) GenerateAPI(siteDirPath string) {
+	apiPath := siteDirPath + "rendered/api/"
+	for _, dir := range []string{apiPath + "posts/", apiPath + "tags/"} {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
+	}
+
+	posts := e.APIPosts()
+	e.writeJSONIndex(apiPath+"posts.json", APIPostList{Version: APIVersion, Posts: posts})
+	for _, post := range posts {
+		postPath := apiPath + "posts/" + post.Slug + ".json"
+		if err := os.MkdirAll(path.Dir(postPath), 0750); err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
+		e.writeJSONIndex(postPath, APIPostResponse{Version: APIVersion, Post: post})
+	}
+
+	tags := e.APITags()
+	tagList := make([]APITag, 0, len(tags))
+	for _, tag := range tags {
+		e.writeJSONIndex(apiPath+"tags/"+tag.Slug+".json", APITagResponse{Version: APIVersion, Tag: tag})
+		tag.Pages = nil
+		tagList = append(tagList, tag)
+	}
+	e.writeJSONIndex(apiPath+"tags.json", APITagList{Version: APIVersion, Tags: tagList})
+}
+
+// APIPosts returns the posts of the JSON API in the order of the postSort config
+func (e *Engine) APIPosts() []APIPost {
+	posts := make([]APIPost, 0, len(e.DeepDataMerge.Posts))
+	for _, page := range e.DeepDataMerge.Posts {
+		if page.Protected || !page.Frontmatter.IsHTML() {
+			continue
+		}
+
+		tags := page.Frontmatter.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		collections := page.Frontmatter.Collections
+		if collections == nil {
+			collections = []string{}
+		}
+
+		posts = append(posts, APIPost{
+			Slug:        e.apiSlug(page.CompleteURL),
+			URL:         e.apiURL(string(page.CompleteURL)),
+			Title:       page.Frontmatter.Title,
+			Description: page.Frontmatter.Description,
+			Date:        apiDate(page.Date),
+			Updated:     apiDate(page.Updated),
+			Authors:     page.Authors,
+			Tags:        tags,
+			Collections: collections,
+			Excerpt:     page.Excerpt,
+			ReadingTime: page.ReadingTime,
+			Cover:       e.apiURL(string(page.CoverURL)),
+			HTML:        string(page.Body),
+		})
+	}
+	return posts
+}
+
+// APITags returns the tags of the JSON API sorted by their slug, along with the pages of every tag
+func (e *Engine) APITags() []APITag {
+	keys := make([]string, 0, len(e.DeepDataMerge.TagsMap))
+	for key := range e.DeepDataMerge.TagsMap {
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+
+	tags := make([]APITag, 0, len(keys))
+	for _, key := range keys {
+		pages := e.DeepDataMerge.TagsMap[template.URL(key)]
+		tag := APITag{
+			Name:  e.displayName(template.URL(key), "tags/"),
+			Slug:  strings.TrimSuffix(strings.TrimPrefix(key, "tags/"), ".html"),
+			URL:   e.DeepDataMerge.LayoutConfig.BaseURL + "/" + key,
+			Count: len(pages),
+			Pages: make([]APIPageSummary, 0, len(pages)),
+		}
+		for _, page := range pages {
+			tag.Pages = append(tag.Pages, APIPageSummary{
+				Title: page.Frontmatter.Title,
+				URL:   e.apiURL(string(page.CompleteURL)),
+				Type:  page.Frontmatter.Type,
+				Date:  apiDate(page.Date),
+			})
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// apiSlug returns the slug of a post, its path without the extension relative to the postsDir config, such as first for posts/first.html
+func (e *Engine) apiSlug(completeURL template.URL) string {
+	slug := strings.TrimSuffix(strings.TrimSuffix(string(completeURL), "/"), ".html")
+	if postsDir := strings.Trim(e.DeepDataMerge.LayoutConfig.PostsDir, "/"); postsDir != "" {
+		slug = strings.TrimPrefix(slug, postsDir+"/")
+	}
+	return slug
+}
+
+// apiURL returns the absolute URL of a path relative to the root of the site, URLs of other sites are returned as they are
+func (e *Engine) apiURL(link string) string {
+	if link == "" || strings.Contains(link, "://") {
+		return link
+	}
+	return e.DeepDataMerge.LayoutConfig.BaseURL + "/" + strings.TrimPrefix(link, "/")
+}
+
+// apiDate formats a Unix time in the RFC 3339 format, pages without a date have an empty date
+func apiDate(date int64) string {
+	if date == 0 {
+		return ""
+	}
+	return time.Unix(date, 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"bytes"
+	"cmp"
+	"html/template"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+// ArchiveYear stores the posts published in a year, grouped by month
+type ArchiveYear struct {
+	Year   string
+	URL    template.URL
+	Count  int
+	Months []ArchiveMonth
+}
+
+// ArchiveMonth stores the number of posts published in a month, Month is the name of the month
+type ArchiveMonth struct {
+	Month string
+	URL   template.URL
+	Count int
+}
+
+type ArchiveRootTemplateData struct {
+	DeepDataMerge DeepDataMerge
+	PageURL       template.URL
+	TemplateData  parser.TemplateData
+}
+
+// The key of the archive page listing posts without a date
+const undatedArchiveKey template.URL = "archive/undated/index.html"
+
+/*
+BuildArchive groups the posts by the year and month of their date
+The posts of every archive page are stored in ArchiveMap with the keys archive/<year>/index.html and archive/<year>/<month>/index.html
+The years and months, newest first, are stored in ArchiveYears for archive widgets in layouts
+Posts without a date are listed in archive/undated/ when the includeUndated option is set
+*/
+func (e *Engine) BuildArchive() {
+	e.DeepDataMerge.Archive = make(map[template.URL]parser.TemplateData)
+	e.DeepDataMerge.ArchiveMap = make(map[template.URL][]parser.TemplateData)
+	e.DeepDataMerge.ArchiveYears = nil
+
+	includeUndated := e.DeepDataMerge.LayoutConfig.Archive != nil && e.DeepDataMerge.LayoutConfig.Archive.IncludeUndated
+
+	// Positions of the years in ArchiveYears and of the months in the Months of their year
+	yearIndex := make(map[string]int)
+	monthIndex := make(map[template.URL]int)
+
+	for _, post := range e.DeepDataMerge.Posts {
+		if !post.Frontmatter.IsHTML() {
+			continue
+		}
+
+		if post.Frontmatter.Date == "" {
+			if includeUndated {
+				e.addToArchive(undatedArchiveKey, "Undated", post)
+			}
+			continue
+		}
+
+		date := time.Unix(post.Date, 0).UTC()
+		year := strconv.Itoa(date.Year())
+		yearKey := template.URL("archive/" + year + "/index.html")
+		monthKey := template.URL("archive/" + year + "/" + date.Format("01") + "/index.html")
+
+		e.addToArchive(yearKey, year, post)
+		e.addToArchive(monthKey, date.Month().String()+" "+year, post)
+
+		yi, ok := yearIndex[year]
+		if !ok {
+			yi = len(e.DeepDataMerge.ArchiveYears)
+			yearIndex[year] = yi
+			e.DeepDataMerge.ArchiveYears = append(e.DeepDataMerge.ArchiveYears, ArchiveYear{Year: year, URL: archiveURL(yearKey)})
+		}
+		archiveYear := &e.DeepDataMerge.ArchiveYears[yi]
+		archiveYear.Count++
+
+		mi, ok := monthIndex[monthKey]
+		if !ok {
+			mi = len(archiveYear.Months)
+			monthIndex[monthKey] = mi
+			archiveYear.Months = append(archiveYear.Months, ArchiveMonth{Month: date.Month().String(), URL: archiveURL(monthKey)})
+		}
+		archiveYear.Months[mi].Count++
+	}
+
+	// Newest years and months first, regardless of the order of the posts
+	slices.SortFunc(e.DeepDataMerge.ArchiveYears, func(a, b ArchiveYear) int {
+		return cmp.Compare(b.URL, a.URL)
+	})
+	for _, archiveYear := range e.DeepDataMerge.ArchiveYears {
+		slices.SortFunc(archiveYear.Months, func(a, b ArchiveMonth) int {
+			return cmp.Compare(b.URL, a.URL)
+		})
+	}
+}
+
+func (e *Engine) addToArchive(key template.URL, title string, post parser.TemplateData) {
+	if _, ok := e.DeepDataMerge.Archive[key]; !ok {
+		e.DeepDataMerge.Archive[key] = parser.TemplateData{
+			CompleteURL: archiveURL(key),
+			Frontmatter: parser.Frontmatter{Title: title},
+		}
+	}
+	e.DeepDataMerge.ArchiveMap[key] = append(e.DeepDataMerge.ArchiveMap[key], post)
+}
+
+// archiveURL returns the directory URL of an archive page, archive/2024/index.html is linked as archive/2024/
+func archiveURL(key template.URL) template.URL {
+	return key[:len(key)-len("index.html")]
+}
+
+// RenderArchive renders the page listing all archive years with the "all-archive" layout and every archive page with the "archive-subpage" layout
+func (e *Engine) RenderArchive(fileOutPath string, templ *template.Template) {
+	var archiveBuffer bytes.Buffer
+
+	archiveTemplateData := ArchiveRootTemplateData{
+		DeepDataMerge: e.DeepDataMerge,
+		PageURL:       "archive/index.html",
+		TemplateData: parser.TemplateData{
+			CompleteURL: "archive/",
+			Frontmatter: parser.Frontmatter{Title: "Archive"},
+		},
+	}
+
+	// Rendering the page listing all years and months
+	err := templ.ExecuteTemplate(&archiveBuffer, "all-archive", archiveTemplateData)
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+	e.writePage(fileOutPath, "archive/index.html", e.postProcess("archive/index.html", parser.TemplateData{}, archiveBuffer.Bytes()))
+
+	for key := range e.DeepDataMerge.ArchiveMap {
+		e.RenderPage(fileOutPath, key, templ, "archive-subpage")
+	}
+}
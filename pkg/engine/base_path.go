@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"regexp"
+	"strings"
+)
+
+var rootRelativeAttrRegex = regexp.MustCompile(`(href|src|action|poster)="(/[^"]*)"`)
+
+/*
+PrefixBasePath prefixes the root-relative links and asset references of a rendered page with the path of the base URL
+This allows layouts to reference "/static/style.css" while the site is hosted under a subpath such as https://user.github.io/repo/
+Protocol-relative URLs and references already prefixed with the base path are left untouched
+*/
+func (e *Engine) PrefixBasePath(html []byte) []byte {
+	basePath := e.DeepDataMerge.LayoutConfig.BasePath()
+	if basePath == "" {
+		return html
+	}
+
+	return rootRelativeAttrRegex.ReplaceAllFunc(html, func(match []byte) []byte {
+		submatches := rootRelativeAttrRegex.FindSubmatch(match)
+		attr, link := string(submatches[1]), string(submatches[2])
+		if strings.HasPrefix(link, "//") || hasBasePath(link, basePath) {
+			return match
+		}
+		return []byte(attr + `="` + basePath + link + `"`)
+	})
+}
+
+// hasBasePath reports whether a root-relative link already starts with the base path
+func hasBasePath(link string, basePath string) bool {
+	if !strings.HasPrefix(link, basePath) {
+		return false
+	}
+	rest := link[len(basePath):]
+	return rest == "" || strings.ContainsAny(rest[:1], "/?#")
+}
+
+// trimBasePath removes the base path from a root-relative link
+func (e *Engine) trimBasePath(link string) string {
+	basePath := e.DeepDataMerge.LayoutConfig.BasePath()
+	if basePath == "" || !hasBasePath(link, basePath) {
+		return link
+	}
+	rest := link[len(basePath):]
+	if !strings.HasPrefix(rest, "/") {
+		rest = "/" + rest
+	}
+	return rest
+}
@@ -0,0 +1,19 @@
+package engine
+
+import (
+	"html/template"
+	"time"
+)
+
+// buildMetaTags returns the meta tags declaring the version of anna and the build time when the buildMeta config is set
+func (e *Engine) buildMetaTags() string {
+	if !e.DeepDataMerge.LayoutConfig.BuildMeta {
+		return ""
+	}
+
+	tags := "<meta name=\"generator\" content=\"anna " + template.HTMLEscapeString(e.DeepDataMerge.Version) + "\" />\n"
+	if !e.DeepDataMerge.BuildTime.IsZero() {
+		tags += "<meta name=\"build-time\" content=\"" + e.DeepDataMerge.BuildTime.Format(time.RFC3339) + "\" />\n"
+	}
+	return tags
+}
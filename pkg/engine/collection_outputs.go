@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"html/template"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+/*
+GenerateCollectionOutputs writes the feed and search index of every collection whose metadata file enables them
+in its outputs, to collections/<name>/feed.xml and collections/<name>/index.json
+The feeds are registered for the OPML file, so they are generated before it
+*/
+func (e *Engine) GenerateCollectionOutputs() {
+	collections := make([]string, 0, len(e.DeepDataMerge.CollectionsMetadata))
+	for collection := range e.DeepDataMerge.CollectionsMetadata {
+		collections = append(collections, string(collection))
+	}
+	sort.Strings(collections)
+
+	for _, collection := range collections {
+		metadata := e.DeepDataMerge.CollectionsMetadata[template.URL(collection)]
+		outputs := metadata.Frontmatter.Outputs
+		if outputs == nil || (!outputs.Feed && !outputs.JSONIndex) {
+			continue
+		}
+
+		pages := e.collectionOutputPages(template.URL(collection), outputs.Types)
+		outputDir := strings.TrimSuffix(collection, ".html") + "/"
+		if err := os.MkdirAll(e.SiteDataPath+"rendered/"+outputDir, 0750); err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
+
+		if outputs.Feed {
+			var posts []parser.TemplateData
+			for _, key := range sortedKeys(pages) {
+				if isFeedPage(pages[key]) {
+					posts = append(posts, pages[key])
+				}
+			}
+
+			title := metadata.Frontmatter.Title
+			if title == "" {
+				title = e.displayName(template.URL(collection), "collections/")
+			}
+			e.writeFeed(outputDir+"feed.xml", e.DeepDataMerge.LayoutConfig.SiteTitle+" - "+title, "", posts)
+		}
+
+		if outputs.JSONIndex {
+			_, jsonIndex := e.buildJSONIndex(pages)
+			e.writeJSONIndex(e.SiteDataPath+"rendered/"+outputDir+"index.json", jsonIndex)
+		}
+	}
+}
+
+// collectionOutputPages returns the pages of a collection by their keys, keeping only the pages of the given types when set
+func (e *Engine) collectionOutputPages(collection template.URL, types []string) map[template.URL]parser.TemplateData {
+	inCollection := make(map[template.URL]bool)
+	for _, page := range e.DeepDataMerge.CollectionsMap[collection] {
+		inCollection[page.CompleteURL] = true
+	}
+
+	pages := make(map[template.URL]parser.TemplateData)
+	for key, page := range e.DeepDataMerge.Templates {
+		if !inCollection[page.CompleteURL] {
+			continue
+		}
+		if len(types) > 0 && !slices.Contains(types, page.Frontmatter.Type) {
+			continue
+		}
+		pages[key] = page
+	}
+	return pages
+}
+
+// sortedKeys returns the keys of the pages in sorted order, as the iteration order of maps differs between builds
+func sortedKeys(pages map[template.URL]parser.TemplateData) []template.URL {
+	keys := make([]template.URL, 0, len(pages))
+	for key := range pages {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
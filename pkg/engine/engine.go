@@ -5,8 +5,12 @@ import (
 	"html/template"
 	"log"
 	"os"
+	"path"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
 	"github.com/anna-ssg/anna/v3/pkg/parser"
 )
 
@@ -16,6 +20,9 @@ type DeepDataMerge struct {
 	// Access the data for a particular page by using the relative path to the file as the key
 	Templates map[template.URL]parser.TemplateData
 
+	// Posts stores the template data of all pages of type post
+	Posts []parser.TemplateData
+
 	// Templates stores the template data of all tag sub-pages of the site
 	Tags map[template.URL]parser.TemplateData
 
@@ -34,8 +41,57 @@ type DeepDataMerge struct {
 	// K-V pair storing the template layout name for a particular collection in the site
 	CollectionsSubPageLayouts map[template.URL]string
 
+	// K-V pair storing the metadata of a collection parsed from content/collections/<name>.md
+	CollectionsMetadata map[template.URL]parser.TemplateData
+
+	// Display names of tags and collections by the key of their page, such as "My Tag" for tags/my-tag.html
+	DisplayNames map[template.URL]string
+
+	// K-V pair storing all templates corresponding to every term of a taxonomy, by the name of the taxonomy
+	// The terms of the built-in tags and collections taxonomies are the TagsMap and CollectionsMap
+	TaxonomyMap map[string]map[template.URL][]parser.TemplateData
+
+	// Templates stores the template data of all term sub-pages of the taxonomies of the config
+	Terms map[template.URL]parser.TemplateData
+
+	// Templates stores the template data of all archive pages of the site
+	Archive map[template.URL]parser.TemplateData
+
+	// K-V pair storing all posts corresponding to a particular archive page, such as archive/2024/index.html
+	ArchiveMap map[template.URL][]parser.TemplateData
+
+	// Years and months with posts, newest first
+	ArchiveYears []ArchiveYear
+
+	// Named menus of the site, merged from the menus config and the menu field of the frontmatter of pages
+	// Access the entries of a menu by its name, such as {{.DeepDataMerge.Menus.main}}
+	Menus map[string][]parser.MenuEntry
+
+	// Notes that neither link to nor are linked from any other page
+	OrphanNotes []parser.TemplateData
+
 	// Stores the index generated for search functionality
 	JSONIndex map[template.URL]JSONIndexTemplate
+
+	// Environment of the build, EnvDevelopment while serving the site and EnvProduction otherwise
+	Env string
+
+	// Time the site was built, from SOURCE_DATE_EPOCH when it is set, available to layouts as {{.DeepDataMerge.BuildTime}}
+	BuildTime time.Time
+
+	// Version of anna building the site
+	Version string
+}
+
+// Environments of a build available to layouts as {{.DeepDataMerge.Env}}
+const (
+	EnvProduction  = "production"
+	EnvDevelopment = "development"
+)
+
+// IsDev reports whether the site is being served locally, so that layouts can include development-only content
+func (d DeepDataMerge) IsDev() bool {
+	return d.Env == EnvDevelopment
 }
 
 type Engine struct {
@@ -45,8 +101,42 @@ type Engine struct {
 	// Common logger for all engine functions
 	ErrorLogger *log.Logger
 
+	// Collects warnings reported while rendering
+	Warnings *helpers.WarningCollector
+
 	// The path to the directory being rendered
 	SiteDataPath string
+
+	// Skips the analytics script, set while serving the site locally
+	DisableAnalytics bool
+
+	// Records the time taken to render every page
+	ProfileRender bool
+
+	// Maps the known forms of an internal link to the URL of the page it points to
+	linkIndex     map[string]string
+	linkIndexOnce sync.Once
+
+	// Connects every page to the pages it links to and the pages linking to it
+	linkGraph     map[template.URL]map[template.URL]bool
+	linkGraphOnce sync.Once
+
+	// Feeds generated for the site, listed in the OPML file
+	feeds []feed
+
+	// Favicons generated from the favicon source image
+	favicons []favicon
+
+	// Render times of pages recorded when ProfileRender is set
+	renderTimes   []PageRenderTime
+	renderTimesMu sync.Mutex
+
+	// Hooks run on every rendered page in the order of registration
+	postRenderHooks []PostRenderHook
+
+	// Tags injected into the <head> of every rendered page
+	headHTML     string
+	headHTMLOnce sync.Once
 }
 
 type PageData struct {
@@ -74,6 +164,32 @@ template - stores the HTML templates parsed from the layout/ directory
 templateStartString - stores the name of the template to be passed to ExecuteTemplate()
 */
 func (e *Engine) RenderPage(fileOutPath string, pagePath template.URL, template *template.Template, templateStartString string) {
+	start := time.Now()
+
+	// Pages without transforms are streamed to the disk instead of being held in memory
+	if !e.needsBuffering(pagePath) {
+		size := e.streamPage(fileOutPath, pagePath, template, templateStartString)
+		e.recordRenderTime(pagePath, start, size)
+		return
+	}
+
+	output := e.ExecutePage(pagePath, template, templateStartString)
+	e.recordRenderTime(pagePath, start, len(output))
+
+	e.writePage(fileOutPath, pagePath, output)
+}
+
+// writePage flushes a rendered page to pagePath in the rendered/ directory
+func (e *Engine) writePage(fileOutPath string, pagePath template.URL, output []byte) {
+	// Flushing the rendered page to the disk
+	err := os.WriteFile(e.outputPath(fileOutPath, pagePath), output, 0666)
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+}
+
+// outputPath returns the path of the file pagePath is rendered to, creating its parent directories
+func (e *Engine) outputPath(fileOutPath string, pagePath template.URL) string {
 	// Creating subdirectories if the filepath contains '/'
 	if strings.Contains(string(pagePath), "/") {
 		// Extracting the directory path from the page path
@@ -87,24 +203,67 @@ func (e *Engine) RenderPage(fileOutPath string, pagePath template.URL, template
 		}
 	}
 
-	filepath := fileOutPath + "rendered/" + string(pagePath)
+	return fileOutPath + "rendered/" + string(pagePath)
+}
+
+// isHTMLPage reports whether the page at pagePath is rendered to HTML, including pages rendered without an extension
+func (e *Engine) isHTMLPage(pagePath template.URL) bool {
+	if page, ok := e.DeepDataMerge.Templates[pagePath]; ok {
+		return page.Frontmatter.IsHTML()
+	}
+	ext := path.Ext(string(pagePath))
+	return ext == ".html" || (ext == "" && e.DeepDataMerge.LayoutConfig.PageURLStyle() == parser.URLStyleExtensionless)
+}
+
+// ExecutePage executes the templateStartString template for the page at pagePath and returns the post-processed HTML
+func (e *Engine) ExecutePage(pagePath template.URL, template *template.Template, templateStartString string) []byte {
+	output := e.executeTemplate(pagePath, template, templateStartString)
+
+	// The transforms and hooks operate on HTML, pages in other output formats are written as executed
+	if !e.isHTMLPage(pagePath) {
+		return output
+	}
+
+	return e.postProcess(pagePath, e.DeepDataMerge.Templates[pagePath], output)
+}
+
+// executeTemplate executes the templateStartString template with the data of the page at pageURL
+func (e *Engine) executeTemplate(pageURL template.URL, template *template.Template, templateStartString string) []byte {
 	var buffer bytes.Buffer
 
 	pageData := PageData{
 		DeepDataMerge: e.DeepDataMerge,
-		PageURL:       pagePath,
+		PageURL:       pageURL,
 	}
 
 	// Storing the rendered HTML file to a buffer
 	err := template.ExecuteTemplate(&buffer, templateStartString, pageData)
 	if err != nil {
-		e.ErrorLogger.Println("Error at path: ", pagePath)
+		e.ErrorLogger.Println("Error at path: ", pageURL)
 		e.ErrorLogger.Fatal(err)
 	}
 
-	// Flushing data from the buffer to the disk
-	err = os.WriteFile(filepath, buffer.Bytes(), 0666)
-	if err != nil {
-		e.ErrorLogger.Fatal(err)
+	return buffer.Bytes()
+}
+
+// postProcess applies the configured transforms and the registered hooks to a rendered page before it is written to the disk
+func (e *Engine) postProcess(pagePath template.URL, page parser.TemplateData, output []byte) []byte {
+	output = e.InjectHead(output)
+	output = insertIntoHead(output, e.pageHeadTags(pagePath, page))
+
+	if e.DeepDataMerge.LayoutConfig.NormalizeLinks {
+		output = e.NormalizeInternalLinks(string(pagePath), output)
+	}
+
+	if e.DeepDataMerge.LayoutConfig.ExternalLinks != nil {
+		output = e.MarkExternalLinks(output)
 	}
+
+	if e.DeepDataMerge.LayoutConfig.ImageDimensionsEnabled() {
+		output = e.AddImageDimensions(string(pagePath), output)
+	}
+
+	output = e.PrefixBasePath(output)
+
+	return e.runPostRenderHooks(pagePath, page, output)
 }
@@ -1,11 +1,16 @@
 package engine_test
 
 import (
+	"fmt"
 	"html/template"
+	"image"
+	"image/png"
 	"log"
 	"os"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/anna-ssg/anna/v3/pkg/engine"
 	"github.com/anna-ssg/anna/v3/pkg/parser"
@@ -60,4 +65,581 @@ func TestRenderPage(t *testing.T) {
 		}
 	})
 
+	t.Run("stream a page without transforms to the disk", func(t *testing.T) {
+		imageDimensions := false
+		testEngine := engine.Engine{
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		testEngine.DeepDataMerge.LayoutConfig.ImageDimensions = &imageDimensions
+		testEngine.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+			"posts/streamed.html": {
+				CompleteURL: "got.html",
+				Frontmatter: parser.Frontmatter{
+					Title:       "Hello",
+					Date:        "2024-03-28",
+					Draft:       false,
+					Description: "Index page of site",
+					Tags:        []string{"blog", "thoughts"},
+					Layout:      "page",
+				},
+				Body: "<h1>Hello World</h1>",
+			},
+		}
+
+		templ, err := template.ParseFiles(TestDirPath + "render_page/template_input.html")
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		testEngine.RenderPage(TestDirPath+"render_page/", "posts/streamed.html", templ, "page")
+
+		gotFile, err := os.ReadFile(TestDirPath + "render_page/rendered/posts/streamed.html")
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		wantFile, err := os.ReadFile(TestDirPath + "render_page/want.html")
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+
+		if !slices.Equal(gotFile, wantFile) {
+			t.Errorf("The expected and streamed page can be found in test/engine/render_page/rendered/posts/streamed.html")
+		}
+	})
+
+	t.Run("stream a page with the default config, adding the dimensions of its images", func(t *testing.T) {
+		siteDirPath := t.TempDir() + "/"
+		if err := os.MkdirAll(siteDirPath+"rendered/static", 0750); err != nil {
+			t.Fatalf("%v", err)
+		}
+		imageFile, err := os.Create(siteDirPath + "rendered/static/wide.png")
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := png.Encode(imageFile, image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := imageFile.Close(); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		testEngine := engine.Engine{
+			SiteDataPath: siteDirPath,
+			ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		testEngine.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+			"streamed.html": {Frontmatter: parser.Frontmatter{Title: "Streamed"}},
+		}
+
+		// A streamed page is written while its template is executed, a buffered page only once it is complete
+		templ := template.Must(template.New("").Funcs(template.FuncMap{
+			"streamed": func() bool {
+				_, err := os.Stat(siteDirPath + "rendered/streamed.html")
+				return err == nil
+			},
+			"src": func() string { return "/static/wide.png" },
+		}).Parse(`{{define "page"}}<p>{{streamed}}</p><img src="{{src}}" alt="wide"><img src="/missing.png">{{end}}`))
+
+		testEngine.RenderPage(siteDirPath, "streamed.html", templ, "page")
+
+		got, err := os.ReadFile(siteDirPath + "rendered/streamed.html")
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		want := `<p>true</p><img width="40" height="20" src="/static/wide.png" alt="wide"><img src="/missing.png">`
+		if string(got) != want {
+			t.Errorf("got %s\nwant %s", got, want)
+		}
+	})
+}
+
+func BenchmarkRenderPage(b *testing.B) {
+	if err := os.MkdirAll(TestDirPath+"render_page/rendered", 0750); err != nil {
+		b.Fatal(err)
+	}
+
+	templ := template.Must(template.New("page").Parse(`<html><head></head><body>{{range .DeepDataMerge.Posts}}<p>{{.Body}}</p>{{end}}</body></html>`))
+	posts := make([]parser.TemplateData, 1000)
+	for i := range posts {
+		posts[i].Body = template.HTML(strings.Repeat("Lorem ipsum dolor sit amet ", 100))
+	}
+
+	imageDimensions := false
+	for _, buffered := range []bool{true, false} {
+		testEngine := engine.Engine{
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		testEngine.DeepDataMerge.LayoutConfig.ImageDimensions = &imageDimensions
+		testEngine.DeepDataMerge.Posts = posts
+		if buffered {
+			// A hook requires the complete HTML of the page
+			testEngine.AddPostRenderHook(engine.PostRenderHookFunc(func(html []byte, pagePath template.URL, page parser.TemplateData) ([]byte, error) {
+				return html, nil
+			}))
+		}
+
+		b.Run(fmt.Sprintf("buffered=%v", buffered), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				testEngine.RenderPage(TestDirPath+"render_page/", "bench.html", templ, "page")
+			}
+		})
+	}
+}
+
+func TestRenderPageEnv(t *testing.T) {
+	if err := os.MkdirAll(TestDirPath+"render_page/rendered", 0750); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	templ := template.Must(template.New("page").Parse(`{{.DeepDataMerge.Env}} {{if .DeepDataMerge.IsDev}}dev banner{{else}}analytics{{end}}`))
+
+	tests := []struct {
+		env  string
+		want string
+	}{
+		{engine.EnvProduction, "production analytics"},
+		{engine.EnvDevelopment, "development dev banner"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			testEngine := engine.Engine{
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			testEngine.DeepDataMerge.Env = tt.env
+
+			testEngine.RenderPage(TestDirPath+"render_page/", "env.txt", templ, "page")
+
+			got, err := os.ReadFile(TestDirPath + "render_page/rendered/env.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlowestPages(t *testing.T) {
+	if err := os.MkdirAll(TestDirPath+"render_page/rendered", 0750); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	templ := template.Must(template.New("page").Parse(`<html><head></head><body>{{.PageURL}}</body></html>`))
+	pages := []template.URL{"profile/first.html", "profile/second.html", "profile/third.html"}
+
+	t.Run("render times are recorded when profiling is enabled", func(t *testing.T) {
+		testEngine := engine.Engine{
+			ErrorLogger:   log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			ProfileRender: true,
+		}
+		for _, page := range pages {
+			testEngine.RenderPage(TestDirPath+"render_page/", page, templ, "page")
+		}
+
+		got := testEngine.SlowestPages(2)
+		if len(got) != 2 {
+			t.Fatalf("got %v pages, want 2", len(got))
+		}
+		if got[0].Duration < got[1].Duration {
+			t.Errorf("pages are not sorted by render time: %v", got)
+		}
+		for _, page := range got {
+			output, err := os.ReadFile(TestDirPath + "render_page/rendered/" + string(page.PagePath))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Size != len(output) {
+				t.Errorf("got size %v for %v, want %v", page.Size, page.PagePath, len(output))
+			}
+		}
+
+		if got := testEngine.SlowestPages(10); len(got) != len(pages) {
+			t.Errorf("got %v pages, want %v", len(got), len(pages))
+		}
+	})
+
+	t.Run("render times are not recorded by default", func(t *testing.T) {
+		testEngine := engine.Engine{
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		testEngine.RenderPage(TestDirPath+"render_page/", pages[0], templ, "page")
+
+		if got := testEngine.SlowestPages(10); len(got) != 0 {
+			t.Errorf("got %v pages, want none", len(got))
+		}
+	})
+}
+
+func TestNormalizeInternalLinks(t *testing.T) {
+	testEngine := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	testEngine.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+	testEngine.DeepDataMerge.LayoutConfig.PrettyURLs = true
+	testEngine.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"index.html":             {CompleteURL: ""},
+		"about/index.html":       {CompleteURL: "about/"},
+		"posts/index.html":       {CompleteURL: "posts/"},
+		"posts/hello/index.html": {CompleteURL: "posts/hello/"},
+		"posts/world.html":       {CompleteURL: "posts/world/"},
+	}
+
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"page without extension", `<a href="/about">`, `<a href="/about/">`},
+		{"page with extension", `<a href="/about.html">`, `<a href="/about/">`},
+		{"page with trailing slash", `<a href="/about/">`, `<a href="/about/">`},
+		{"section index", `<a href="/posts/index.html">`, `<a href="/posts/">`},
+		{"site root", `<a href="/index.html">`, `<a href="/">`},
+		{"fragment and query", `<a href="/posts/hello.html?a=b#intro">`, `<a href="/posts/hello/?a=b#intro">`},
+		{"base url prefix", `<a href="https://example.org/about.html">`, `<a href="https://example.org/about/">`},
+		{"external link", `<a href="https://example.com/about.html">`, `<a href="https://example.com/about.html">`},
+		{"anchor link", `<a href="#about">`, `<a href="#about">`},
+		{"static asset", `<link href="/static/style.css">`, `<link href="/static/style.css">`},
+		{"unknown page", `<a href="/missing.html">`, `<a href="/missing.html">`},
+		// Relative links are resolved against the directory of posts/hello.html
+		{"sibling markdown file", `<a href="world.md">`, `<a href="/posts/world/">`},
+		{"sibling page with fragment", `<a href="./world.html#intro">`, `<a href="/posts/world/#intro">`},
+		{"parent markdown file", `<a href="../about/index.md">`, `<a href="/about/">`},
+		{"parent page without extension", `<a href="../about">`, `<a href="/about/">`},
+		{"parent section", `<a href="../posts/">`, `<a href="/posts/">`},
+		{"section index markdown file", `<a href="index.md">`, `<a href="/posts/">`},
+		{"relative link outside of the site", `<a href="../../about.md">`, `<a href="../../about.md">`},
+		{"relative unknown page", `<a href="missing.md">`, `<a href="missing.md">`},
+		{"mail link", `<a href="mailto:hello@example.org">`, `<a href="mailto:hello@example.org">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(testEngine.NormalizeInternalLinks("posts/hello.html", []byte(tt.html)))
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostRenderHooks(t *testing.T) {
+	testEngine := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	testEngine.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"posts/hook.html": {
+			CompleteURL: "posts/hook.html",
+			Frontmatter: parser.Frontmatter{Title: "Hook"},
+		},
+	}
+
+	templ, err := template.New("page").Parse(`<a href="/about.html">About</a><img src="/static/anna.png"><a href="//cdn.example.org/x.js">CDN</a><a href="#top">Top</a>`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var gotTitles []string
+	testEngine.AddPostRenderHook(engine.PostRenderHookFunc(func(html []byte, pagePath template.URL, page parser.TemplateData) ([]byte, error) {
+		gotTitles = append(gotTitles, page.Frontmatter.Title)
+		return html, nil
+	}))
+	testEngine.AddPostRenderHook(engine.NewAbsoluteURLHook("https://example.org/"))
+
+	got := string(testEngine.ExecutePage("posts/hook.html", templ, "page"))
+
+	want := `<a href="https://example.org/about.html">About</a><img src="https://example.org/static/anna.png"><a href="//cdn.example.org/x.js">CDN</a><a href="#top">Top</a>`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if !slices.Equal(gotTitles, []string{"Hook"}) {
+		t.Errorf("got %v, want the hook to receive the page data", gotTitles)
+	}
+}
+
+func TestExecutePageOutputFormat(t *testing.T) {
+	testEngine := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	testEngine.AddPostRenderHook(engine.NewAbsoluteURLHook("https://example.org/"))
+
+	templ, err := template.New("page.txt").Parse(`See /about.html`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// Hooks only apply to HTML pages
+	if got := string(testEngine.ExecutePage("posts/first.txt", templ, "page.txt")); got != "See /about.html" {
+		t.Errorf("got %s, want the page to be written as executed", got)
+	}
+}
+
+func TestRenderReaderPages(t *testing.T) {
+	if err := os.MkdirAll(TestDirPath+"reader/rendered", 0750); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	testEngine := engine.Engine{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	testEngine.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+	testEngine.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+		"posts/first.html": {
+			CompleteURL: "posts/first.html",
+			Frontmatter: parser.Frontmatter{Title: "First", Type: "post"},
+			Body:        "<p>Hello</p>",
+			ReaderURL:   "posts/first/reader.html",
+		},
+		"about.html": {
+			CompleteURL: "about.html",
+			Frontmatter: parser.Frontmatter{Title: "About", Type: "page"},
+		},
+	}
+
+	templ, err := template.New("reader").Parse(`{{define "page"}}<head></head>{{end}}{{define "reader"}}{{$PageData := index .DeepDataMerge.Templates .PageURL}}<head></head>{{$PageData.Frontmatter.Title}}{{$PageData.Body}}{{end}}`)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	testEngine.RenderReaderPages(TestDirPath+"reader/", templ)
+
+	gotReader, err := os.ReadFile(TestDirPath + "reader/rendered/posts/first/reader.html")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	wantReader := "<head><link rel=\"canonical\" href=\"https://example.org/posts/first.html\" />\n</head>First<p>Hello</p>"
+	if string(gotReader) != wantReader {
+		t.Errorf("got %s, want %s", gotReader, wantReader)
+	}
+
+	if _, err := os.Stat(TestDirPath + "reader/rendered/about/reader.html"); !os.IsNotExist(err) {
+		t.Errorf("want no reader page for pages that are not posts")
+	}
+
+	gotPage := string(testEngine.ExecutePage("posts/first.html", templ, "page"))
+	wantPage := "<head><link rel=\"alternate\" href=\"https://example.org/posts/first/reader.html\" title=\"Reader view\" />\n</head>"
+	if gotPage != wantPage {
+		t.Errorf("got %s, want %s", gotPage, wantPage)
+	}
+}
+
+func TestAddImageDimensions(t *testing.T) {
+	if err := os.MkdirAll(TestDirPath+"image_dimensions/rendered/static", 0750); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	imageFile, err := os.Create(TestDirPath + "image_dimensions/rendered/static/wide.png")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := png.Encode(imageFile, image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := imageFile.Close(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	testEngine := engine.Engine{
+		SiteDataPath: TestDirPath + "image_dimensions/",
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	testEngine.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+
+	tests := []struct {
+		name     string
+		pagePath string
+		html     string
+		want     string
+	}{
+		{"root-relative image", "posts/first.html", `<img src="/static/wide.png" alt="wide">`, `<img width="40" height="20" src="/static/wide.png" alt="wide">`},
+		{"relative image", "static/page.html", `<img src="wide.png?v=1">`, `<img width="40" height="20" src="wide.png?v=1">`},
+		{"image prefixed with the base URL", "index.html", `<img src="https://example.org/static/wide.png">`, `<img width="40" height="20" src="https://example.org/static/wide.png">`},
+		{"dimensions set by the author", "index.html", `<img width="10" src="/static/wide.png">`, `<img width="10" src="/static/wide.png">`},
+		{"remote image", "index.html", `<img src="https://cdn.example.org/wide.png">`, `<img src="https://cdn.example.org/wide.png">`},
+		{"missing image", "index.html", `<img src="/static/missing.png">`, `<img src="/static/missing.png">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(testEngine.AddImageDimensions(tt.pagePath, []byte(tt.html))); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// An image replaced by another one is measured again, even if its modification time is kept by the copy
+	info, err := os.Stat(TestDirPath + "image_dimensions/rendered/static/wide.png")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	imageFile, err = os.Create(TestDirPath + "image_dimensions/rendered/static/wide.png")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := png.Encode(imageFile, image.NewRGBA(image.Rect(0, 0, 30, 30))); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := imageFile.Close(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := os.Chtimes(TestDirPath+"image_dimensions/rendered/static/wide.png", info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	got := string(testEngine.AddImageDimensions("index.html", []byte(`<img src="/static/wide.png">`)))
+	want := `<img width="30" height="30" src="/static/wide.png">`
+	if got != want {
+		t.Errorf("got %s after replacing the image, want %s", got, want)
+	}
+}
+
+func TestPrefixBasePath(t *testing.T) {
+	page := `<link href="/static/style.css"><a href="/about">About</a><a href="/repo/docs.html">Docs</a><img src="/static/anna.png"><a href="//cdn.example.org/x.js">CDN</a><a href="#top">Top</a><a href="https://example.org/">Home</a>`
+
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+	}{
+		{
+			"root hosting",
+			"https://example.org",
+			`<link href="/static/style.css"><a href="/about.html">About</a><a href="/repo/docs.html">Docs</a><img src="/static/anna.png"><a href="//cdn.example.org/x.js">CDN</a><a href="#top">Top</a><a href="https://example.org/">Home</a>`,
+		},
+		{
+			"subpath hosting",
+			"https://user.github.io/repo",
+			`<link href="/repo/static/style.css"><a href="/repo/about.html">About</a><a href="/repo/docs.html">Docs</a><img src="/repo/static/anna.png"><a href="//cdn.example.org/x.js">CDN</a><a href="#top">Top</a><a href="https://example.org/">Home</a>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testEngine := engine.Engine{
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			testEngine.DeepDataMerge.LayoutConfig.BaseURL = tt.baseURL
+			testEngine.DeepDataMerge.LayoutConfig.NormalizeLinks = true
+			testEngine.DeepDataMerge.Templates = map[template.URL]parser.TemplateData{
+				"about.html": {CompleteURL: "about.html"},
+				"docs.html":  {CompleteURL: "docs.html"},
+			}
+
+			templ, err := template.New("page").Parse(page)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			if got := string(testEngine.ExecutePage("about.html", templ, "page")); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkExternalLinks(t *testing.T) {
+	page := `<a href="https://github.com/anna-ssg/anna">GitHub</a><a href="https://example.org/docs.html">Docs</a><a href="/about.html">About</a><a href="#top">Top</a><a href="mailto:anna@example.org">Mail</a><a href="//cdn.example.org/x">CDN</a><a href="https://go.dev" target="_self" rel="external">Go</a>`
+
+	disabled := false
+	tests := []struct {
+		name   string
+		config parser.ExternalLinksConfig
+		want   string
+	}{
+		{
+			"new tab by default",
+			parser.ExternalLinksConfig{},
+			`<a rel="noopener" target="_blank" href="https://github.com/anna-ssg/anna">GitHub</a><a href="https://example.org/docs.html">Docs</a><a href="/about.html">About</a><a href="#top">Top</a><a href="mailto:anna@example.org">Mail</a><a rel="noopener" target="_blank" href="//cdn.example.org/x">CDN</a><a href="https://go.dev" target="_self" rel="external noopener">Go</a>`,
+		},
+		{
+			"nofollow without a new tab",
+			parser.ExternalLinksConfig{NewTab: &disabled, NoFollow: true},
+			`<a rel="nofollow" href="https://github.com/anna-ssg/anna">GitHub</a><a href="https://example.org/docs.html">Docs</a><a href="/about.html">About</a><a href="#top">Top</a><a href="mailto:anna@example.org">Mail</a><a rel="nofollow" href="//cdn.example.org/x">CDN</a><a href="https://go.dev" target="_self" rel="external nofollow">Go</a>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testEngine := engine.Engine{
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			testEngine.DeepDataMerge.LayoutConfig.BaseURL = "https://example.org"
+			testEngine.DeepDataMerge.LayoutConfig.ExternalLinks = &tt.config
+
+			if got := string(testEngine.MarkExternalLinks([]byte(page))); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildMeta(t *testing.T) {
+	for _, buildMeta := range []bool{false, true} {
+		t.Run(fmt.Sprintf("meta tags with buildMeta %v", buildMeta), func(t *testing.T) {
+			e := engine.Engine{
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			e.DeepDataMerge.LayoutConfig.BuildMeta = buildMeta
+			e.DeepDataMerge.Version = "v3.0.0"
+			e.DeepDataMerge.BuildTime = time.Unix(1700000000, 0).UTC()
+
+			want := "<head></head>"
+			if buildMeta {
+				want = "<head><meta name=\"generator\" content=\"anna v3.0.0\" />\n<meta name=\"build-time\" content=\"2023-11-14T22:13:20Z\" />\n</head>"
+			}
+			if got := string(e.InjectHead([]byte("<head></head>"))); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestInlineCSS(t *testing.T) {
+	e := engine.Engine{
+		SiteDataPath: TestDirPath + "inline_css/",
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	e.DeepDataMerge.LayoutConfig.InlineCSS = []string{"static/critical.css"}
+
+	page := `<head><link rel="stylesheet" href="/static/critical.css?v=1" /><link rel="stylesheet" href="/static/style.css" /><link rel="icon" href="/static/critical.css" /></head>`
+	want := `<head><link rel="stylesheet" href="/static/style.css" /><link rel="icon" href="/static/critical.css" /><style>
+body {
+  margin: 0;
+}
+</style>
+</head>`
+
+	if got := string(e.InjectHead([]byte(page))); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPrintCSS(t *testing.T) {
+	tests := []struct {
+		name     string
+		printCSS string
+		wantHref string
+	}{
+		{"static stylesheet", "static/print.css", "/static/print.css"},
+		{"root-relative stylesheet", "/static/print.css", "/static/print.css"},
+		{"remote stylesheet", "https://cdn.example.org/print.css", "https://cdn.example.org/print.css"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := engine.Engine{
+				SiteDataPath: TestDirPath + "print_css/",
+				ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			e.DeepDataMerge.LayoutConfig.PrintCSS = tt.printCSS
+
+			want := "<head><style media=\"print\">@page { margin: 2cm; }</style>\n<link rel=\"stylesheet\" href=\"" + tt.wantHref + "\" media=\"print\" />\n</head>"
+			if got := string(e.InjectHead([]byte("<head></head>"))); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
 }
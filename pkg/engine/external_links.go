@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	anchorTagRegex    = regexp.MustCompile(`<a\b[^>]*>`)
+	anchorHrefRegex   = regexp.MustCompile(`\shref="([^"]*)"`)
+	anchorTargetRegex = regexp.MustCompile(`\starget=`)
+	anchorRelRegex    = regexp.MustCompile(`\srel="([^"]*)"`)
+)
+
+/*
+MarkExternalLinks adds the configured target and rel attributes to the links of a rendered page which point to other sites
+Links are external if they are absolute or protocol-relative and their host differs from the host of the base URL
+Relative links, anchors and mailto links are left untouched, as are target attributes set by the author
+*/
+func (e *Engine) MarkExternalLinks(html []byte) []byte {
+	config := e.DeepDataMerge.LayoutConfig.ExternalLinks
+
+	var relTokens []string
+	if config.NewTabEnabled() {
+		relTokens = append(relTokens, "noopener")
+	}
+	if config.NoFollow {
+		relTokens = append(relTokens, "nofollow")
+	}
+
+	return anchorTagRegex.ReplaceAllFunc(html, func(tag []byte) []byte {
+		match := anchorHrefRegex.FindSubmatch(tag)
+		if match == nil || !e.isExternalLink(string(match[1])) {
+			return tag
+		}
+
+		anchor := string(tag)
+		if config.NewTabEnabled() && !anchorTargetRegex.MatchString(anchor) {
+			anchor = `<a target="_blank"` + anchor[len("<a"):]
+		}
+
+		if relMatch := anchorRelRegex.FindStringSubmatch(anchor); relMatch != nil {
+			rel := strings.Fields(relMatch[1])
+			for _, token := range relTokens {
+				if !slices.Contains(rel, token) {
+					rel = append(rel, token)
+				}
+			}
+			anchor = strings.Replace(anchor, relMatch[0], ` rel="`+strings.Join(rel, " ")+`"`, 1)
+		} else if len(relTokens) > 0 {
+			anchor = `<a rel="` + strings.Join(relTokens, " ") + `"` + anchor[len("<a"):]
+		}
+
+		return []byte(anchor)
+	})
+}
+
+// isExternalLink reports whether a link points to a host other than the host of the base URL
+func (e *Engine) isExternalLink(href string) bool {
+	if !strings.HasPrefix(href, "http://") && !strings.HasPrefix(href, "https://") && !strings.HasPrefix(href, "//") {
+		return false
+	}
+
+	link, err := url.Parse(href)
+	if err != nil || link.Host == "" {
+		return false
+	}
+
+	baseURL, err := url.Parse(e.DeepDataMerge.LayoutConfig.BaseURL)
+	if err != nil || baseURL.Host == "" {
+		return true
+	}
+	return !strings.EqualFold(link.Host, baseURL.Host)
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"errors"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"io/fs"
+	"os"
+	"strconv"
+
+	"golang.org/x/image/draw"
+)
+
+var (
+	defaultFaviconSizes       = []int{16, 32, 48, 192, 512}
+	defaultAppleTouchIconSize = 180
+	defaultFaviconSource      = "static/icon.png"
+)
+
+type favicon struct {
+	rel  string
+	size int
+	// Path of the favicon relative to rendered/
+	path string
+}
+
+func (f favicon) sizes() string {
+	return strconv.Itoa(f.size) + "x" + strconv.Itoa(f.size)
+}
+
+/*
+GenerateFavicons resizes the favicon source image to the configured sizes and an apple-touch-icon
+The favicons are written to the root of rendered/ and linked in the head of every page
+Favicons are not generated if the source image does not exist
+*/
+func (e *Engine) GenerateFavicons(fileOutPath string) {
+	faviconConfig := e.DeepDataMerge.LayoutConfig.Favicon
+
+	sourcePath := faviconConfig.Source
+	if sourcePath == "" {
+		sourcePath = defaultFaviconSource
+	}
+
+	sourceFile, err := os.Open(fileOutPath + sourcePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		// A missing source image is only reported when it was explicitly configured
+		if faviconConfig.Source != "" {
+			e.Warnings.Warn("Favicon source not found: ", sourcePath)
+		}
+		return
+	}
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+	defer func() {
+		err = sourceFile.Close()
+		if err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
+	}()
+
+	sourceImage, _, err := image.Decode(sourceFile)
+	if err != nil {
+		e.ErrorLogger.Println("Error decoding favicon source: ", sourcePath)
+		e.ErrorLogger.Fatal(err)
+	}
+
+	sizes := faviconConfig.Sizes
+	if len(sizes) == 0 {
+		sizes = defaultFaviconSizes
+	}
+	appleTouchIconSize := faviconConfig.AppleTouchIconSize
+	if appleTouchIconSize == 0 {
+		appleTouchIconSize = defaultAppleTouchIconSize
+	}
+
+	e.favicons = nil
+	for _, size := range sizes {
+		icon := favicon{rel: "icon", size: size}
+		icon.path = "favicon-" + icon.sizes() + ".png"
+		e.favicons = append(e.favicons, icon)
+	}
+	e.favicons = append(e.favicons, favicon{rel: "apple-touch-icon", size: appleTouchIconSize, path: "apple-touch-icon.png"})
+
+	for _, icon := range e.favicons {
+		resizedImage := image.NewRGBA(image.Rect(0, 0, icon.size, icon.size))
+		draw.CatmullRom.Scale(resizedImage, resizedImage.Bounds(), sourceImage, sourceImage.Bounds(), draw.Over, nil)
+
+		iconFile, err := os.Create(fileOutPath + "rendered/" + icon.path)
+		if err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
+
+		err = png.Encode(iconFile, resizedImage)
+		if err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
+
+		err = iconFile.Close()
+		if err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+)
+
+// headInjections returns the tags injected into the <head> of every rendered page
+func (e *Engine) headInjections() string {
+	e.headHTMLOnce.Do(func() {
+		var head strings.Builder
+
+		for _, icon := range e.favicons {
+			head.WriteString("<link rel=\"" + icon.rel + "\" type=\"image/png\" sizes=\"" + icon.sizes() + "\" href=\"/" + icon.path + "\" />\n")
+		}
+
+		if pwa := e.DeepDataMerge.LayoutConfig.PWA; pwa != nil {
+			head.WriteString("<link rel=\"manifest\" href=\"/manifest.webmanifest\" />\n")
+			if pwa.ThemeColor != "" {
+				head.WriteString("<meta name=\"theme-color\" content=\"" + template.HTMLEscapeString(pwa.ThemeColor) + "\" />\n")
+			}
+			if pwa.ServiceWorker {
+				head.WriteString("<script>if (\"serviceWorker\" in navigator) { navigator.serviceWorker.register(\"" + e.DeepDataMerge.LayoutConfig.BasePath() + "/sw.js\"); }</script>\n")
+			}
+		}
+
+		head.WriteString(e.inlineStyles())
+		head.WriteString(e.printStylesheet())
+		head.WriteString(e.analyticsSnippet())
+		head.WriteString(e.buildMetaTags())
+
+		e.headHTML = head.String()
+	})
+	return e.headHTML
+}
+
+/*
+InjectHead inserts the tags generated from the site configuration before the closing </head> tag of a rendered page
+The stylesheets inlined into the head are no longer linked
+*/
+func (e *Engine) InjectHead(html []byte) []byte {
+	return insertIntoHead(e.RemoveInlinedStylesheets(html), e.headInjections())
+}
+
+// insertIntoHead inserts the given tags before the closing </head> tag of a rendered page
+func insertIntoHead(html []byte, injections string) []byte {
+	if injections == "" {
+		return html
+	}
+
+	index := bytes.Index(html, []byte("</head>"))
+	if index == -1 {
+		return html
+	}
+
+	output := make([]byte, 0, len(html)+len(injections))
+	output = append(output, html[:index]...)
+	output = append(output, injections...)
+	output = append(output, html[index:]...)
+	return output
+}
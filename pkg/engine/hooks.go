@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+/*
+PostRenderHook transforms the HTML of a rendered page before it is written to the disk
+
+pagePath - the path of the page relative to rendered/, such as posts/file1.html
+
+page - the template data of the page, empty for pages generated by the engine such as tag and collection pages
+*/
+type PostRenderHook interface {
+	PostRender(html []byte, pagePath template.URL, page parser.TemplateData) ([]byte, error)
+}
+
+// PostRenderHookFunc allows the use of ordinary functions as post-render hooks
+type PostRenderHookFunc func(html []byte, pagePath template.URL, page parser.TemplateData) ([]byte, error)
+
+func (f PostRenderHookFunc) PostRender(html []byte, pagePath template.URL, page parser.TemplateData) ([]byte, error) {
+	return f(html, pagePath, page)
+}
+
+/*
+AddPostRenderHook registers a hook run on every rendered page
+Hooks run in the order of registration, after the built-in transforms such as head injection
+Hooks must be registered before rendering and must be safe for concurrent use, as pages are rendered in parallel
+*/
+func (e *Engine) AddPostRenderHook(hook PostRenderHook) {
+	e.postRenderHooks = append(e.postRenderHooks, hook)
+}
+
+func (e *Engine) runPostRenderHooks(pagePath template.URL, page parser.TemplateData, output []byte) []byte {
+	if len(e.postRenderHooks) == 0 {
+		return output
+	}
+
+	for _, hook := range e.postRenderHooks {
+		var err error
+		output, err = hook.PostRender(output, pagePath, page)
+		if err != nil {
+			e.ErrorLogger.Println("Error at path: ", pagePath)
+			e.ErrorLogger.Fatal(err)
+		}
+	}
+	return output
+}
+
+var rootRelativeURLRegex = regexp.MustCompile(`(href|src)="/([^/"][^"]*)?"`)
+
+// NewAbsoluteURLHook returns a hook rewriting root-relative href and src attributes ("/about.html") to absolute URLs prefixed with baseURL
+func NewAbsoluteURLHook(baseURL string) PostRenderHook {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return PostRenderHookFunc(func(html []byte, pagePath template.URL, page parser.TemplateData) ([]byte, error) {
+		return rootRelativeURLRegex.ReplaceAll(html, []byte(`$1="`+baseURL+`/$2"`)), nil
+	})
+}
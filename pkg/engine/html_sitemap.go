@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"bytes"
+	"html/template"
+	"sort"
+	"strings"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+// The key of the human-readable sitemap
+const htmlSitemapKey template.URL = "sitemap.html"
+
+// SitemapSection stores the pages of a top-level directory of the site, Title and URL are those of its index page when it has one
+// The section of the pages at the root of the site has an empty URL
+type SitemapSection struct {
+	Title string
+	URL   template.URL
+	Pages []parser.TemplateData
+}
+
+type HTMLSitemapTemplateData struct {
+	DeepDataMerge DeepDataMerge
+	PageURL       template.URL
+	TemplateData  parser.TemplateData
+	Sections      []SitemapSection
+}
+
+// sitemapKeys returns the sorted keys of the pages listed in sitemaps, drafts, protected pages and pages in other output formats are left out
+func (e *Engine) sitemapKeys() []string {
+	keys := make([]string, 0, len(e.DeepDataMerge.Templates))
+	for key, page := range e.DeepDataMerge.Templates {
+		if page.Frontmatter.IsHTML() && !page.Protected && !page.Frontmatter.Draft {
+			keys = append(keys, string(key))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+HTMLSitemapSections groups the pages of the XML sitemap by the top-level directory they are in, in the order of their paths
+The pages at the root of the site come first, and hidden pages are left out as the sitemap is a listing of the site
+*/
+func (e *Engine) HTMLSitemapSections() []SitemapSection {
+	var sections []SitemapSection
+	sectionIndex := make(map[string]int)
+
+	for _, key := range e.sitemapKeys() {
+		page := e.DeepDataMerge.Templates[template.URL(key)]
+		if page.Frontmatter.Hidden {
+			continue
+		}
+
+		dir, _, ok := strings.Cut(key, "/")
+		if !ok {
+			dir = ""
+		}
+
+		i, ok := sectionIndex[dir]
+		if !ok {
+			i = len(sections)
+			sectionIndex[dir] = i
+			section := SitemapSection{Title: dir}
+			if dir != "" {
+				section.URL = template.URL(dir + "/")
+				if root, ok := e.DeepDataMerge.Templates[template.URL(dir+"/index.html")]; ok {
+					section.Title = root.Frontmatter.Title
+					section.URL = root.CompleteURL
+				}
+			}
+			sections = append(sections, section)
+		}
+
+		// The index page of a section is its heading
+		if dir != "" && key == dir+"/index.html" {
+			continue
+		}
+		sections[i].Pages = append(sections[i].Pages, page)
+	}
+
+	// The pages at the root of the site are listed first
+	if i, ok := sectionIndex[""]; ok && i > 0 {
+		root := sections[i]
+		copy(sections[1:i+1], sections[:i])
+		sections[0] = root
+	}
+	return sections
+}
+
+// RenderHTMLSitemap renders the sections of the site to sitemap.html with the "sitemap" layout
+func (e *Engine) RenderHTMLSitemap(fileOutPath string, templ *template.Template) {
+	var sitemapBuffer bytes.Buffer
+
+	sitemapTemplateData := HTMLSitemapTemplateData{
+		DeepDataMerge: e.DeepDataMerge,
+		PageURL:       htmlSitemapKey,
+		TemplateData: parser.TemplateData{
+			CompleteURL: htmlSitemapKey,
+			Frontmatter: parser.Frontmatter{Title: "Sitemap"},
+		},
+		Sections: e.HTMLSitemapSections(),
+	}
+
+	err := templ.ExecuteTemplate(&sitemapBuffer, "sitemap", sitemapTemplateData)
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+	e.writePage(fileOutPath, htmlSitemapKey, e.postProcess(htmlSitemapKey, parser.TemplateData{}, sitemapBuffer.Bytes()))
+}
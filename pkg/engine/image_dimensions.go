@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	imgTagRegex  = regexp.MustCompile(`<img\b[^>]*>`)
+	imgSrcRegex  = regexp.MustCompile(`\ssrc="([^"]*)"`)
+	imgSizeRegex = regexp.MustCompile(`\s(width|height)=`)
+)
+
+// imageSize stores the intrinsic dimensions of an image, zero for files that cannot be decoded
+type imageSize struct {
+	width  int
+	height int
+}
+
+/*
+imageSizeCache maps the SHA-256 hash of the content of an image to its dimensions
+The rendered copies of static files are rewritten on every build, so images are keyed by their content rather than
+their path and modification time. An image referenced by several pages, or copied under several paths, is decoded once
+Rebuilds of anna serve run in a new process, so the cache only lasts for a single build
+*/
+var imageSizeCache sync.Map
+
+/*
+AddImageDimensions adds the intrinsic width and height to the <img> tags of a rendered page which set neither
+Only local images present in the rendered directory are measured, remote images are skipped
+Only the header of the image is decoded to read its dimensions
+*/
+func (e *Engine) AddImageDimensions(pagePath string, html []byte) []byte {
+	return imgTagRegex.ReplaceAllFunc(html, func(img []byte) []byte {
+		if imgSizeRegex.Match(img) {
+			return img
+		}
+
+		match := imgSrcRegex.FindSubmatch(img)
+		if match == nil {
+			return img
+		}
+
+		filePath, ok := e.localImagePath(pagePath, string(match[1]))
+		if !ok {
+			return img
+		}
+
+		size := cachedImageSize(filePath)
+		if size.width == 0 || size.height == 0 {
+			return img
+		}
+
+		attrs := ` width="` + strconv.Itoa(size.width) + `" height="` + strconv.Itoa(size.height) + `"`
+		return append([]byte("<img"+attrs), img[len("<img"):]...)
+	})
+}
+
+// localImagePath resolves the src of an image on the page at pagePath to a file in the rendered directory
+func (e *Engine) localImagePath(pagePath string, src string) (string, bool) {
+	// Images prefixed with the base URL are local
+	baseURL := strings.TrimSuffix(e.DeepDataMerge.LayoutConfig.BaseURL, "/")
+	if baseURL != "" && strings.HasPrefix(src, baseURL+"/") {
+		src = strings.TrimPrefix(src, baseURL)
+	}
+
+	if src == "" || strings.Contains(src, "://") || strings.HasPrefix(src, "//") || strings.HasPrefix(src, "data:") {
+		return "", false
+	}
+
+	if index := strings.IndexAny(src, "?#"); index != -1 {
+		src = src[:index]
+	}
+
+	var relPath string
+	if strings.HasPrefix(src, "/") {
+		relPath = path.Clean(strings.TrimPrefix(e.trimBasePath(src), "/"))
+	} else {
+		relPath = path.Join(path.Dir(pagePath), src)
+	}
+	if relPath == "." || strings.HasPrefix(relPath, "../") {
+		return "", false
+	}
+
+	return e.SiteDataPath + "rendered/" + relPath, true
+}
+
+// cachedImageSize returns the dimensions of the image at filePath, decoding it only if no image with the same content was measured
+func cachedImageSize(filePath string) imageSize {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return imageSize{}
+	}
+
+	hash := sha256.Sum256(content)
+	if size, ok := imageSizeCache.Load(hash); ok {
+		return size.(imageSize)
+	}
+
+	var size imageSize
+	if config, _, err := image.DecodeConfig(bytes.NewReader(content)); err == nil {
+		size = imageSize{width: config.Width, height: config.Height}
+	}
+
+	imageSizeCache.Store(hash, size)
+	return size
+}
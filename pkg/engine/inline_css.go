@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	linkTagRegex  = regexp.MustCompile(`<link\b[^>]*>`)
+	linkHrefRegex = regexp.MustCompile(`\shref="([^"]*)"`)
+	linkRelRegex  = regexp.MustCompile(`\srel="([^"]*)"`)
+)
+
+// inlineCSSPath returns the path of a stylesheet of the inlineCSS config relative to the site directory
+func inlineCSSPath(stylesheet string) string {
+	return strings.TrimPrefix(stylesheet, "/")
+}
+
+/*
+inlineStyles returns the contents of the stylesheets of the inlineCSS config in a single <style> tag, in the order they are listed
+The stylesheets must be present in the static/ directory, and the build fails when any of them is missing
+*/
+func (e *Engine) inlineStyles() string {
+	stylesheets := e.DeepDataMerge.LayoutConfig.InlineCSS
+	if len(stylesheets) == 0 {
+		return ""
+	}
+
+	var styles strings.Builder
+	styles.WriteString("<style>\n")
+	for _, stylesheet := range stylesheets {
+		stylesheetPath := inlineCSSPath(stylesheet)
+		if !strings.HasPrefix(stylesheetPath, "static/") {
+			e.ErrorLogger.Fatal("Inlined stylesheet must be placed in the static/ directory: ", stylesheet)
+		}
+
+		css, err := os.ReadFile(e.SiteDataPath + stylesheetPath)
+		if err != nil {
+			e.ErrorLogger.Fatal("Inlined stylesheet not found: ", err)
+		}
+
+		// A closing style tag in the stylesheet would end the inlined styles early
+		styles.WriteString(strings.ReplaceAll(strings.TrimSpace(string(css)), "</style", `<\/style`))
+		styles.WriteString("\n")
+	}
+	styles.WriteString("</style>\n")
+	return styles.String()
+}
+
+// RemoveInlinedStylesheets removes the <link> tags of the stylesheets inlined with the inlineCSS config from a rendered page
+func (e *Engine) RemoveInlinedStylesheets(html []byte) []byte {
+	stylesheets := e.DeepDataMerge.LayoutConfig.InlineCSS
+	if len(stylesheets) == 0 {
+		return html
+	}
+
+	inlined := make(map[string]bool, len(stylesheets))
+	for _, stylesheet := range stylesheets {
+		inlined[inlineCSSPath(stylesheet)] = true
+	}
+
+	return linkTagRegex.ReplaceAllFunc(html, func(tag []byte) []byte {
+		rel := linkRelRegex.FindSubmatch(tag)
+		if rel == nil || !strings.Contains(string(rel[1]), "stylesheet") {
+			return tag
+		}
+
+		href := linkHrefRegex.FindSubmatch(tag)
+		if href == nil {
+			return tag
+		}
+		hrefPath, _, _ := strings.Cut(string(href[1]), "?")
+		if !inlined[strings.TrimPrefix(hrefPath, "/")] {
+			return tag
+		}
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+)
+
+/*
+MissingLayouts returns a message for every page, tag, collection and archive page whose layout is not defined in templ
+Executing an undefined layout fails with an error that does not name the page, so layouts are checked before rendering
+*/
+func (e *Engine) MissingLayouts(templ *template.Template) []string {
+	var missing []string
+	check := func(pageURL template.URL, layoutName string) {
+		if templ.Lookup(layoutName) == nil {
+			missing = append(missing, fmt.Sprintf("%s: the %q layout is not defined by any file in layout/", pageURL, layoutName))
+		}
+	}
+
+	for pageURL, page := range e.DeepDataMerge.Templates {
+		check(pageURL, page.Frontmatter.LayoutName())
+	}
+	check("tags.html", "all-tags")
+	check("collections.html", "all-collections")
+	if len(e.DeepDataMerge.TagsMap) > 0 {
+		check("tags/", "tag-subpage")
+	}
+	for collection := range e.DeepDataMerge.CollectionsMap {
+		check(collection, e.collectionLayout(collection))
+	}
+	for _, taxonomy := range e.DeepDataMerge.LayoutConfig.Taxonomies {
+		if len(e.DeepDataMerge.TaxonomyMap[taxonomy.Name]) > 0 {
+			check(template.URL(taxonomy.Name+"/"), taxonomy.LayoutName())
+		}
+	}
+	if archive := e.DeepDataMerge.LayoutConfig.Archive; archive != nil {
+		check("archive/index.html", "all-archive")
+		// The archive pages are built after the layouts are checked, from the posts listed in them
+		for _, post := range e.DeepDataMerge.Posts {
+			if post.Frontmatter.IsHTML() && (post.Frontmatter.Date != "" || archive.IncludeUndated) {
+				check("archive/", "archive-subpage")
+				break
+			}
+		}
+	}
+	if e.DeepDataMerge.LayoutConfig.HTMLSitemap {
+		check(htmlSitemapKey, "sitemap")
+	}
+	if e.DeepDataMerge.LayoutConfig.PostsListing {
+		check(e.DeepDataMerge.LayoutConfig.PostsListingKey(), e.DeepDataMerge.LayoutConfig.PostsListingLayout())
+	}
+
+	sort.Strings(missing)
+	return missing
+}
+
+// CheckLayouts fails the build with the list of pages using layouts that are not defined
+func (e *Engine) CheckLayouts(templ *template.Template) {
+	missing := e.MissingLayouts(templ)
+	if len(missing) == 0 {
+		return
+	}
+
+	for _, message := range missing {
+		e.ErrorLogger.Println(message)
+	}
+	e.ErrorLogger.Fatalf("%d page(s) use layouts that are not defined", len(missing))
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"html/template"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var hrefRegex = regexp.MustCompile(`href="([^"]*)"`)
+
+/*
+NormalizeInternalLinks rewrites the internal links of a rendered page to the URL of the page they point to
+
+Only root-relative ("/about.html"), BaseURL-prefixed and relative ("../about.md") links to rendered pages are rewritten,
+so that "/about", "/about.html" and "/about/" all match the configured URL style
+Relative links are resolved against the directory of the page at pagePath and rewritten to root-relative links
+External links, anchors and links to static assets are left untouched
+*/
+func (e *Engine) NormalizeInternalLinks(pagePath string, html []byte) []byte {
+	e.linkIndexOnce.Do(e.buildLinkIndex)
+
+	return hrefRegex.ReplaceAllFunc(html, func(match []byte) []byte {
+		href := string(hrefRegex.FindSubmatch(match)[1])
+		normalized, ok := e.normalizeLink(pagePath, href)
+		if !ok {
+			return match
+		}
+		return []byte(`href="` + normalized + `"`)
+	})
+}
+
+func (e *Engine) normalizeLink(pagePath string, href string) (string, bool) {
+	prefix, pageURL, suffix, ok := e.resolveLink(pagePath, href)
+	if !ok {
+		return "", false
+	}
+
+	return prefix + "/" + pageURL + suffix, true
+}
+
+/*
+resolveLink splits an internal link into its base URL prefix, the URL of the page it points to and its query string or fragment
+Relative links are resolved against the directory of the page at pagePath
+*/
+func (e *Engine) resolveLink(pagePath string, href string) (prefix string, pageURL string, suffix string, ok bool) {
+	linkPath := href
+
+	// Preserving the query string and fragment of the link
+	if index := strings.IndexAny(linkPath, "?#"); index != -1 {
+		suffix = linkPath[index:]
+		linkPath = linkPath[:index]
+	}
+
+	baseURL := strings.TrimSuffix(e.DeepDataMerge.LayoutConfig.BaseURL, "/")
+	if baseURL != "" && strings.HasPrefix(linkPath, baseURL+"/") {
+		prefix = baseURL
+		linkPath = strings.TrimPrefix(linkPath, baseURL)
+	} else if isRelativeLink(linkPath) {
+		relPath := path.Join(path.Dir(pagePath), linkPath)
+		if relPath == ".." || strings.HasPrefix(relPath, "../") {
+			return "", "", "", false
+		}
+		linkPath = "/" + relPath
+		if relPath == "." {
+			linkPath = "/"
+		} else if strings.HasSuffix(href[:len(href)-len(suffix)], "/") {
+			linkPath += "/"
+		}
+	} else if !strings.HasPrefix(linkPath, "/") || strings.HasPrefix(linkPath, "//") {
+		return "", "", "", false
+	} else {
+		// The base path is added back to root-relative links by PrefixBasePath
+		linkPath = e.trimBasePath(linkPath)
+	}
+
+	pageURL, ok = e.linkIndex[strings.TrimPrefix(linkPath, "/")]
+	return prefix, pageURL, suffix, ok
+}
+
+// isRelativeLink reports whether a link is relative to the page it is on, such as "sibling.md" or "../about.html"
+// Root-relative links and links with a scheme such as https: or mailto: are not relative
+func isRelativeLink(href string) bool {
+	if href == "" || strings.HasPrefix(href, "/") {
+		return false
+	}
+	scheme, _, found := strings.Cut(href, ":")
+	return !found || strings.Contains(scheme, "/")
+}
+
+// buildLinkIndex maps every form of a link to a page (with or without the extension and trailing slash, or to its markdown file) to its URL
+func (e *Engine) buildLinkIndex() {
+	e.linkIndex = make(map[string]string)
+
+	keys := make([]string, 0, len(e.DeepDataMerge.Templates))
+	for key := range e.DeepDataMerge.Templates {
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+
+	// The output paths of pages take precedence over the alternate forms of a link
+	for _, key := range keys {
+		e.linkIndex[key] = string(e.DeepDataMerge.Templates[template.URL(key)].CompleteURL)
+	}
+
+	for _, key := range keys {
+		pageURL := string(e.DeepDataMerge.Templates[template.URL(key)].CompleteURL)
+		base, _ := strings.CutSuffix(key, ".html")
+
+		var forms []string
+		if base == "index" || strings.HasSuffix(base, "/index") {
+			dir, _ := strings.CutSuffix(base, "index")
+			forms = append(forms, dir, base+".md")
+			if dir != "" {
+				forms = append(forms, strings.TrimSuffix(dir, "/"), strings.TrimSuffix(dir, "/")+".html")
+			}
+		} else {
+			forms = append(forms, base, base+"/", base+".html", base+".md")
+		}
+
+		for _, form := range forms {
+			if _, ok := e.linkIndex[form]; !ok {
+				e.linkIndex[form] = pageURL
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"html/template"
+	"sort"
+	"strings"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+/*
+BuildMenus stores the named menus of the site in Menus, merging the entries of the menus config with the pages
+that list a menu in the menu field of their frontmatter
+
+Entries are ordered by increasing weight, entries of the config come before pages of the same weight, and pages of the
+same weight are ordered by title. A page already linked by an entry of the config in the same menu is not added again,
+as the entry of the config takes precedence. Hidden pages are not added to menus
+*/
+func (e *Engine) BuildMenus() {
+	menus := make(map[string][]parser.MenuEntry, len(e.DeepDataMerge.LayoutConfig.Menus))
+	linked := make(map[string]map[string]bool)
+	// Number of entries of every menu coming from the config, which come before the pages
+	fromConfig := make(map[string]int, len(e.DeepDataMerge.LayoutConfig.Menus))
+	for menu, entries := range e.DeepDataMerge.LayoutConfig.Menus {
+		linked[menu] = make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			linked[menu][strings.TrimPrefix(entry.URL, "/")] = true
+		}
+		menus[menu] = append([]parser.MenuEntry(nil), entries...)
+		fromConfig[menu] = len(entries)
+	}
+
+	keys := make([]string, 0, len(e.DeepDataMerge.Templates))
+	for key := range e.DeepDataMerge.Templates {
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		page := e.DeepDataMerge.Templates[template.URL(key)]
+		if !page.Frontmatter.IsHTML() || page.Frontmatter.Hidden {
+			continue
+		}
+		pageURL := string(page.CompleteURL)
+		for _, menu := range page.Frontmatter.Menu {
+			if linked[menu][pageURL] {
+				continue
+			}
+			if linked[menu] == nil {
+				linked[menu] = make(map[string]bool)
+			}
+			linked[menu][pageURL] = true
+			menus[menu] = append(menus[menu], parser.MenuEntry{
+				Name:   page.Frontmatter.Title,
+				URL:    pageURL,
+				Weight: page.Frontmatter.Weight,
+			})
+		}
+	}
+
+	for menu, entries := range menus {
+		pages := entries[fromConfig[menu]:]
+		sort.SliceStable(pages, func(i, j int) bool {
+			return pages[i].Name < pages[j].Name
+		})
+
+		// The stable sort keeps the entries of the config in their order and before the pages on ties
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Weight < entries[j].Weight
+		})
+	}
+	e.DeepDataMerge.Menus = menus
+}
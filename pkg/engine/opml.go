@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+)
+
+// feed stores the title and the path relative to the rendered directory of a generated feed
+type feed struct {
+	title string
+	path  string
+}
+
+// GenerateOPML writes an OPML file listing every feed generated so far, so that readers can subscribe to all of them at once
+func (e *Engine) GenerateOPML(fileOutPath string) {
+	baseURL := e.DeepDataMerge.LayoutConfig.BaseURL
+
+	var buffer bytes.Buffer
+	buffer.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n")
+	buffer.WriteString("<opml version=\"2.0\">\n")
+	buffer.WriteString("  <head>\n")
+	buffer.WriteString("    <title>")
+	xml.EscapeText(&buffer, []byte(e.DeepDataMerge.LayoutConfig.SiteTitle))
+	buffer.WriteString("</title>\n")
+	buffer.WriteString("  </head>\n")
+	buffer.WriteString("  <body>\n")
+	for _, f := range e.feeds {
+		buffer.WriteString("    <outline type=\"rss\" text=\"")
+		xml.EscapeText(&buffer, []byte(f.title))
+		buffer.WriteString("\" title=\"")
+		xml.EscapeText(&buffer, []byte(f.title))
+		buffer.WriteString("\" xmlUrl=\"")
+		xml.EscapeText(&buffer, []byte(baseURL+"/"+f.path))
+		buffer.WriteString("\" htmlUrl=\"")
+		xml.EscapeText(&buffer, []byte(baseURL+"/"))
+		buffer.WriteString("\" />\n")
+	}
+	buffer.WriteString("  </body>\n")
+	buffer.WriteString("</opml>\n")
+
+	err := os.WriteFile(fileOutPath, buffer.Bytes(), 0666)
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"cmp"
+	"html/template"
+	"slices"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+/*
+BuildPostNavigation links every post to the posts published right before and after it in PrevPost and NextPost
+Posts are ordered by their date regardless of the postSort config, ties are ordered by their URL
+The oldest post has no PrevPost and the newest post has no NextPost, posts without a date are not linked
+*/
+func (e *Engine) BuildPostNavigation() {
+	var posts []parser.TemplateData
+	for _, post := range e.DeepDataMerge.Posts {
+		if post.Frontmatter.Date == "" || !post.Frontmatter.IsHTML() {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	slices.SortFunc(posts, func(a, b parser.TemplateData) int {
+		return cmp.Or(cmp.Compare(a.Date, b.Date), cmp.Compare(a.CompleteURL, b.CompleteURL))
+	})
+
+	prevPosts := make(map[template.URL]*parser.PostLink, len(posts))
+	nextPosts := make(map[template.URL]*parser.PostLink, len(posts))
+	for i, post := range posts {
+		if i > 0 {
+			prevPosts[post.CompleteURL] = postLink(posts[i-1])
+		}
+		if i < len(posts)-1 {
+			nextPosts[post.CompleteURL] = postLink(posts[i+1])
+		}
+	}
+
+	for i, post := range e.DeepDataMerge.Posts {
+		post.PrevPost = prevPosts[post.CompleteURL]
+		post.NextPost = nextPosts[post.CompleteURL]
+		e.DeepDataMerge.Posts[i] = post
+	}
+	for key, page := range e.DeepDataMerge.Templates {
+		if page.Frontmatter.Type != "post" {
+			continue
+		}
+		page.PrevPost = prevPosts[page.CompleteURL]
+		page.NextPost = nextPosts[page.CompleteURL]
+		e.DeepDataMerge.Templates[key] = page
+	}
+}
+
+func postLink(post parser.TemplateData) *parser.PostLink {
+	return &parser.PostLink{
+		Title: post.Frontmatter.Title,
+		URL:   post.CompleteURL,
+	}
+}
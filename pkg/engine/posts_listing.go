@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+type PostsListingTemplateData struct {
+	DeepDataMerge DeepDataMerge
+	PageURL       template.URL
+	TemplateData  parser.TemplateData
+	// Posts listed on the page in the order of the postSort config
+	Posts []parser.TemplateData
+}
+
+// ListedPosts returns the posts of the posts listing in the order of the postSort config, hidden posts and posts in other output formats are left out
+func (e *Engine) ListedPosts() []parser.TemplateData {
+	var posts []parser.TemplateData
+	for _, post := range e.DeepDataMerge.Posts {
+		if !post.Frontmatter.Hidden && post.Frontmatter.IsHTML() {
+			posts = append(posts, post)
+		}
+	}
+	return posts
+}
+
+/*
+RenderPostsListing renders the posts to the postsPath config with the postsTemplate layout
+The build fails when a page of the content is rendered to the same path, such as content/index.md for a listing at index.html
+*/
+func (e *Engine) RenderPostsListing(fileOutPath string, templ *template.Template) {
+	config := e.DeepDataMerge.LayoutConfig
+	key := config.PostsListingKey()
+	if _, ok := e.DeepDataMerge.Templates[key]; ok {
+		e.ErrorLogger.Fatalf("%s: the posts listing is rendered to the path of a page, set postsPath to another path", key)
+	}
+
+	var buffer bytes.Buffer
+	postsTemplateData := PostsListingTemplateData{
+		DeepDataMerge: e.DeepDataMerge,
+		PageURL:       key,
+		TemplateData: parser.TemplateData{
+			CompleteURL: config.PostsListingURL(),
+			Frontmatter: parser.Frontmatter{Title: "Posts"},
+		},
+		Posts: e.ListedPosts(),
+	}
+
+	err := templ.ExecuteTemplate(&buffer, config.PostsListingLayout(), postsTemplateData)
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+	e.writePage(fileOutPath, key, e.postProcess(key, parser.TemplateData{}, buffer.Bytes()))
+}
+
+// postsListingSitemapEntry returns the posts listing as a page of the sitemap, updated when its newest post was
+func (e *Engine) postsListingSitemapEntry() parser.TemplateData {
+	entry := parser.TemplateData{CompleteURL: e.DeepDataMerge.LayoutConfig.PostsListingURL()}
+	for _, post := range e.ListedPosts() {
+		entry.Updated = max(entry.Updated, post.Date, post.Updated)
+	}
+	return entry
+}
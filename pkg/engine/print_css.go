@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"html/template"
+	"os"
+	"strings"
+)
+
+// Page box of printed pages, declared before the print stylesheet so that it can be overridden with its own @page rule
+const defaultPrintPageStyle = "<style media=\"print\">@page { margin: 2cm; }</style>\n"
+
+/*
+printStylesheet returns the tags linking the stylesheet of the printCSS config with media="print", along with a default @page rule
+Stylesheets of the site are loaded for every medium, so the print stylesheet only needs to adjust them for printing
+*/
+func (e *Engine) printStylesheet() string {
+	stylesheet := e.DeepDataMerge.LayoutConfig.PrintCSS
+	if stylesheet == "" {
+		return ""
+	}
+
+	href := stylesheet
+	if !strings.Contains(stylesheet, "://") {
+		stylesheetPath := strings.TrimPrefix(stylesheet, "/")
+		if _, err := os.Stat(e.SiteDataPath + stylesheetPath); err != nil {
+			e.ErrorLogger.Fatal("Print stylesheet not found: ", err)
+		}
+		// The base path is added to the root-relative link by PrefixBasePath
+		href = "/" + stylesheetPath
+	}
+	return defaultPrintPageStyle + "<link rel=\"stylesheet\" href=\"" + template.HTMLEscapeString(href) + "\" media=\"print\" />\n"
+}
@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"cmp"
+	"html/template"
+	"slices"
+	"time"
+)
+
+// PageRenderTime is the time taken to render a page along with the size of its output
+type PageRenderTime struct {
+	PagePath template.URL
+	Duration time.Duration
+	Size     int
+}
+
+// recordRenderTime stores the render time of a page when render profiling is enabled
+func (e *Engine) recordRenderTime(pagePath template.URL, start time.Time, size int) {
+	if !e.ProfileRender {
+		return
+	}
+
+	duration := time.Since(start)
+
+	e.renderTimesMu.Lock()
+	defer e.renderTimesMu.Unlock()
+
+	e.renderTimes = append(e.renderTimes, PageRenderTime{
+		PagePath: pagePath,
+		Duration: duration,
+		Size:     size,
+	})
+}
+
+// SlowestPages returns the n pages that took the longest to render, slowest first
+func (e *Engine) SlowestPages(n int) []PageRenderTime {
+	e.renderTimesMu.Lock()
+	defer e.renderTimesMu.Unlock()
+
+	pages := slices.Clone(e.renderTimes)
+	slices.SortFunc(pages, func(a, b PageRenderTime) int {
+		return cmp.Or(cmp.Compare(b.Duration, a.Duration), cmp.Compare(a.PagePath, b.PagePath))
+	})
+
+	if n < len(pages) {
+		pages = pages[:n]
+	}
+	return pages
+}
@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type webAppManifest struct {
+	Name            string            `json:"name"`
+	ShortName       string            `json:"short_name,omitempty"`
+	Description     string            `json:"description,omitempty"`
+	StartURL        string            `json:"start_url"`
+	Display         string            `json:"display"`
+	ThemeColor      string            `json:"theme_color,omitempty"`
+	BackgroundColor string            `json:"background_color,omitempty"`
+	Icons           []webAppIconEntry `json:"icons,omitempty"`
+}
+
+type webAppIconEntry struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// GenerateManifest writes the web app manifest described by the pwa config to outFilePath
+func (e *Engine) GenerateManifest(outFilePath string) {
+	pwa := e.DeepDataMerge.LayoutConfig.PWA
+	if pwa == nil {
+		return
+	}
+
+	manifest := webAppManifest{
+		Name:            pwa.Name,
+		ShortName:       pwa.ShortName,
+		Description:     pwa.Description,
+		StartURL:        pwa.StartURL,
+		Display:         pwa.Display,
+		ThemeColor:      pwa.ThemeColor,
+		BackgroundColor: pwa.BackgroundColor,
+	}
+	if manifest.Name == "" {
+		manifest.Name = e.DeepDataMerge.LayoutConfig.SiteTitle
+	}
+	basePath := e.DeepDataMerge.LayoutConfig.BasePath()
+	if manifest.StartURL == "" {
+		manifest.StartURL = basePath + "/"
+	}
+	if manifest.Display == "" {
+		manifest.Display = "standalone"
+	}
+
+	// Icons must be present in the static/ directory
+	for _, icon := range pwa.Icons {
+		iconPath := strings.TrimPrefix(icon.Src, "/")
+		if !strings.HasPrefix(iconPath, "static/") {
+			e.ErrorLogger.Fatal("PWA icon must be placed in the static/ directory: ", icon.Src)
+		}
+		if _, err := os.Stat(e.SiteDataPath + iconPath); err != nil {
+			e.ErrorLogger.Fatal("PWA icon not found: ", err)
+		}
+
+		manifest.Icons = append(manifest.Icons, webAppIconEntry{
+			Src:   basePath + "/" + iconPath,
+			Sizes: icon.Sizes,
+			Type:  icon.Type,
+		})
+	}
+
+	// Using the generated favicons as the app icons when none are configured
+	if len(pwa.Icons) == 0 {
+		for _, icon := range e.favicons {
+			if icon.rel == "icon" && icon.size >= 192 {
+				manifest.Icons = append(manifest.Icons, webAppIconEntry{
+					Src:   basePath + "/" + icon.path,
+					Sizes: icon.sizes(),
+					Type:  "image/png",
+				})
+			}
+		}
+	}
+
+	marshaledManifest, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+
+	err = os.WriteFile(outFilePath, marshaledManifest, 0666)
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+}
+
+const serviceWorkerTemplate = `const CACHE_NAME = "anna-%s";
+const PRECACHE_URLS = %s;
+
+self.addEventListener("install", (event) => {
+  event.waitUntil(
+    caches
+      .open(CACHE_NAME)
+      .then((cache) => cache.addAll(PRECACHE_URLS))
+      .then(() => self.skipWaiting()),
+  );
+});
+
+self.addEventListener("activate", (event) => {
+  event.waitUntil(
+    caches
+      .keys()
+      .then((keys) =>
+        Promise.all(keys.filter((key) => key !== CACHE_NAME).map((key) => caches.delete(key))),
+      )
+      .then(() => self.clients.claim()),
+  );
+});
+
+self.addEventListener("fetch", (event) => {
+  if (event.request.method !== "GET") {
+    return;
+  }
+  event.respondWith(caches.match(event.request).then((cached) => cached || fetch(event.request)));
+});
+`
+
+/*
+GenerateServiceWorker writes rendered/sw.js, which precaches the pages and static assets of the rendered site
+The cache name contains a hash of the rendered files, so that every build with changes invalidates the old caches
+Pages are precached before other assets when the number of files exceeds the precacheLimit
+*/
+func (e *Engine) GenerateServiceWorker(fileOutPath string) {
+	pwa := e.DeepDataMerge.LayoutConfig.PWA
+	if pwa == nil || !pwa.ServiceWorker {
+		return
+	}
+
+	renderedPath := fileOutPath + "rendered/"
+	basePath := e.DeepDataMerge.LayoutConfig.BasePath()
+	var pages, assets []string
+	buildHash := sha256.New()
+
+	err := filepath.WalkDir(renderedPath, func(path string, dir fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if dir.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(renderedPath, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == "sw.js" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		buildHash.Write([]byte(relPath))
+		buildHash.Write(content)
+
+		if e.isHTMLPage(template.URL(relPath)) {
+			pages = append(pages, basePath+precacheURL(relPath))
+		} else {
+			assets = append(assets, basePath+"/"+relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+
+	precacheURLs := append(pages, assets...)
+	if pwa.PrecacheLimit > 0 && len(precacheURLs) > pwa.PrecacheLimit {
+		precacheURLs = precacheURLs[:pwa.PrecacheLimit]
+	}
+
+	marshaledURLs, err := json.Marshal(precacheURLs)
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+
+	cacheHash := hex.EncodeToString(buildHash.Sum(nil))[:12]
+	serviceWorker := fmt.Sprintf(serviceWorkerTemplate, cacheHash, marshaledURLs)
+
+	err = os.WriteFile(renderedPath+"sw.js", []byte(serviceWorker), 0666)
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+}
+
+// precacheURL returns the URL a page is requested at, index.html files are served at their directory
+func precacheURL(relPath string) string {
+	if relPath == "index.html" {
+		return "/"
+	}
+	if dir, ok := strings.CutSuffix(relPath, "/index.html"); ok {
+		return "/" + dir + "/"
+	}
+	return "/" + relPath
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"html/template"
+	"sort"
+	"strings"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+// readerPagePath returns the path relative to rendered/ of the reader-mode alternate of a page
+func readerPagePath(page parser.TemplateData) template.URL {
+	if strings.HasSuffix(string(page.ReaderURL), "/") {
+		return page.ReaderURL + "index.html"
+	}
+	return page.ReaderURL
+}
+
+/*
+RenderReaderPages renders the reader-mode alternate of every post with the "reader" layout
+The layout receives the data of the canonical page, so it can be accessed in the same manner as in other layouts
+*/
+func (e *Engine) RenderReaderPages(fileOutPath string, templates *template.Template) {
+	if templates.Lookup("reader") == nil {
+		e.ErrorLogger.Fatal("readerMode is enabled but no \"reader\" layout is defined in layout/")
+	}
+
+	pageURLs := make([]string, 0, len(e.DeepDataMerge.Templates))
+	for pageURL, page := range e.DeepDataMerge.Templates {
+		if page.ReaderURL != "" {
+			pageURLs = append(pageURLs, string(pageURL))
+		}
+	}
+	sort.Strings(pageURLs)
+
+	for _, pageURL := range pageURLs {
+		page := e.DeepDataMerge.Templates[template.URL(pageURL)]
+		readerPath := readerPagePath(page)
+
+		output := e.executeTemplate(template.URL(pageURL), templates, "reader")
+		e.writePage(fileOutPath, readerPath, e.postProcess(readerPath, page, output))
+	}
+}
+
+// pageHeadTags returns the tags linking a page and its reader-mode alternate to each other
+func (e *Engine) pageHeadTags(pagePath template.URL, page parser.TemplateData) string {
+	if page.ReaderURL == "" {
+		return ""
+	}
+
+	baseURL := e.DeepDataMerge.LayoutConfig.BaseURL + "/"
+	if pagePath == readerPagePath(page) {
+		// The reader-mode alternate of a republished page points to the canonical URL set in its frontmatter
+		canonicalURL := string(page.CanonicalURL)
+		if canonicalURL == "" {
+			canonicalURL = baseURL + string(page.CompleteURL)
+		}
+		return "<link rel=\"canonical\" href=\"" + template.HTMLEscapeString(canonicalURL) + "\" />\n"
+	}
+	return "<link rel=\"alternate\" href=\"" + template.HTMLEscapeString(baseURL+string(page.ReaderURL)) + "\" title=\"Reader view\" />\n"
+}
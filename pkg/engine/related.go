@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"html/template"
+	"sort"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+// defaultRelatedNotes is the number of related notes listed when the relatedNotes config is not set
+const defaultRelatedNotes = 5
+
+/*
+BuildRelatedNotes stores the notes related to every page of type note in its RelatedNotes
+
+Notes are related when they link to or are linked from the same pages, or when they share tags
+A shared link weighs twice as much as a shared tag, notes that link to each other directly are not listed
+as the link is already visible on the page, and hidden notes are never listed
+*/
+func (e *Engine) BuildRelatedNotes() {
+	limit := e.DeepDataMerge.LayoutConfig.RelatedNotes
+	if limit == 0 {
+		limit = defaultRelatedNotes
+	}
+	if limit < 0 {
+		return
+	}
+
+	e.linkGraphOnce.Do(e.buildLinkGraph)
+
+	notes := e.noteKeys()
+	for _, note := range notes {
+		page := e.DeepDataMerge.Templates[note]
+		page.RelatedNotes = e.relatedNotes(note, notes, limit)
+		e.DeepDataMerge.Templates[note] = page
+	}
+}
+
+// relatedNotes returns the notes most related to a note, ordered by their score and then by their path
+func (e *Engine) relatedNotes(note template.URL, notes []template.URL, limit int) []parser.TemplateData {
+	type scoredNote struct {
+		key   template.URL
+		score int
+	}
+
+	tags := make(map[string]bool)
+	for _, tag := range e.DeepDataMerge.Templates[note].Frontmatter.Tags {
+		tags[tag] = true
+	}
+
+	var scored []scoredNote
+	for _, other := range notes {
+		if other == note || e.linkGraph[note][other] || e.DeepDataMerge.Templates[other].Frontmatter.Hidden {
+			continue
+		}
+
+		score := 0
+		for neighbour := range e.linkGraph[other] {
+			if e.linkGraph[note][neighbour] {
+				score += 2
+			}
+		}
+		for _, tag := range e.DeepDataMerge.Templates[other].Frontmatter.Tags {
+			if tags[tag] {
+				score++
+			}
+		}
+
+		if score > 0 {
+			scored = append(scored, scoredNote{key: other, score: score})
+		}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	related := make([]parser.TemplateData, 0, min(limit, len(scored)))
+	for _, candidate := range scored[:min(limit, len(scored))] {
+		relatedNote := e.DeepDataMerge.Templates[candidate.key]
+		relatedNote.RelatedNotes = nil
+		related = append(related, relatedNote)
+	}
+	return related
+}
+
+// noteKeys returns the keys of the pages of type note in sorted order
+func (e *Engine) noteKeys() []template.URL {
+	var notes []template.URL
+	for key, page := range e.DeepDataMerge.Templates {
+		if page.Frontmatter.Type == "note" {
+			notes = append(notes, key)
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i] < notes[j]
+	})
+	return notes
+}
+
+// buildLinkGraph connects every page to the pages it links to and the pages linking to it
+func (e *Engine) buildLinkGraph() {
+	e.linkIndexOnce.Do(e.buildLinkIndex)
+
+	keyOfURL := make(map[string]template.URL, len(e.DeepDataMerge.Templates))
+	for key, page := range e.DeepDataMerge.Templates {
+		keyOfURL[string(page.CompleteURL)] = key
+	}
+
+	e.linkGraph = make(map[template.URL]map[template.URL]bool)
+	addEdge := func(from, to template.URL) {
+		if e.linkGraph[from] == nil {
+			e.linkGraph[from] = make(map[template.URL]bool)
+		}
+		e.linkGraph[from][to] = true
+	}
+
+	for key, page := range e.DeepDataMerge.Templates {
+		for _, match := range hrefRegex.FindAllStringSubmatch(string(page.Body), -1) {
+			_, pageURL, _, ok := e.resolveLink(string(key), match[1])
+			if !ok {
+				continue
+			}
+			target, ok := keyOfURL[pageURL]
+			if !ok || target == key {
+				continue
+			}
+			addEdge(key, target)
+			addEdge(target, key)
+		}
+	}
+}
+
+/*
+BuildOrphanNotes stores the notes that neither link to nor are linked from any other page in OrphanNotes
+Notes left out of the search index with searchExclude are not considered orphans
+*/
+func (e *Engine) BuildOrphanNotes() {
+	e.linkGraphOnce.Do(e.buildLinkGraph)
+
+	e.DeepDataMerge.OrphanNotes = nil
+	for _, note := range e.noteKeys() {
+		page := e.DeepDataMerge.Templates[note]
+		if page.Frontmatter.SearchExclude || len(e.linkGraph[note]) > 0 {
+			continue
+		}
+		page.RelatedNotes = nil
+		e.DeepDataMerge.OrphanNotes = append(e.DeepDataMerge.OrphanNotes, page)
+	}
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"html/template"
+	"path"
+	"sort"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+/*
+BuildSections stores the direct children of every section root in its Children
+A section root is the page rendered to the index.html of a directory, such as blog/index.html from content/blog/index.md
+Its children are the pages of the directory and the roots of its sub-directories, ordered according to the postSort config
+Hidden pages are not listed as children
+*/
+func (e *Engine) BuildSections() {
+	keys := make([]string, 0, len(e.DeepDataMerge.Templates))
+	for key := range e.DeepDataMerge.Templates {
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+
+	children := make(map[template.URL][]parser.TemplateData)
+	for _, key := range keys {
+		page := e.DeepDataMerge.Templates[template.URL(key)]
+		if !page.Frontmatter.IsHTML() || page.Frontmatter.Hidden {
+			continue
+		}
+
+		sectionKey, ok := parentSectionKey(key)
+		if !ok {
+			continue
+		}
+		if _, ok := e.DeepDataMerge.Templates[sectionKey]; ok {
+			children[sectionKey] = append(children[sectionKey], page)
+		}
+	}
+
+	for sectionKey, pages := range children {
+		e.SortPages(pages)
+
+		section := e.DeepDataMerge.Templates[sectionKey]
+		section.Children = pages
+		e.DeepDataMerge.Templates[sectionKey] = section
+	}
+}
+
+// parentSectionKey returns the key of the root of the section a page belongs to, the root page of the site has none
+func parentSectionKey(key string) (template.URL, bool) {
+	dir := path.Dir(key)
+	if path.Base(key) == "index.html" {
+		if dir == "." {
+			return "", false
+		}
+		dir = path.Dir(dir)
+	}
+
+	if dir == "." {
+		return "index.html", true
+	}
+	return template.URL(dir + "/index.html"), true
+}
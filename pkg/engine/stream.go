@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"html/template"
+	"io"
+	"os"
+)
+
+// needsBuffering reports whether a page has to be rendered into memory, as its post-processing requires the complete HTML
+// Pages in other output formats and HTML pages without any transforms or hooks are streamed to the disk,
+// the dimensions of images only need the complete <img> tag and are added while the page is streamed
+func (e *Engine) needsBuffering(pagePath template.URL) bool {
+	if !e.isHTMLPage(pagePath) {
+		return false
+	}
+
+	config := e.DeepDataMerge.LayoutConfig
+	return len(e.postRenderHooks) > 0 ||
+		e.headInjections() != "" ||
+		e.pageHeadTags(pagePath, e.DeepDataMerge.Templates[pagePath]) != "" ||
+		config.NormalizeLinks ||
+		config.ExternalLinks != nil ||
+		config.BasePath() != ""
+}
+
+// streamPage executes the templateStartString template for the page at pagePath directly into its output file
+// It returns the number of bytes written
+func (e *Engine) streamPage(fileOutPath string, pagePath template.URL, template *template.Template, templateStartString string) int {
+	file, err := os.Create(e.outputPath(fileOutPath, pagePath))
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+
+	bufferedWriter := bufio.NewWriter(file)
+	writer := &countingWriter{writer: bufferedWriter}
+	var pageWriter io.Writer = writer
+	var imageWriter *imageDimensionsWriter
+	if e.isHTMLPage(pagePath) && e.DeepDataMerge.LayoutConfig.ImageDimensionsEnabled() {
+		imageWriter = &imageDimensionsWriter{engine: e, pagePath: string(pagePath), writer: writer}
+		pageWriter = imageWriter
+	}
+
+	pageData := PageData{
+		DeepDataMerge: e.DeepDataMerge,
+		PageURL:       pagePath,
+	}
+
+	err = template.ExecuteTemplate(pageWriter, templateStartString, pageData)
+	if err != nil {
+		e.ErrorLogger.Println("Error at path: ", pagePath)
+		e.ErrorLogger.Fatal(err)
+	}
+	if imageWriter != nil {
+		if err = imageWriter.Flush(); err != nil {
+			e.ErrorLogger.Fatal(err)
+		}
+	}
+
+	if err = bufferedWriter.Flush(); err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+	if err = file.Close(); err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+
+	return writer.count
+}
+
+// countingWriter counts the bytes written to the underlying writer
+type countingWriter struct {
+	writer io.Writer
+	count  int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.count += n
+	return n, err
+}
+
+/*
+imageDimensionsWriter adds the intrinsic dimensions of images to a page as it is streamed to the underlying writer
+Only an <img> tag being written is held back until it is complete, the rest of the page is passed through
+*/
+type imageDimensionsWriter struct {
+	engine   *Engine
+	pagePath string
+	writer   io.Writer
+	pending  []byte
+}
+
+func (w *imageDimensionsWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		start := bytes.Index(w.pending, []byte("<img"))
+		if start == -1 {
+			// The end of the output may be the start of an <img> tag continued by the next write
+			keep := partialPrefixLen(w.pending, "<img")
+			if err := w.flushPending(len(w.pending) - keep); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}
+		if err := w.flushPending(start); err != nil {
+			return 0, err
+		}
+
+		end := bytes.IndexByte(w.pending, '>')
+		if end == -1 {
+			return len(p), nil
+		}
+		if _, err := w.writer.Write(w.engine.AddImageDimensions(w.pagePath, w.pending[:end+1])); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[end+1:]
+	}
+}
+
+// Flush writes the output held back, such as an unterminated <img tag at the end of the page
+func (w *imageDimensionsWriter) Flush() error {
+	return w.flushPending(len(w.pending))
+}
+
+// flushPending writes the first n bytes held back to the underlying writer
+func (w *imageDimensionsWriter) flushPending(n int) error {
+	if n == 0 {
+		return nil
+	}
+	if _, err := w.writer.Write(w.pending[:n]); err != nil {
+		return err
+	}
+	w.pending = append(w.pending[:0], w.pending[n:]...)
+	return nil
+}
+
+// partialPrefixLen returns the length of the longest end of output which is the start of prefix
+func partialPrefixLen(output []byte, prefix string) int {
+	for n := min(len(prefix)-1, len(output)); n > 0; n-- {
+		if bytes.HasSuffix(output, []byte(prefix[:n])) {
+			return n
+		}
+	}
+	return 0
+}
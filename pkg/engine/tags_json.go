@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// TagsJSONEntry is a tag of tags.json, the list of tags for autocompletion in search interfaces
+type TagsJSONEntry struct {
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	URL   string `json:"url"`
+	Count int    `json:"count"`
+}
+
+// TagsJSON returns every tag with the number of pages under it, most used first and then by their name
+func (e *Engine) TagsJSON() []TagsJSONEntry {
+	tags := make([]TagsJSONEntry, 0, len(e.DeepDataMerge.TagsMap))
+	for key, pages := range e.DeepDataMerge.TagsMap {
+		tags = append(tags, TagsJSONEntry{
+			Name:  e.displayName(key, "tags/"),
+			Slug:  strings.TrimSuffix(strings.TrimPrefix(string(key), "tags/"), ".html"),
+			URL:   e.apiURL(string(key)),
+			Count: len(pages),
+		})
+	}
+
+	slices.SortFunc(tags, func(a, b TagsJSONEntry) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)), cmp.Compare(a.Slug, b.Slug))
+	})
+	return tags
+}
+
+// GenerateTagsJSON writes the tags of the site to tags.json in the rendered directory, a smaller file than the search index for tag inputs
+func (e *Engine) GenerateTagsJSON(siteDirPath string) {
+	e.writeJSONIndex(siteDirPath+"rendered/tags.json", e.TagsJSON())
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"cmp"
+	"html/template"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+type TaxonomyRootTemplateData struct {
+	DeepDataMerge DeepDataMerge
+	PageURL       template.URL
+	TemplateData  parser.TemplateData
+	// Taxonomy whose terms are listed
+	Taxonomy parser.TaxonomyConfig
+	// Terms of the taxonomy sorted by their name, along with the number of pages of every term
+	Terms []TermCount
+}
+
+// TermPages returns the pages of the term of a taxonomy at pageURL, such as categories/go.html, in the order of the postSort config
+func (d DeepDataMerge) TermPages(pageURL template.URL) []parser.TemplateData {
+	for _, terms := range d.TaxonomyMap {
+		if pages, ok := terms[pageURL]; ok {
+			return pages
+		}
+	}
+	return nil
+}
+
+/*
+RenderTaxonomies renders every taxonomy, the tags and collections with RenderTags and RenderCollections and
+the taxonomies of the config with theirs: the terms to <name>.html with its listLayout and the pages of every term
+to <name>/<term>.html with its layout
+Layouts of term pages read the pages of the term with {{.DeepDataMerge.TermPages .PageURL}}
+
+Pages of a taxonomy with the URL of a content page are skipped with a warning, so that the content page is kept
+*/
+func (e *Engine) RenderTaxonomies(fileOutPath string, templ *template.Template) {
+	e.DeepDataMerge.Terms = make(map[template.URL]parser.TemplateData)
+	for _, taxonomy := range e.DeepDataMerge.LayoutConfig.Taxonomies {
+		for term := range e.DeepDataMerge.TaxonomyMap[taxonomy.Name] {
+			e.SortPages(e.DeepDataMerge.TaxonomyMap[taxonomy.Name][term])
+			e.DeepDataMerge.Terms[term] = parser.TemplateData{
+				CompleteURL: term,
+				Frontmatter: parser.Frontmatter{Title: e.displayName(term, taxonomy.Name+"/")},
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, taxonomy := range e.DeepDataMerge.LayoutConfig.AllTaxonomies() {
+		switch taxonomy.Name {
+		case "tags":
+			e.RenderTags(fileOutPath, templ)
+			continue
+		case "collections":
+			e.RenderCollections(fileOutPath, templ)
+			continue
+		}
+
+		terms := e.DeepDataMerge.TaxonomyMap[taxonomy.Name]
+		keys := make([]template.URL, 0, len(terms))
+		for term := range terms {
+			keys = append(keys, term)
+		}
+		slices.SortFunc(keys, func(a, b template.URL) int {
+			return cmp.Compare(strings.ToLower(string(a)), strings.ToLower(string(b)))
+		})
+
+		termCounts := make([]TermCount, 0, len(keys))
+		for _, term := range keys {
+			termCounts = append(termCounts, TermCount{
+				Name:  e.displayName(term, taxonomy.Name+"/"),
+				URL:   term,
+				Count: len(terms[term]),
+			})
+		}
+
+		rootTemplateData := TaxonomyRootTemplateData{
+			DeepDataMerge: e.DeepDataMerge,
+			PageURL:       template.URL(taxonomy.Name + ".html"),
+			TemplateData: parser.TemplateData{
+				Frontmatter: parser.Frontmatter{Title: taxonomy.Name},
+			},
+			Taxonomy: taxonomy,
+			Terms:    termCounts,
+		}
+		if !e.collidesWithPage(taxonomy.Name, rootTemplateData.PageURL) {
+			e.renderIndexPage(fileOutPath, templ, taxonomy.ListLayoutName(), rootTemplateData.PageURL, rootTemplateData)
+		}
+
+		for _, term := range keys {
+			if e.collidesWithPage(taxonomy.Name, term) {
+				continue
+			}
+			wg.Add(1)
+			go func(term template.URL, layoutName string) {
+				defer wg.Done()
+
+				e.RenderPage(fileOutPath, term, templ, layoutName)
+			}(term, taxonomy.LayoutName())
+		}
+	}
+
+	wg.Wait()
+}
+
+// collidesWithPage reports whether a page of a taxonomy has the URL of a content page, warning that it is skipped
+func (e *Engine) collidesWithPage(taxonomyName string, pageURL template.URL) bool {
+	if _, ok := e.DeepDataMerge.Templates[pageURL]; !ok {
+		return false
+	}
+	e.Warnings.Warnf("%s: the page of the %s taxonomy has the URL of a content page, skipping it", pageURL, taxonomyName)
+	return true
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/anna-ssg/anna/v3/pkg/parser"
+)
+
+// TemplateFileData is the data of the files in layout/templates/, the config is embedded so that {{.BaseURL}} refers to it
+type TemplateFileData struct {
+	parser.LayoutConfig
+	DeepDataMerge DeepDataMerge
+}
+
+/*
+RenderTemplateFiles renders every file in layout/templates/ to the same path in rendered/, such as
+layout/templates/.well-known/security.txt to rendered/.well-known/security.txt
+The files are text templates executed with the config and the data of the site
+
+layout/robots.txt is rendered to rendered/robots.txt in the same way, unless layout/templates/ contains a robots.txt
+*/
+func (e *Engine) RenderTemplateFiles(siteDirPath string) {
+	data := TemplateFileData{
+		LayoutConfig:  e.DeepDataMerge.LayoutConfig,
+		DeepDataMerge: e.DeepDataMerge,
+	}
+	templatesDirPath := siteDirPath + "layout/templates/"
+	renderedDirPath := siteDirPath + "rendered/"
+
+	_, err := os.Stat(templatesDirPath + "robots.txt")
+	if os.IsNotExist(err) {
+		if _, err := os.Stat(siteDirPath + "layout/robots.txt"); err == nil {
+			if err := parser.ExecuteTemplateFile(siteDirPath+"layout/robots.txt", renderedDirPath+"robots.txt", data); err != nil {
+				e.ErrorLogger.Fatal(err)
+			}
+		}
+	}
+
+	if _, err := os.Stat(templatesDirPath); os.IsNotExist(err) {
+		return
+	}
+
+	err = filepath.WalkDir(templatesDirPath, func(path string, dir fs.DirEntry, err error) error {
+		if err != nil || dir.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(templatesDirPath, path)
+		if err != nil {
+			return err
+		}
+		return parser.ExecuteTemplateFile(path, renderedDirPath+filepath.ToSlash(relPath), data)
+	})
+	if err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+}
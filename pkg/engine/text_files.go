@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GenerateHumansTxt writes the humans.txt described by the humans config to fileOutPath, in the sections of humanstxt.org
+func (e *Engine) GenerateHumansTxt(fileOutPath string) {
+	humans := e.DeepDataMerge.LayoutConfig.Humans
+	if humans == nil {
+		return
+	}
+
+	var buffer bytes.Buffer
+	if len(humans.Team) > 0 {
+		buffer.WriteString("/* TEAM */\n")
+		for i, member := range humans.Team {
+			if i > 0 {
+				buffer.WriteString("\n")
+			}
+			writeTextField(&buffer, "\t", "Name", member.Name)
+			writeTextField(&buffer, "\t", "Role", member.Role)
+			writeTextField(&buffer, "\t", "Contact", member.Contact)
+			writeTextField(&buffer, "\t", "From", member.Location)
+		}
+		buffer.WriteString("\n")
+	}
+
+	if len(humans.Thanks) > 0 {
+		buffer.WriteString("/* THANKS */\n")
+		for _, name := range humans.Thanks {
+			writeTextField(&buffer, "\t", "Name", name)
+		}
+		buffer.WriteString("\n")
+	}
+
+	buffer.WriteString("/* SITE */\n")
+	writeTextField(&buffer, "\t", "Title", e.DeepDataMerge.LayoutConfig.SiteTitle)
+	writeTextField(&buffer, "\t", "Software", "anna")
+	writeTextField(&buffer, "\t", "Components", strings.Join(humans.Tech, ", "))
+
+	if err := os.WriteFile(fileOutPath, buffer.Bytes(), 0666); err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+}
+
+/*
+GenerateSecurityTxt writes the security.txt described by the security config to rendered/.well-known/security.txt
+The file is canonical at the base URL, and the config is validated when it is parsed so that the file has a contact and has not expired
+*/
+func (e *Engine) GenerateSecurityTxt(siteDirPath string) {
+	security := e.DeepDataMerge.LayoutConfig.Security
+	if security == nil {
+		return
+	}
+
+	var buffer bytes.Buffer
+	for _, contact := range security.ContactURIs() {
+		writeTextField(&buffer, "", "Contact", contact)
+	}
+	writeTextField(&buffer, "", "Expires", security.Expires)
+	writeTextField(&buffer, "", "Encryption", security.Encryption)
+	writeTextField(&buffer, "", "Acknowledgments", security.Acknowledgments)
+	writeTextField(&buffer, "", "Preferred-Languages", strings.Join(security.PreferredLanguages, ", "))
+	if baseURL := e.DeepDataMerge.LayoutConfig.BaseURL; baseURL != "" {
+		writeTextField(&buffer, "", "Canonical", baseURL+"/.well-known/security.txt")
+	}
+	writeTextField(&buffer, "", "Policy", security.Policy)
+
+	fileOutPath := siteDirPath + "rendered/.well-known/security.txt"
+	if err := os.MkdirAll(filepath.Dir(fileOutPath), 0750); err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+	if err := os.WriteFile(fileOutPath, buffer.Bytes(), 0666); err != nil {
+		e.ErrorLogger.Fatal(err)
+	}
+}
+
+// writeTextField writes a "Name: value" line of a text file, fields without a value are left out
+func writeTextField(buffer *bytes.Buffer, indent string, name string, value string) {
+	if value = strings.TrimSpace(value); value == "" {
+		return
+	}
+	fmt.Fprintf(buffer, "%s%s: %s\n", indent, name, value)
+}
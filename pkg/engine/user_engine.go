@@ -37,7 +37,7 @@ func (e *Engine) RenderUserDefinedPages(fileOutPath string, templates *template.
 				wg.Done()
 			}()
 
-			e.RenderPage(fileOutPath, template.URL(templateURL), templates, e.DeepDataMerge.Templates[template.URL(templateURL)].Frontmatter.Layout)
+			e.RenderPage(fileOutPath, template.URL(templateURL), templates, e.DeepDataMerge.Templates[template.URL(templateURL)].Frontmatter.LayoutName())
 		}(templateURL)
 	}
 
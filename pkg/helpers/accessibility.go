@@ -0,0 +1,94 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Rules of the accessibility issues found in rendered pages
+const (
+	RuleImageAlt = "image-alt"
+	RuleLinkText = "link-text"
+)
+
+// Maximum number of characters of the text quoted around an issue
+const issueContextLength = 60
+
+// AccessibilityIssue is an element of a rendered page that is not usable with a screen reader
+type AccessibilityIssue struct {
+	Rule string
+	// Describes the element along with the text around it, so that authors can find it in their content
+	Message string
+}
+
+/*
+AccessibilityIssues returns the images without an alt attribute and the links without discernible text in rendered HTML
+Decorative images are marked with an explicitly empty alt="", and links are described by their text, an aria-label,
+aria-labelledby or title attribute, or the alt text of an image they contain
+*/
+func AccessibilityIssues(htmlContent string) ([]AccessibilityIssue, error) {
+	document, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []AccessibilityIssue
+	document.Find("img").Each(func(_ int, image *goquery.Selection) {
+		if _, ok := image.Attr("alt"); ok {
+			return
+		}
+		src, _ := image.Attr("src")
+		issues = append(issues, AccessibilityIssue{
+			Rule:    RuleImageAlt,
+			Message: fmt.Sprintf("image %q has no alt text, add one or alt=\"\" for decorative images%s", src, issueContext(image)),
+		})
+	})
+
+	document.Find("a[href]").Each(func(_ int, link *goquery.Selection) {
+		if hasDiscernibleText(link) {
+			return
+		}
+		href, _ := link.Attr("href")
+		issues = append(issues, AccessibilityIssue{
+			Rule:    RuleLinkText,
+			Message: fmt.Sprintf("link to %q has no discernible text, add text, an aria-label or alt text to its image%s", href, issueContext(link)),
+		})
+	})
+
+	return issues, nil
+}
+
+// hasDiscernibleText reports whether a link has a name screen readers can announce
+func hasDiscernibleText(link *goquery.Selection) bool {
+	if strings.TrimSpace(link.Text()) != "" {
+		return true
+	}
+	for _, attr := range []string{"aria-label", "aria-labelledby", "title"} {
+		if value, _ := link.Attr(attr); strings.TrimSpace(value) != "" {
+			return true
+		}
+	}
+
+	described := false
+	link.Find("img[alt]").EachWithBreak(func(_ int, image *goquery.Selection) bool {
+		alt, _ := image.Attr("alt")
+		described = strings.TrimSpace(alt) != ""
+		return !described
+	})
+	return described
+}
+
+// issueContext quotes the text of the closest block around an element, empty when the block has no text
+func issueContext(element *goquery.Selection) string {
+	block := element.Closest("p, li, td, th, figure, blockquote, h1, h2, h3, h4, h5, h6")
+	text := []rune(strings.Join(strings.Fields(block.Text()), " "))
+	if len(text) == 0 {
+		return ""
+	}
+	if len(text) > issueContextLength {
+		text = append(text[:issueContextLength], '…')
+	}
+	return fmt.Sprintf(" near %q", string(text))
+}
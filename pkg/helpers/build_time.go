@@ -0,0 +1,33 @@
+package helpers
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+/*
+SourceDateEpoch returns the time set in the SOURCE_DATE_EPOCH environment variable, reporting whether it is set
+Timestamps written to the rendered site are derived from it so that builds of unchanged content can be reproduced
+*/
+func SourceDateEpoch() (time.Time, bool, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Time{}, false, nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(seconds, 0).UTC(), true, nil
+}
+
+// BuildTime returns the time the site is built, which is SOURCE_DATE_EPOCH when it is set and the current time otherwise
+func BuildTime() (time.Time, error) {
+	epoch, ok, err := SourceDateEpoch()
+	if err != nil || ok {
+		return epoch, err
+	}
+	return time.Now().UTC(), nil
+}
@@ -1,23 +1,73 @@
 package helpers
 
 import (
+	"errors"
 	"io"
 	"log"
 	"os"
+	"runtime"
 	"strings"
+	"sync"
 )
 
 var version = "2.1.0" // use variable
 
 type Helper struct {
 	ErrorLogger *log.Logger
+
+	// Number of files copied concurrently by CopyDirectoryContents, defaults to the number of CPUs
+	Jobs int
 }
 
 /*
 CopyDirectoryContents
 Copies the contents of the dirPath directory to outDirPath
+
+The directory tree is created before the files are copied by a pool of Jobs workers
+The errors of every worker are reported together and fail the build
 */
 func (h *Helper) CopyDirectoryContents(dirPath string, outDirPath string) {
+	var copies [][2]string
+	h.collectCopies(dirPath, outDirPath, &copies)
+
+	jobs := h.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	paths := make(chan [2]string)
+	var (
+		errs   []error
+		errsMu sync.Mutex
+		wg     sync.WaitGroup
+	)
+	for range min(jobs, len(copies)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				if err := copyFile(path[0], path[1]); err != nil {
+					errsMu.Lock()
+					errs = append(errs, err)
+					errsMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, path := range copies {
+		paths <- path
+	}
+	close(paths)
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		h.ErrorLogger.Fatal(err)
+	}
+}
+
+// collectCopies creates the directories of outDirPath and lists the source and destination paths of the files to be copied
+func (h *Helper) collectCopies(dirPath string, outDirPath string, copies *[][2]string) {
 	dirEntries, err := os.ReadDir(dirPath)
 	if err != nil {
 		h.ErrorLogger.Fatal(err)
@@ -28,28 +78,16 @@ func (h *Helper) CopyDirectoryContents(dirPath string, outDirPath string) {
 		h.ErrorLogger.Fatal(err)
 	}
 
-	// Copying the contents of the dirPath directory
 	for _, entry := range dirEntries {
 		if entry.IsDir() {
-			h.CopyDirectoryContents(dirPath+entry.Name()+"/", outDirPath+entry.Name()+"/")
+			h.collectCopies(dirPath+entry.Name()+"/", outDirPath+entry.Name()+"/", copies)
 		} else {
-			h.CopyFiles(dirPath+entry.Name(), outDirPath+entry.Name())
+			*copies = append(*copies, [2]string{dirPath + entry.Name(), outDirPath + entry.Name()})
 		}
 	}
 }
 
 func (h *Helper) CopyFiles(srcPath string, destPath string) {
-	source, err := os.Open(srcPath)
-	if err != nil {
-		h.ErrorLogger.Fatal(err)
-	}
-	defer func() {
-		err = source.Close()
-		if err != nil {
-			h.ErrorLogger.Fatal(err)
-		}
-	}()
-
 	// Creating subdirectories if the filepath contains '/'
 	if strings.Contains(destPath, "/") {
 		// Extracting the directory path from the page path
@@ -63,21 +101,31 @@ func (h *Helper) CopyFiles(srcPath string, destPath string) {
 		}
 	}
 
+	if err := copyFile(srcPath, destPath); err != nil {
+		h.ErrorLogger.Fatal(err)
+	}
+}
+
+// copyFile copies the contents of srcPath to destPath, whose directory must exist
+func copyFile(srcPath string, destPath string) (err error) {
+	source, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
 	destination, err := os.Create(destPath)
 	if err != nil {
-		h.ErrorLogger.Fatal(err)
+		return err
 	}
 	defer func() {
-		err = destination.Close()
-		if err != nil {
-			h.ErrorLogger.Fatal(err)
+		if closeErr := destination.Close(); err == nil {
+			err = closeErr
 		}
 	}()
 
 	_, err = io.Copy(destination, source)
-	if err != nil {
-		h.ErrorLogger.Fatal(err)
-	}
+	return err
 }
 
 func (h *Helper) CreateRenderedDir(fileOutPath string) {
@@ -1,11 +1,19 @@
 package helpers_test
 
 import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/anna-ssg/anna/v3/pkg/helpers"
 )
@@ -27,6 +35,80 @@ func TestCopyDirectoryContents(t *testing.T) {
 	})
 }
 
+func TestCopyDirectoryContentsConcurrently(t *testing.T) {
+	srcDir := t.TempDir() + "/"
+	outDir := t.TempDir() + "/static/"
+
+	want := make(map[string]string)
+	for i := range 200 {
+		path := fmt.Sprintf("dir%d/nested%d/file%d.txt", i%7, i%3, i)
+		want[path] = strings.Repeat(fmt.Sprintf("contents of file %d\n", i), i+1)
+
+		if err := os.MkdirAll(filepath.Dir(srcDir+path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(srcDir+path, []byte(want[path]), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	helper := helpers.Helper{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Jobs:        8,
+	}
+	helper.CopyDirectoryContents(srcDir, outDir)
+
+	copied := 0
+	err := filepath.WalkDir(outDir, func(path string, dir fs.DirEntry, err error) error {
+		if err != nil || dir.IsDir() {
+			return err
+		}
+		copied++
+
+		relPath, _ := filepath.Rel(outDir, path)
+		got, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if string(got) != want[filepath.ToSlash(relPath)] {
+			t.Errorf("%s was not copied with the correct contents", relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if copied != len(want) {
+		t.Errorf("got %d copied files, want %d", copied, len(want))
+	}
+}
+
+func BenchmarkCopyDirectoryContents(b *testing.B) {
+	srcDir := b.TempDir() + "/"
+	for i := range 2000 {
+		path := fmt.Sprintf("%sdir%d/image%d.png", srcDir, i%20, i)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(path, bytes.Repeat([]byte{byte(i)}, 64*1024), 0640); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, jobs := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			helper := helpers.Helper{
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				Jobs:        jobs,
+			}
+			for range b.N {
+				helper.CopyDirectoryContents(srcDir, b.TempDir()+"/")
+			}
+		})
+	}
+}
+
 func testfuncTraverseDirectory(baseDirFS fs.FS, t *testing.T) error {
 	err := fs.WalkDir(baseDirFS, ".", func(path string, dir fs.DirEntry, err error) error {
 		if !dir.IsDir() {
@@ -72,3 +154,228 @@ func TestCopyFiles(t *testing.T) {
 		}
 	})
 }
+
+func TestWarningCollector(t *testing.T) {
+	t.Run("record warnings from concurrent reporters", func(t *testing.T) {
+		warnings := helpers.NewWarningCollector()
+		warnings.Logger = log.New(io.Discard, "", 0)
+
+		var wg sync.WaitGroup
+		for i := range 10 {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				warnings.Warnf("warning %d", i)
+			}(i)
+		}
+		wg.Wait()
+
+		if got := warnings.Count(); got != 10 {
+			t.Errorf("got %d warnings, want 10", got)
+		}
+		if !slices.Contains(warnings.Warnings(), "warning 3") {
+			t.Errorf("got %v, want it to contain 'warning 3'", warnings.Warnings())
+		}
+	})
+
+	t.Run("nil collector does not record warnings", func(t *testing.T) {
+		var warnings *helpers.WarningCollector
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(os.Stderr)
+
+		warnings.Warn("warning")
+
+		if got := warnings.Count(); got != 0 {
+			t.Errorf("got %d warnings, want 0", got)
+		}
+	})
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name   string
+		html   string
+		length int
+		want   string
+	}{
+		{"strip tags and whitespace", "<h1>Hello</h1>\n<p>World &amp; more</p>", 100, "Hello World & more"},
+		{"truncate at a word boundary", "<p>The quick brown fox</p>", 12, "The quick…"},
+		{"exact length", "<p>fox</p>", 3, "fox"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := helpers.Excerpt(tt.html, tt.length); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordCount(t *testing.T) {
+	tests := []struct {
+		html string
+		want int
+	}{
+		{"", 0},
+		{"<h1>Hello</h1>\n<p>World &amp; more</p>", 4},
+		{"<p>one<br>two</p><img src=\"a.png\">", 2},
+	}
+
+	for _, tt := range tests {
+		if got := helpers.WordCount(tt.html); got != tt.want {
+			t.Errorf("WordCount(%q) = %v, want %v", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	tests := []struct {
+		words int
+		want  int
+	}{
+		{0, 0},
+		{1, 1},
+		{helpers.WordsPerMinute, 1},
+		{helpers.WordsPerMinute + 1, 2},
+	}
+
+	for _, tt := range tests {
+		html := "<p>" + strings.Repeat("word ", tt.words) + "</p>"
+		if got := helpers.ReadingTime(html); got != tt.want {
+			t.Errorf("ReadingTime of %v words = %v, want %v", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"go", "go"},
+		{"My Tag", "my-tag"},
+		{"C++", "c"},
+		{"node.js", "nodejs"},
+		{"  web  dev_tools - 2024 ", "web-dev-tools-2024"},
+		{"Café", "café"},
+		{"++", ""},
+	}
+
+	for _, tt := range tests {
+		if got := helpers.Slugify(tt.name); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAccessibilityIssues(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []helpers.AccessibilityIssue
+	}{
+		{
+			name: "described images and links",
+			html: `<p><img src="a.png" alt="A cat"> <img src="line.png" alt=""> <a href="/about">About</a></p>
+<a href="/home" aria-label="Home"><svg></svg></a> <a href="/logo"><img src="logo.png" alt="Logo"></a> <a name="anchor"></a>`,
+		},
+		{
+			name: "image without alt",
+			html: `<p>Look at this chart <img src="chart.png"></p>`,
+			want: []helpers.AccessibilityIssue{{
+				Rule:    helpers.RuleImageAlt,
+				Message: `image "chart.png" has no alt text, add one or alt="" for decorative images near "Look at this chart"`,
+			}},
+		},
+		{
+			name: "links without text",
+			html: `<li><a href="/empty"> </a></li><a href="/icon"><img src="icon.png" alt=""></a>`,
+			want: []helpers.AccessibilityIssue{
+				{
+					Rule:    helpers.RuleLinkText,
+					Message: `link to "/empty" has no discernible text, add text, an aria-label or alt text to its image`,
+				},
+				{
+					Rule:    helpers.RuleLinkText,
+					Message: `link to "/icon" has no discernible text, add text, an aria-label or alt text to its image`,
+				},
+			},
+		},
+		{
+			name: "long context is truncated",
+			html: `<p>` + strings.Repeat("word ", 20) + `<img src="x.png"></p>`,
+			want: []helpers.AccessibilityIssue{{
+				Rule:    helpers.RuleImageAlt,
+				Message: `image "x.png" has no alt text, add one or alt="" for decorative images near "` + strings.Repeat("word ", 12) + `…"`,
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := helpers.AccessibilityIssues(tt.html)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("AccessibilityIssues() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// Test vectors from RFC 7914
+	tests := []struct {
+		password   string
+		salt       string
+		iterations int
+		keyLen     int
+		want       string
+	}{
+		{"passwd", "salt", 1, 64, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, 64, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s with %d iterations", tt.password, tt.iterations), func(t *testing.T) {
+			got := hex.EncodeToString(helpers.PBKDF2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, tt.keyLen))
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTime(t *testing.T) {
+	t.Run("build time from SOURCE_DATE_EPOCH", func(t *testing.T) {
+		t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+		got, err := helpers.BuildTime()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := time.Unix(1700000000, 0).UTC(); !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("build time without SOURCE_DATE_EPOCH", func(t *testing.T) {
+		t.Setenv("SOURCE_DATE_EPOCH", "")
+		before := time.Now()
+		got, err := helpers.BuildTime()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Before(before.Truncate(time.Second)) || got.After(time.Now()) {
+			t.Errorf("got %v, want the current time", got)
+		}
+	})
+
+	t.Run("invalid SOURCE_DATE_EPOCH", func(t *testing.T) {
+		t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+		if _, err := helpers.BuildTime(); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// PlainText strips the tags from rendered HTML and collapses whitespace
+func PlainText(htmlContent string) string {
+	text := htmlTagRegex.ReplaceAllString(htmlContent, " ")
+	text = html.UnescapeString(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Excerpt returns the plain text of rendered HTML truncated to at most length characters at a word boundary
+func Excerpt(htmlContent string, length int) string {
+	text := []rune(PlainText(htmlContent))
+	if len(text) <= length {
+		return string(text)
+	}
+
+	excerpt := string(text[:length])
+	if index := strings.LastIndex(excerpt, " "); index > 0 {
+		excerpt = excerpt[:index]
+	}
+	return excerpt + "…"
+}
+
+// WordCount returns the number of words in the plain text of rendered HTML
+func WordCount(htmlContent string) int {
+	return len(strings.Fields(PlainText(htmlContent)))
+}
+
+// WordsPerMinute is the average reading speed used to estimate the reading time of pages
+const WordsPerMinute = 200
+
+// ReadingTime returns the estimated number of minutes needed to read rendered HTML, rounded up
+func ReadingTime(htmlContent string) int {
+	return (WordCount(htmlContent) + WordsPerMinute - 1) / WordsPerMinute
+}
+
+// Slugify converts a name into a URL-safe slug, lowercasing it, replacing whitespace with hyphens and stripping other special characters
+func Slugify(name string) string {
+	var slug strings.Builder
+	separate := false
+
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if separate && slug.Len() > 0 {
+				slug.WriteRune('-')
+			}
+			separate = false
+			slug.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			separate = true
+		}
+	}
+
+	return slug.String()
+}
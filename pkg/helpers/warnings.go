@@ -0,0 +1,69 @@
+package helpers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// WarningCollector gathers the warnings reported by the parser, engine and validators during a build
+// All methods are safe for concurrent use and a nil collector only logs warnings
+type WarningCollector struct {
+	Logger *log.Logger
+
+	mu       sync.Mutex
+	warnings []string
+}
+
+func NewWarningCollector() *WarningCollector {
+	return &WarningCollector{
+		Logger: log.New(os.Stderr, "WARN\t", log.Ldate|log.Ltime),
+	}
+}
+
+// Warn logs a warning and records it
+func (w *WarningCollector) Warn(v ...any) {
+	message := fmt.Sprint(v...)
+	if w == nil {
+		log.Println("WARN\t" + message)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.warnings = append(w.warnings, message)
+	if w.Logger != nil {
+		w.Logger.Println(message)
+	}
+}
+
+// Warnf logs a formatted warning and records it
+func (w *WarningCollector) Warnf(format string, v ...any) {
+	w.Warn(fmt.Sprintf(format, v...))
+}
+
+// Warnings returns the warnings recorded so far
+func (w *WarningCollector) Warnings() []string {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]string(nil), w.warnings...)
+}
+
+// Count returns the number of warnings recorded so far
+func (w *WarningCollector) Count() int {
+	if w == nil {
+		return 0
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.warnings)
+}
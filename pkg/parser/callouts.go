@@ -0,0 +1,128 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// calloutTitles maps the types of GitHub-style alerts to the titles of their callouts
+var calloutTitles = map[string]string{
+	"note":      "Note",
+	"tip":       "Tip",
+	"important": "Important",
+	"warning":   "Warning",
+	"caution":   "Caution",
+}
+
+var calloutMarkerRegex = regexp.MustCompile(`^\[!(\w+)\]$`)
+
+// kindCallout is the kind of the callout blocks of the markdown AST
+var kindCallout = ast.NewNodeKind("Callout")
+
+// calloutNode is a blockquote starting with an alert marker such as [!NOTE]
+type calloutNode struct {
+	ast.BaseBlock
+	calloutType string
+}
+
+func (n *calloutNode) Kind() ast.NodeKind {
+	return kindCallout
+}
+
+func (n *calloutNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Type": n.calloutType}, nil)
+}
+
+/*
+calloutExtension renders GitHub-style alerts as callouts
+
+	> [!WARNING]
+	> Back up your site before upgrading
+
+is rendered as <div class="callout callout-warning" role="note"> with a "Warning" title
+Blockquotes without a marker of a known type are rendered as blockquotes
+*/
+type calloutExtension struct{}
+
+func (calloutExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(calloutTransformer{}, 999)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(calloutRenderer{}, 500)))
+}
+
+type calloutTransformer struct{}
+
+func (calloutTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	var blockquotes []*ast.Blockquote
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if blockquote, ok := node.(*ast.Blockquote); ok && entering {
+			blockquotes = append(blockquotes, blockquote)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, blockquote := range blockquotes {
+		paragraph, ok := blockquote.FirstChild().(*ast.Paragraph)
+		if !ok || paragraph.Lines().Len() == 0 {
+			continue
+		}
+
+		// The marker must be alone on the first line of the blockquote
+		markerLine := paragraph.Lines().At(0)
+		match := calloutMarkerRegex.FindStringSubmatch(strings.TrimSpace(string(markerLine.Value(source))))
+		if match == nil {
+			continue
+		}
+		calloutType := strings.ToLower(match[1])
+		if _, ok := calloutTitles[calloutType]; !ok {
+			continue
+		}
+
+		// Removing the text of the marker, along with the paragraph if the content starts in the next paragraph
+		for child := paragraph.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			textNode, ok := child.(*ast.Text)
+			if !ok || textNode.Segment.Start >= markerLine.Stop {
+				break
+			}
+			paragraph.RemoveChild(paragraph, child)
+			child = next
+		}
+		if !paragraph.HasChildren() {
+			blockquote.RemoveChild(blockquote, paragraph)
+		}
+
+		callout := &calloutNode{calloutType: calloutType}
+		for child := blockquote.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			callout.AppendChild(callout, child)
+			child = next
+		}
+		blockquote.Parent().ReplaceChild(blockquote.Parent(), blockquote, callout)
+	}
+}
+
+type calloutRenderer struct{}
+
+func (r calloutRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindCallout, r.renderCallout)
+}
+
+func (r calloutRenderer) renderCallout(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	callout := node.(*calloutNode)
+	if entering {
+		_, _ = w.WriteString("<div class=\"callout callout-" + callout.calloutType + "\" role=\"note\">\n")
+		_, _ = w.WriteString("<p class=\"callout-title\">" + calloutTitles[callout.calloutType] + "</p>\n")
+	} else {
+		_, _ = w.WriteString("</div>\n")
+	}
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
+)
+
+/*
+CollectionQuery defines a collection in the site config by the pages it contains, instead of the collections
+listed in the frontmatter of every page. A page is in the collection when it matches every filter that is set
+*/
+type CollectionQuery struct {
+	// Name of the collection, nested collections are separated by > as in the frontmatter, such as "posts>featured"
+	Name string `json:"name"`
+	// Glob pattern matched against the path of the markdown file relative to the content directory, and against its parent directories
+	Path string `json:"path"`
+	// Tags every page of the collection has
+	Tags []string `json:"tags"`
+	// Type of the pages of the collection
+	Type string `json:"type"`
+	// Earliest and latest dates of the pages of the collection in the YYYY-MM-DD format, both included
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// validate returns the errors in the fields of a collection query
+func (q CollectionQuery) validate() []string {
+	var errs []string
+	if strings.TrimSpace(q.Name) == "" {
+		errs = append(errs, "collections: a collection is missing its name")
+	}
+	if _, err := path.Match(q.Path, ""); err != nil {
+		errs = append(errs, fmt.Sprintf("collections: invalid path pattern %q of the %q collection: %v", q.Path, q.Name, err))
+	}
+	for _, date := range []string{q.From, q.To} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			errs = append(errs, fmt.Sprintf("collections: invalid date %q of the %q collection, expected YYYY-MM-DD", date, q.Name))
+		}
+	}
+	return errs
+}
+
+/*
+matches reports whether a page, at a slash-separated path relative to its content directory, is in the collection
+The type of the frontmatter is expected to be set, and tags are compared by their slug after resolving their aliases
+*/
+func (q CollectionQuery) matches(filePath string, frontmatter Frontmatter, tags []string) bool {
+	if q.Path != "" && !matchesPathOrParent(strings.Trim(q.Path, "/"), filePath) {
+		return false
+	}
+	if q.Type != "" && q.Type != frontmatter.Type {
+		return false
+	}
+	pageTags := make(map[string]bool, len(frontmatter.Tags))
+	for _, tag := range frontmatter.Tags {
+		pageTags[helpers.Slugify(tag)] = true
+	}
+	for _, tag := range tags {
+		if !pageTags[helpers.Slugify(tag)] {
+			return false
+		}
+	}
+
+	// Dates in the YYYY-MM-DD format are ordered as strings, the time of the day is ignored and undated pages are not in collections with a date range
+	if q.From != "" || q.To != "" {
+		day, _, _ := strings.Cut(strings.Replace(frontmatter.Date, " ", "T", 1), "T")
+		if day == "" || (q.From != "" && day < q.From) || (q.To != "" && day > q.To) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesPathOrParent reports whether a pattern matches a path or any of its parent directories
+func matchesPathOrParent(pattern string, filePath string) bool {
+	for current := filePath; current != "." && current != "/" && current != ""; current = path.Dir(current) {
+		if matched, _ := path.Match(pattern, current); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// queriedCollections returns the collections of the collections config a page is in, which are not already listed in its frontmatter
+func (p *Parser) queriedCollections(filePath string, frontmatter Frontmatter) []string {
+	var collections []string
+	for _, query := range p.LayoutConfig.Collections {
+		if slices.Contains(frontmatter.Collections, query.Name) || slices.Contains(collections, query.Name) {
+			continue
+		}
+		if query.matches(filePath, frontmatter, p.normalizeTags(query.Tags)) {
+			collections = append(collections, query.Name)
+		}
+	}
+	return collections
+}
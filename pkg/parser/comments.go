@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"html/template"
+	"strings"
+)
+
+// commentsHTML returns the embed of the configured comment system for a post
+// Pages that are not posts and posts with comments disabled in the frontmatter get no embed
+func (p *Parser) commentsHTML(page TemplateData) template.HTML {
+	comments := p.LayoutConfig.Comments
+	if comments == nil || page.Frontmatter.Type != "post" {
+		return ""
+	}
+	if page.Frontmatter.Comments != nil && !*page.Frontmatter.Comments {
+		return ""
+	}
+
+	mapping := comments.Mapping
+	if mapping == "" {
+		mapping = "pathname"
+	}
+
+	var embed strings.Builder
+	switch comments.Provider {
+	case "giscus":
+		if comments.Repo == "" || comments.RepoID == "" || comments.CategoryID == "" {
+			p.Warnings.Warn("comments: giscus requires a repo, repoID and categoryID, skipping the embed")
+			return ""
+		}
+		theme := comments.Theme
+		if theme == "" {
+			theme = "preferred_color_scheme"
+		}
+		embed.WriteString("<script src=\"https://giscus.app/client.js\"")
+		writeAttr(&embed, "data-repo", comments.Repo)
+		writeAttr(&embed, "data-repo-id", comments.RepoID)
+		writeAttr(&embed, "data-category", comments.Category)
+		writeAttr(&embed, "data-category-id", comments.CategoryID)
+		writeAttr(&embed, "data-mapping", mapping)
+		writeAttr(&embed, "data-reactions-enabled", "1")
+		writeAttr(&embed, "data-theme", theme)
+		embed.WriteString(" crossorigin=\"anonymous\" async></script>")
+
+	case "utterances":
+		if comments.Repo == "" {
+			p.Warnings.Warn("comments: utterances requires a repo, skipping the embed")
+			return ""
+		}
+		theme := comments.Theme
+		if theme == "" {
+			theme = "preferred-color-scheme"
+		}
+		embed.WriteString("<script src=\"https://utteranc.es/client.js\"")
+		writeAttr(&embed, "repo", comments.Repo)
+		writeAttr(&embed, "issue-term", mapping)
+		writeAttr(&embed, "theme", theme)
+		embed.WriteString(" crossorigin=\"anonymous\" async></script>")
+
+	case "disqus":
+		if comments.Shortname == "" {
+			p.Warnings.Warn("comments: disqus requires a shortname, skipping the embed")
+			return ""
+		}
+		pageURL := strings.TrimSuffix(p.LayoutConfig.BaseURL, "/") + "/" + string(page.CompleteURL)
+		embed.WriteString("<div id=\"disqus_thread\"></div>\n<script>var disqus_config = function () { this.page.url = '")
+		embed.WriteString(template.JSEscapeString(pageURL))
+		embed.WriteString("'; this.page.identifier = '")
+		embed.WriteString(template.JSEscapeString(string(page.CompleteURL)))
+		embed.WriteString("'; }; (function () { var d = document, s = d.createElement('script'); s.src = 'https://")
+		embed.WriteString(template.JSEscapeString(comments.Shortname))
+		embed.WriteString(".disqus.com/embed.js'; s.setAttribute('data-timestamp', +new Date()); (d.head || d.body).appendChild(s); })();</script>")
+
+	default:
+		p.Warnings.Warnf("comments: unknown provider %q, expected giscus, utterances or disqus", comments.Provider)
+		return ""
+	}
+
+	return template.HTML(embed.String())
+}
+
+func writeAttr(embed *strings.Builder, name string, value string) {
+	embed.WriteString(" " + name + "=\"" + template.HTMLEscapeString(value) + "\"")
+}
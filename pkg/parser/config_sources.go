@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Sources of the values of the config reported by ConfigSources, environment variables are reported as "env <name>"
+const (
+	ConfigSourceDefault = "default"
+	ConfigSourceFile    = "config.json"
+)
+
+// ConfigKeys returns the keys of config.json in the order of the fields of LayoutConfig
+func ConfigKeys() []string {
+	configType := reflect.TypeOf(LayoutConfig{})
+	keys := make([]string, 0, configType.NumField())
+	for i := range configType.NumField() {
+		key, _, _ := strings.Cut(configType.Field(i).Tag.Get("json"), ",")
+		if key != "" && key != "-" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+/*
+ConfigSources returns where the value of every key of the config comes from, the default value of the field,
+the config file or an ANNA_* environment variable, which takes precedence over the config file as in ParseConfig
+*/
+func (p *Parser) ConfigSources(inFilePath string) map[string]string {
+	configFile, err := os.ReadFile(inFilePath)
+	if err != nil {
+		p.ErrorLogger.Fatal(err)
+	}
+
+	var setKeys map[string]json.RawMessage
+	if err := json.Unmarshal(configFile, &setKeys); err != nil {
+		p.ErrorLogger.Println("Error at: ", inFilePath)
+		p.ErrorLogger.Fatal(err)
+	}
+
+	// Keys of the config file are matched case-insensitively, as when it is decoded
+	lowerSetKeys := make(map[string]bool, len(setKeys))
+	for key := range setKeys {
+		lowerSetKeys[strings.ToLower(key)] = true
+	}
+
+	sources := make(map[string]string)
+	for _, key := range ConfigKeys() {
+		sources[key] = ConfigSourceDefault
+		if lowerSetKeys[strings.ToLower(key)] {
+			sources[key] = ConfigSourceFile
+		}
+	}
+	for envVar, override := range envOverrides {
+		if _, ok := os.LookupEnv(envVar); ok {
+			sources[override.key] = "env " + envVar
+		}
+	}
+	return sources
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"cmp"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ContentRoot is a directory of markdown content of the site and the prefix of the URLs of its pages
+type ContentRoot struct {
+	// Path to the directory, with a trailing slash
+	Path string
+	// Prefix of the URLs of the pages in the directory, empty or with a trailing slash
+	URLPrefix string
+}
+
+/*
+ContentRoots returns the directories of markdown content of the site
+They are set by the contentDirs config, and default to the single directory of the contentDir config
+*/
+func (p *Parser) ContentRoots() []ContentRoot {
+	if len(p.LayoutConfig.ContentDirs) == 0 {
+		return []ContentRoot{{Path: p.ContentDirPath()}}
+	}
+
+	roots := make([]ContentRoot, 0, len(p.LayoutConfig.ContentDirs))
+	for _, contentDir := range p.LayoutConfig.ContentDirs {
+		root := ContentRoot{Path: p.SiteDataPath + strings.Trim(cmp.Or(contentDir.Dir, "content"), "/") + "/"}
+		if prefix := strings.Trim(contentDir.URLPrefix, "/"); prefix != "" {
+			root.URLPrefix = prefix + "/"
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// IsExcludedDir reports whether a directory of content, at a slash-separated path relative to its content directory, matches a pattern of the excludeDirs config
+func (c LayoutConfig) IsExcludedDir(dirPath string) bool {
+	for _, pattern := range c.ExcludeDirs {
+		if matched, _ := path.Match(strings.Trim(pattern, "/"), dirPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseContentDirs parses the markdown files of every content directory of the site
+func (p *Parser) ParseContentDirs() {
+	for _, root := range p.ContentRoots() {
+		p.ParseMDDir(root.Path, os.DirFS(root.Path))
+	}
+	p.TranscludeNotes()
+	if p.LayoutConfig.CheckDescriptions {
+		p.checkDescriptions()
+	}
+}
+
+// contentKey returns the path of a file in a content directory relative to the site, prefixed with the URL prefix of the directory
+// The deepest directory containing the file is used when content directories are nested
+func (p *Parser) contentKey(filePath string) string {
+	roots := p.ContentRoots()
+	sort.SliceStable(roots, func(i, j int) bool {
+		return len(roots[i].Path) > len(roots[j].Path)
+	})
+
+	for _, root := range roots {
+		if key, ok := strings.CutPrefix(filePath, root.Path); ok {
+			return root.URLPrefix + key
+		}
+	}
+	return filePath
+}
@@ -0,0 +1,26 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// The {year} and {year-range 2019} placeholders of the copyright config
+var copyrightYearRegex = regexp.MustCompile(`\{year(?:-range\s+(\d{4}))?\}`)
+
+/*
+CopyrightNotice returns the copyright config with {year} replaced by the year of the build, and {year-range 2019}
+by the years from 2019 to the year of the build, such as "2019-2026", so that the footer of the site does not go stale
+Copyrights without a placeholder are returned as they are
+*/
+func (l LayoutConfig) CopyrightNotice(buildTime time.Time) string {
+	year := buildTime.Year()
+	return copyrightYearRegex.ReplaceAllStringFunc(l.Copyright, func(match string) string {
+		start, err := strconv.Atoi(copyrightYearRegex.FindStringSubmatch(match)[1])
+		if err != nil || start >= year {
+			return strconv.Itoa(year)
+		}
+		return strconv.Itoa(start) + "-" + strconv.Itoa(year)
+	})
+}
@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+/*
+checkDescriptions warns about the posts without a description and the posts sharing their description with another post,
+as descriptions are used in search results and link previews
+Descriptions are compared ignoring case and whitespace
+*/
+func (p *Parser) checkDescriptions() {
+	shared := make(map[string][]string)
+	for _, post := range p.Posts {
+		description := strings.ToLower(strings.Join(strings.Fields(post.Frontmatter.Description), " "))
+		if description == "" {
+			p.Warnings.Warnf("%s: post is missing a description", post.CompleteURL)
+			continue
+		}
+		shared[description] = append(shared[description], string(post.CompleteURL))
+	}
+
+	var duplicates []string
+	for _, posts := range shared {
+		if len(posts) > 1 {
+			sort.Strings(posts)
+			duplicates = append(duplicates, strings.Join(posts, ", "))
+		}
+	}
+	sort.Strings(duplicates)
+	for _, posts := range duplicates {
+		p.Warnings.Warnf("%s: posts share the same description", posts)
+	}
+}
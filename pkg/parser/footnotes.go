@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// footnotesStart is the opening tag of the list of footnotes rendered by goldmark at the end of the body
+const footnotesStart = `<div class="footnotes" role="doc-endnotes">`
+
+var (
+	footnotesRuleRegex = regexp.MustCompile(`^\s*<hr\s*/?>\n`)
+	footnoteItemRegex  = regexp.MustCompile(`<li id="fn:([^"]+)">\n`)
+)
+
+// Footnote is a footnote of a page, ID is the anchor the references of the footnote link to, such as fn:1
+type Footnote struct {
+	ID    string
+	Index int
+	Body  template.HTML
+}
+
+/*
+footnotes returns the footnotes of a rendered body, along with the body in which the list of footnotes is titled
+with the footnoteHeading config, or from which it is removed when the separateFootnotes config is set
+*/
+func (p *Parser) footnotes(body string) (string, []Footnote) {
+	start := strings.LastIndex(body, footnotesStart)
+	if start < 0 {
+		return body, nil
+	}
+	end := strings.LastIndex(body, "</div>")
+	if end < start {
+		return body, nil
+	}
+
+	section := body[start+len(footnotesStart) : end]
+	list := section
+	if listStart := strings.Index(section, "<ol>"); listStart >= 0 {
+		list = section[listStart+len("<ol>"):]
+	}
+	list = strings.TrimSuffix(strings.TrimSpace(list), "</ol>")
+
+	var footnotes []Footnote
+	items := footnoteItemRegex.FindAllStringSubmatchIndex(list, -1)
+	for i, item := range items {
+		itemEnd := len(list)
+		if i+1 < len(items) {
+			itemEnd = items[i+1][0]
+		}
+		itemBody := strings.TrimSuffix(strings.TrimSpace(list[item[1]:itemEnd]), "</li>")
+		footnotes = append(footnotes, Footnote{
+			ID:    "fn:" + list[item[2]:item[3]],
+			Index: i + 1,
+			Body:  template.HTML(strings.TrimSpace(itemBody)),
+		})
+	}
+
+	if p.LayoutConfig.SeparateFootnotes {
+		return body[:start] + body[end+len("</div>"):], footnotes
+	}
+
+	if heading := p.LayoutConfig.FootnoteHeading; heading != "" {
+		titled := footnotesRuleRegex.ReplaceAllString(strings.TrimPrefix(section, "\n"), "")
+		body = body[:start] + footnotesStart + "\n<h2 class=\"footnotes-heading\">" + html.EscapeString(heading) + "</h2>\n" + titled + body[end:]
+	}
+	return body, footnotes
+}
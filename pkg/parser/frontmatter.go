@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FrontmatterError is an error in the frontmatter of a markdown file
+// Line and Column are 1-based positions in the file, Column is 0 when it is not known
+type FrontmatterError struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e FrontmatterError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Message)
+}
+
+var (
+	frontmatterTitleRegex = regexp.MustCompile(`title(.*): (.*)`)
+	yamlErrorLineRegex    = regexp.MustCompile(`line (\d+): (.*)`)
+	// A CSS class that can be selected without escaping, such as wide or dark-variant
+	classNameRegex = regexp.MustCompile(`^-?[_a-zA-Z][_a-zA-Z0-9-]*$`)
+)
+
+/*
+ParseFrontmatter splits a markdown file into its frontmatter and markdown content
+The errors of the frontmatter are returned with their positions in the file rather than in the frontmatter block
+*/
+func ParseFrontmatter(filecontent string, path string) (Frontmatter, string, []FrontmatterError) {
+	var frontmatter Frontmatter
+
+	/*
+	   ---
+	   frontmatter_content
+	   ---
+
+	   markdown content
+	   --- => markdown divider and not to be touched while yaml parsing
+	*/
+	splitContents := strings.Split(filecontent, "---")
+	if len(splitContents) <= 1 {
+		return frontmatter, "", []FrontmatterError{{Path: path, Line: 1, Message: "frontmatter is missing"}}
+	}
+
+	// Line 1 of the frontmatter block is the line of the opening ---
+	lineOffset := strings.Count(splitContents[0], "\n")
+	frontmatterBlock := splitContents[1]
+	markdown := strings.Join(splitContents[2:], "---")
+
+	// If the first section of the page contains a title field, continue parsing
+	// Else, prevent parsing of the current file
+	if frontmatterTitleRegex.FindStringSubmatch(frontmatterBlock) == nil {
+		return frontmatter, markdown, []FrontmatterError{{Path: path, Line: lineOffset + 1, Message: "title field is missing from the frontmatter"}}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(frontmatterBlock), &root); err != nil {
+		return frontmatter, markdown, yamlErrors(err, path, lineOffset, nil)
+	}
+
+	if err := root.Decode(&frontmatter); err != nil {
+		return frontmatter, markdown, yamlErrors(err, path, lineOffset, &root)
+	}
+
+	var errs []FrontmatterError
+	for _, field := range []string{"date", "updated", "expiryDate"} {
+		value := mappingValue(&root, field)
+		if value == nil || value.Value == "" {
+			continue
+		}
+		if !isDate(value.Value) {
+			errs = append(errs, FrontmatterError{
+				Path:    path,
+				Line:    lineOffset + value.Line,
+				Column:  value.Column,
+				Message: fmt.Sprintf("%s %q is not of the form YYYY-MM-DD or YYYY-MM-DDTHH:MM:SS", field, value.Value),
+			})
+		}
+	}
+
+	return frontmatter, markdown, errs
+}
+
+// isDate reports whether a date of the frontmatter is in one of the layouts of dateLayouts
+func isDate(date string) bool {
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, date); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlErrors converts the errors of the yaml parser to frontmatter errors, locating the column of decoding errors in root
+func yamlErrors(err error, path string, lineOffset int, root *yaml.Node) []FrontmatterError {
+	messages := []string{err.Error()}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	}
+
+	errs := make([]FrontmatterError, 0, len(messages))
+	for _, message := range messages {
+		frontmatterErr := FrontmatterError{Path: path, Line: lineOffset + 1, Message: message}
+
+		if match := yamlErrorLineRegex.FindStringSubmatch(message); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			frontmatterErr.Line = lineOffset + line
+			frontmatterErr.Message = match[2]
+			if node := lastScalarOnLine(root, line); node != nil {
+				frontmatterErr.Column = node.Column
+			}
+		}
+
+		errs = append(errs, frontmatterErr)
+	}
+	return errs
+}
+
+// mappingValue returns the value of a top-level key of the frontmatter
+func mappingValue(root *yaml.Node, key string) *yaml.Node {
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+
+	mapping := root.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// lastScalarOnLine returns the last scalar on a line of the frontmatter, which is the value of a key
+func lastScalarOnLine(node *yaml.Node, line int) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+
+	var last *yaml.Node
+	if node.Kind == yaml.ScalarNode && node.Line == line {
+		last = node
+	}
+	for _, child := range node.Content {
+		if found := lastScalarOnLine(child, line); found != nil {
+			last = found
+		}
+	}
+	return last
+}
+
+// ClassList is a list of CSS classes, written in the frontmatter as a space-separated string or as a list
+type ClassList []string
+
+// UnmarshalYAML splits the classes of a string or of every item of a list on whitespace
+func (c *ClassList) UnmarshalYAML(value *yaml.Node) error {
+	var items []string
+	if value.Kind == yaml.ScalarNode {
+		items = []string{value.Value}
+	} else if err := value.Decode(&items); err != nil {
+		return err
+	}
+
+	*c = nil
+	for _, item := range items {
+		*c = append(*c, strings.Fields(item)...)
+	}
+	return nil
+}
+
+// bodyClass joins the classes of the bodyClass frontmatter field once, leaving out duplicates and the classes that are not valid CSS identifiers
+func (p *Parser) bodyClass(classes ClassList, filePath string) string {
+	var valid []string
+	for _, class := range classes {
+		if !classNameRegex.MatchString(class) {
+			p.Warnings.Warnf("%s: bodyClass %q is not a valid CSS class, it is left out", filePath, class)
+			continue
+		}
+		if !slices.Contains(valid, class) {
+			valid = append(valid, class)
+		}
+	}
+	return strings.Join(valid, " ")
+}
@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var imgTagRegex = regexp.MustCompile(`<img\b[^>]*>`)
+
+// LazyImagesEnabled reports whether images are loaded lazily
+func (l LayoutConfig) LazyImagesEnabled() bool {
+	return l.LazyImages == nil || *l.LazyImages
+}
+
+// ImageDimensionsEnabled reports whether the intrinsic dimensions of local images are added to rendered pages
+func (l LayoutConfig) ImageDimensionsEnabled() bool {
+	return l.ImageDimensions == nil || *l.ImageDimensions
+}
+
+// lazyLoadImages adds loading="lazy" and decoding="async" to the images of rendered HTML, keeping attributes set by the author
+// When eagerFirst is set, the first image is not loaded lazily
+func lazyLoadImages(html string, eagerFirst bool) string {
+	first := true
+	return imgTagRegex.ReplaceAllStringFunc(html, func(img string) string {
+		var attrs string
+		if !(eagerFirst && first) && !strings.Contains(img, "loading=") {
+			attrs += ` loading="lazy"`
+		}
+		if !strings.Contains(img, "decoding=") {
+			attrs += ` decoding="async"`
+		}
+		first = false
+
+		return "<img" + attrs + img[len("<img"):]
+	})
+}
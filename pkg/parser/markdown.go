@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"cmp"
+	"regexp"
+	"sync"
+
+	figure "github.com/mangoumbrella/goldmark-figure"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"go.abhg.dev/goldmark/anchor"
+	"go.abhg.dev/goldmark/mermaid"
+	"go.abhg.dev/goldmark/toc"
+)
+
+// Fenced code blocks of mermaid diagrams, the only blocks the mermaid extension renders
+var mermaidBlockRegex = regexp.MustCompile("(?m)^ {0,3}(?:```|~~~)[ \t]*mermaid\\b")
+
+// markdownOptions are the options a markdown renderer is built with, pages with the same options share a renderer
+type markdownOptions struct {
+	autoHeadingID bool
+	unsafe        bool
+	hardWraps     bool
+	xhtml         bool
+	// Renders a table of contents along with anchors next to the headings
+	toc         bool
+	tocMinDepth int
+	tocMaxDepth int
+	// Renders mermaid diagrams, only set for pages with a mermaid code block
+	mermaid bool
+}
+
+// markdownOptions returns the options of the markdown renderer of a page from the config and its frontmatter
+func (p *Parser) markdownOptions(frontmatter Frontmatter, markdown string) markdownOptions {
+	markdownConfig := p.LayoutConfig.Markdown
+	options := markdownOptions{
+		autoHeadingID: markdownConfig.AutoHeadingIDEnabled(),
+		unsafe:        markdownConfig.UnsafeEnabled(),
+		hardWraps:     markdownConfig.HardWraps,
+		xhtml:         markdownConfig.XHTML,
+		toc:           frontmatter.TOC,
+		mermaid:       mermaidBlockRegex.MatchString(markdown),
+	}
+	if options.toc {
+		// The depths set in the frontmatter of a page take precedence over the config
+		options.tocMinDepth = cmp.Or(frontmatter.TOCMinDepth, p.LayoutConfig.TOCMinDepth)
+		options.tocMaxDepth = cmp.Or(frontmatter.TOCMaxDepth, p.LayoutConfig.TOCMaxDepth)
+	}
+	return options
+}
+
+// extensions returns the goldmark extensions enabled by the options
+func (o markdownOptions) extensions() []goldmark.Extender {
+	extensions := []goldmark.Extender{
+		extension.TaskList,
+		extension.Footnote,
+		figure.Figure,
+		calloutExtension{},
+	}
+	if o.toc {
+		extensions = append(extensions,
+			&toc.Extender{
+				Compact:  true,
+				MinDepth: o.tocMinDepth,
+				MaxDepth: o.tocMaxDepth,
+			},
+			&anchor.Extender{
+				Texter: anchor.Text("#"),
+			},
+		)
+	}
+	if o.mermaid {
+		extensions = append(extensions, &mermaid.Extender{
+			RenderMode: mermaid.RenderModeClient,
+		})
+	}
+	return extensions
+}
+
+// newMarkdown builds a markdown renderer with the options
+func (o markdownOptions) newMarkdown() goldmark.Markdown {
+	var parserOptions []parser.Option
+	if o.autoHeadingID {
+		parserOptions = append(parserOptions, parser.WithAutoHeadingID())
+	}
+
+	var rendererOptions []renderer.Option
+	if o.unsafe {
+		rendererOptions = append(rendererOptions, html.WithUnsafe())
+	}
+	if o.hardWraps {
+		rendererOptions = append(rendererOptions, html.WithHardWraps())
+	}
+	if o.xhtml {
+		rendererOptions = append(rendererOptions, html.WithXHTML())
+	}
+
+	return goldmark.New(
+		goldmark.WithParserOptions(parserOptions...),
+		goldmark.WithExtensions(o.extensions()...),
+		goldmark.WithRendererOptions(rendererOptions...),
+	)
+}
+
+// Markdown renderers by their options, shared by every parser as the options determine the renderer
+// A goldmark.Markdown is safe for concurrent use once it is built, so pages can be converted in parallel
+var (
+	markdownRenderersMu sync.Mutex
+	markdownRenderers   = make(map[markdownOptions]goldmark.Markdown)
+)
+
+// cachedMarkdown returns the markdown renderer of the options, which is built once and reused for every page with the same options
+func cachedMarkdown(options markdownOptions) goldmark.Markdown {
+	markdownRenderersMu.Lock()
+	defer markdownRenderersMu.Unlock()
+
+	md, ok := markdownRenderers[options]
+	if !ok {
+		md = options.newMarkdown()
+		markdownRenderers[options] = md
+	}
+	return md
+}
@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MenuEntry is a link of a named menu, listed in the menus config or added by the menu field of the frontmatter of a page
+type MenuEntry struct {
+	// Text of the link
+	Name string `json:"name"`
+	// Path relative to the root of the site as in the navbar config, such as docs.html, or the URL of another site
+	URL string `json:"url"`
+	// Entries are ordered by increasing weight, pages use the weight of their frontmatter
+	Weight int `json:"weight"`
+}
+
+// validateMenus returns the errors in the entries of the menus config
+func (l LayoutConfig) validateMenus() []string {
+	menus := make([]string, 0, len(l.Menus))
+	for menu := range l.Menus {
+		menus = append(menus, menu)
+	}
+	sort.Strings(menus)
+
+	var errs []string
+	for _, menu := range menus {
+		for i, entry := range l.Menus[menu] {
+			if strings.TrimSpace(entry.Name) == "" {
+				errs = append(errs, fmt.Sprintf("menus: entry %d of the %q menu is missing its name", i, menu))
+			}
+			if strings.TrimSpace(entry.URL) == "" {
+				errs = append(errs, fmt.Sprintf("menus: entry %d of the %q menu is missing its url", i, menu))
+			}
+		}
+	}
+	return errs
+}
@@ -2,62 +2,537 @@ package parser
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"io/fs"
 	"log"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/anna-ssg/anna/v3/pkg/helpers"
-	figure "github.com/mangoumbrella/goldmark-figure"
-	"github.com/yuin/goldmark"
-	"github.com/yuin/goldmark/extension"
-	"github.com/yuin/goldmark/parser"
-	"github.com/yuin/goldmark/renderer/html"
-	"go.abhg.dev/goldmark/anchor"
-	"go.abhg.dev/goldmark/mermaid"
-	"go.abhg.dev/goldmark/toc"
-	"gopkg.in/yaml.v3"
+	"github.com/microcosm-cc/bluemonday"
 )
 
 type LayoutConfig struct {
-	Navbar            []map[string]string `json:"navbar"`
-	BaseURL           string              `json:"baseURL"`
-	SiteTitle         string              `json:"siteTitle"`
-	SiteScripts       []string            `json:"siteScripts"`
-	Author            string              `json:"author"`
-	Copyright         string              `json:"copyright"`
-	ThemeURL          string              `json:"themeURL"`
-	Socials           map[string]string   `json:"socials"`
-	CollectionLayouts map[string]string   `json:"collectionLayouts"`
+	Navbar      []map[string]string `json:"navbar"`
+	BaseURL     string              `json:"baseURL"`
+	SiteTitle   string              `json:"siteTitle"`
+	SiteScripts []string            `json:"siteScripts"`
+	Author      string              `json:"author"`
+	Copyright   string              `json:"copyright"`
+	ThemeURL    string              `json:"themeURL"`
+	// Path prefixed to root-relative links and assets, such as "/repo", overriding the path of the base URL, "/" serves the site from the root
+	PathPrefix string `json:"basePath"`
+	// Theme installed to themes/<name>/ with anna theme install, whose layouts and static files are overridden by those of the site
+	Theme             string            `json:"theme"`
+	Socials           map[string]string `json:"socials"`
+	CollectionLayouts map[string]string `json:"collectionLayouts"`
+	// Named menus of links, merged with the pages that list the menu in their frontmatter
+	Menus map[string][]MenuEntry `json:"menus"`
+	// Collections defined by queries on the path, tags, type and date of pages, in addition to the collections of the frontmatter
+	Collections []CollectionQuery `json:"collections"`
+	// Canonical names of tags by their aliases, such as "javascript" for "js", matched case-insensitively
+	TagAliases map[string]string `json:"tagAliases"`
+	// Adds the version of anna and the build time to the <head> of every page as meta tags
+	BuildMeta bool `json:"buildMeta"`
+	// Maximum number of characters in the excerpts of pages, defaults to 200
+	ExcerptLength int `json:"excerptLength"`
+	// Preview image of pages without a previewimage or cover
+	DefaultPreviewImage string `json:"defaultPreviewImage"`
+	// Copies the markdown source of every rendered page next to its output
+	CopyMarkdown bool `json:"copyMarkdown"`
+	// Renders pages as <name>/index.html so that they are served at /<name>/
+	PrettyURLs bool `json:"prettyURLs"`
+	// Style of the URLs of pages, html, pretty or extensionless, which takes precedence over prettyURLs
+	URLStyle string `json:"urlStyle"`
+	// Rewrites internal links in rendered pages to match the configured URL style
+	NormalizeLinks bool `json:"normalizeLinks"`
+	// Adds target and rel attributes to links to other sites when set
+	ExternalLinks *ExternalLinksConfig `json:"externalLinks,omitempty"`
+	// Directory of the markdown content relative to the site directory, defaults to content
+	ContentDir string `json:"contentDir"`
+	// Directories of markdown content merged into the site, replacing contentDir when set
+	ContentDirs []ContentDirConfig `json:"contentDirs"`
+	// Glob patterns of directories left out of the build, matched against their path relative to the content directory
+	ExcludeDirs []string `json:"excludeDirs"`
+	// Directory of the markdown snippets included in pages, relative to the site directory, defaults to snippets/ in the content directory
+	SnippetsDir string `json:"snippetsDir"`
+	// Directory relative to the content directory whose pages default to the post type
+	PostsDir string `json:"postsDir"`
+	// Dates pages from the YYYY-MM-DD- prefix of their file name, which is left out of their URL
+	DateFromFilename bool `json:"dateFromFilename"`
+	// Layouts of pages by their type, used when the frontmatter of a page sets no layout
+	Layouts map[string]string `json:"layouts"`
+	// Maximum number of related notes listed on every page of type note, defaults to 5 and a negative value disables them
+	RelatedNotes int `json:"relatedNotes"`
+	// Order of posts in the posts index, feed and listings
+	PostSort PostSort `json:"postSort"`
+	// Generates a web app manifest when set
+	PWA *PWAConfig `json:"pwa,omitempty"`
+	// Generates favicons from a single source image
+	Favicon FaviconConfig `json:"favicon"`
+	// Options of the markdown renderer
+	Markdown MarkdownConfig `json:"markdown"`
+	// Heading of the list of footnotes at the end of pages, which has none when empty
+	FootnoteHeading string `json:"footnoteHeading"`
+	// Removes the list of footnotes from the body of pages, so that layouts place their Footnotes
+	SeparateFootnotes bool `json:"separateFootnotes"`
+	// Stylesheets of the static/ directory inlined into a <style> tag in the head of every page in place of their <link> tags
+	InlineCSS []string `json:"inlineCSS"`
+	// Stylesheet linked with media="print" in the head of every page, a path in the static/ directory or a URL
+	PrintCSS string `json:"printCSS"`
+	// Lowest and highest heading levels included in tables of contents, 0 includes every level
+	TOCMinDepth int `json:"tocMinDepth"`
+	TOCMaxDepth int `json:"tocMaxDepth"`
+	// Adds loading="lazy" and decoding="async" to the images of every page, defaults to true
+	LazyImages *bool `json:"lazyImages"`
+	// Loads the first image of every page eagerly, as it is often the largest element in the viewport
+	EagerFirstImage bool `json:"eagerFirstImage"`
+	// Adds the intrinsic width and height of local images to every page to prevent layout shift, defaults to true
+	ImageDimensions *bool `json:"imageDimensions"`
+	// Fields of the search index
+	JSONIndex JSONIndexConfig `json:"jsonIndex"`
+	// Generates a JSON API of the posts and tags in rendered/api/ for headless use
+	API bool `json:"api"`
+	// Generates tags.json listing every tag with its number of pages, for tag autocompletion
+	TagsJSON bool `json:"tagsJSON"`
+	// Injects the analytics script of the provider into production builds when set
+	Analytics *AnalyticsConfig `json:"analytics,omitempty"`
+	// Renders a reader-mode alternate of every post with the "reader" layout
+	ReaderMode bool `json:"readerMode"`
+	// Renders archive pages of posts grouped by year and month when set
+	Archive *ArchiveConfig `json:"archive,omitempty"`
+	// Options of the generated feeds
+	Feed FeedConfig `json:"feed"`
+	// Adds the iTunes podcast fields to the feed when set, so that posts with an enclosure are listed as episodes
+	Podcast *PodcastConfig `json:"podcast,omitempty"`
+	// Renders a human-readable sitemap.html listing the pages of the sitemap by section with the "sitemap" layout
+	HTMLSitemap bool `json:"htmlSitemap"`
+	// Renders a page listing the posts with the postsTemplate layout at postsPath
+	PostsListing bool `json:"postsListing"`
+	// Layout of the posts listing, defaults to posts
+	PostsTemplate string `json:"postsTemplate"`
+	// Path of the posts listing relative to the root of the site, such as index.html or blog/, defaults to posts.html
+	PostsPath string `json:"postsPath"`
+	// Taxonomies whose terms are read from fields of the frontmatter, besides tags and collections
+	Taxonomies []TaxonomyConfig `json:"taxonomies"`
+	// Warns about posts without a description and posts sharing their description
+	CheckDescriptions bool `json:"checkDescriptions"`
+	// Generates feeds.opml listing every feed of the site
+	OPML bool `json:"opml"`
+	// Embeds the comment system of the provider into posts when set
+	Comments *CommentsConfig `json:"comments,omitempty"`
+	// Generates humans.txt crediting the team of the site when set
+	Humans *HumansConfig `json:"humans,omitempty"`
+	// Generates .well-known/security.txt when set
+	Security *SecurityConfig `json:"security,omitempty"`
+}
+
+// CommentsConfig stores the comment provider (giscus, utterances or disqus) and its options
+type CommentsConfig struct {
+	Provider string `json:"provider"`
+	// GitHub repository of the discussions or issues for giscus and utterances, as owner/name
+	Repo       string `json:"repo"`
+	RepoID     string `json:"repoID"`
+	Category   string `json:"category"`
+	CategoryID string `json:"categoryID"`
+	// Mapping between posts and discussions or issues, defaults to pathname
+	Mapping string `json:"mapping"`
+	Theme   string `json:"theme"`
+	// Shortname of the site for disqus
+	Shortname string `json:"shortname"`
+}
+
+// ContentDirConfig stores a directory of markdown content relative to the site directory and the prefix of the URLs of its pages
+type ContentDirConfig struct {
+	Dir string `json:"dir"`
+	// Prefix of the URLs of the pages, such as docs for docs/setup.html, empty for the root of the site
+	URLPrefix string `json:"urlPrefix"`
+}
+
+// ExternalLinksConfig stores the attributes added to links to other sites
+type ExternalLinksConfig struct {
+	// Opens external links in a new tab with target="_blank" rel="noopener", defaults to true
+	NewTab *bool `json:"newTab"`
+	// Adds rel="nofollow" to external links
+	NoFollow bool `json:"noFollow"`
+}
+
+// NewTabEnabled reports whether external links are opened in a new tab
+func (c ExternalLinksConfig) NewTabEnabled() bool {
+	return c.NewTab == nil || *c.NewTab
+}
+
+// ArchiveConfig stores the options of the archive pages
+type ArchiveConfig struct {
+	// Lists posts without a date in archive/undated/, such posts are excluded by default
+	IncludeUndated bool `json:"includeUndated"`
+}
+
+// FeedConfig stores the options of the generated feeds
+type FeedConfig struct {
+	// URL of the WebSub hub declared in the feeds, no hub is declared when empty
+	Hub string `json:"hub"`
+}
+
+// PodcastConfig stores the fields of the podcast declared in the feed
+type PodcastConfig struct {
+	// Cover art of the podcast, a path to an image in static/ or an absolute URL
+	Image string `json:"image"`
+	// Apple Podcasts category, such as Technology
+	Category string `json:"category"`
+	// Marks the podcast as explicit, episodes can override it in their enclosure
+	Explicit bool `json:"explicit"`
+	// Contact address of the owner of the podcast
+	Email string `json:"email"`
+}
+
+// AnalyticsConfig stores the analytics provider (ga4, plausible or umami) and the identifier of the site
+type AnalyticsConfig struct {
+	Provider string `json:"provider"`
+	// Measurement ID for ga4, website ID for umami
+	SiteID string `json:"siteID"`
+	// Domain of the site for plausible, defaults to the host of the base URL
+	Domain string `json:"domain"`
+	// URL of the tracking script for self-hosted plausible and umami instances
+	ScriptURL string `json:"scriptURL"`
+}
+
+/*
+JSONIndexConfig stores the fields (title, url, tags, date, description, excerpt, body) included in the search index
+The complete frontmatter of every page is indexed when no fields are set
+*/
+type JSONIndexConfig struct {
+	Fields []string `json:"fields"`
+	// Maximum number of characters in the excerpt, defaults to 200
+	ExcerptLength int `json:"excerptLength"`
+}
+
+// MarkdownConfig stores the goldmark options, unset fields retain the defaults
+type MarkdownConfig struct {
+	// Renders raw HTML in markdown files, defaults to true
+	Unsafe *bool `json:"unsafe"`
+	// Renders newlines as <br>, defaults to false
+	HardWraps bool `json:"hardWraps"`
+	// Renders XHTML instead of HTML, defaults to false
+	XHTML bool `json:"xhtml"`
+	// Generates IDs for headings, defaults to true
+	AutoHeadingID *bool `json:"autoHeadingID"`
+	// Sanitizes the rendered HTML of every page, defaults to false
+	Sanitize bool `json:"sanitize"`
+	// Allowlist policy used to sanitize pages, "ugc" (default) or "strict"
+	SanitizePolicy string `json:"sanitizePolicy"`
+	// Elements and attributes allowed in addition to the sanitize policy
+	SanitizeAllowElements   []string `json:"sanitizeAllowElements"`
+	SanitizeAllowAttributes []string `json:"sanitizeAllowAttributes"`
+}
+
+// Policy returns the allowlist policy used to sanitize rendered pages
+func (m MarkdownConfig) Policy() *bluemonday.Policy {
+	var policy *bluemonday.Policy
+	switch m.SanitizePolicy {
+	case "strict":
+		policy = bluemonday.StrictPolicy()
+	default:
+		policy = bluemonday.UGCPolicy()
+	}
+
+	if len(m.SanitizeAllowElements) > 0 {
+		policy.AllowElements(m.SanitizeAllowElements...)
+	}
+	if len(m.SanitizeAllowAttributes) > 0 {
+		policy.AllowAttrs(m.SanitizeAllowAttributes...).Globally()
+	}
+
+	return policy
+}
+
+/*
+BasePath returns the path component of the base URL without the trailing slash, such as "/repo" for "https://user.github.io/repo/"
+The basePath config takes precedence over the base URL, so that assets and links can be prefixed independently of the canonical URLs
+Sites hosted at the root of a domain have an empty base path
+*/
+func (l LayoutConfig) BasePath() string {
+	if prefix := strings.TrimSpace(l.PathPrefix); prefix != "" {
+		prefix = strings.Trim(prefix, "/")
+		if prefix == "" {
+			return ""
+		}
+		return "/" + prefix
+	}
+
+	baseURL, err := url.Parse(l.BaseURL)
+	if err != nil || baseURL.Host == "" {
+		return ""
+	}
+	return strings.TrimSuffix(baseURL.Path, "/")
+}
+
+// URL styles of pages, set by the urlStyle config
+const (
+	// posts/file.html
+	URLStyleHTML = "html"
+	// posts/file/index.html served at posts/file/
+	URLStylePretty = "pretty"
+	// posts/file, for hosts serving files without an extension as HTML
+	URLStyleExtensionless = "extensionless"
+)
+
+// Validate returns the errors in the required fields of the config, the base URL and the title of the site
+func (l LayoutConfig) Validate() []string {
+	var errs []string
+	if l.BaseURL == "" {
+		errs = append(errs, "baseURL is not set")
+	} else if baseURL, err := url.Parse(l.BaseURL); err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		errs = append(errs, fmt.Sprintf("baseURL %q is not an absolute http or https URL", l.BaseURL))
+	}
+	if l.SiteTitle == "" {
+		errs = append(errs, "siteTitle is not set")
+	}
+	return errs
+}
+
+// PageURLStyle returns the URL style of pages, prettyURLs selects the pretty style when urlStyle is not set
+func (l LayoutConfig) PageURLStyle() string {
+	if l.URLStyle != "" {
+		return l.URLStyle
+	}
+	if l.PrettyURLs {
+		return URLStylePretty
+	}
+	return URLStyleHTML
+}
+
+// PostsListingLayout returns the layout of the posts listing
+func (l LayoutConfig) PostsListingLayout() string {
+	return cmp.Or(l.PostsTemplate, "posts")
+}
+
+// PostsListingKey returns the path the posts listing is rendered to, paths of directories are rendered to their index.html
+func (l LayoutConfig) PostsListingKey() template.URL {
+	listingPath := strings.TrimPrefix(cmp.Or(l.PostsPath, "posts.html"), "/")
+	if listingPath == "" || strings.HasSuffix(listingPath, "/") {
+		listingPath += "index.html"
+	}
+	return template.URL(listingPath)
+}
+
+// PostsListingURL returns the URL of the posts listing relative to the root of the site, blog/index.html is linked as blog/
+func (l LayoutConfig) PostsListingURL() template.URL {
+	key := l.PostsListingKey()
+	if key == "index.html" || strings.HasSuffix(string(key), "/index.html") {
+		return key[:len(key)-len("index.html")]
+	}
+	return key
+}
+
+// ContentPath returns the directory of the markdown content relative to the site directory, with a trailing slash
+func (l LayoutConfig) ContentPath() string {
+	return strings.Trim(cmp.Or(l.ContentDir, "content"), "/") + "/"
+}
+
+// DefaultPreviewImageURL returns the absolute URL of the defaultPreviewImage, which is empty when it is not set
+func (l LayoutConfig) DefaultPreviewImageURL() template.URL {
+	return l.absoluteURL(l.DefaultPreviewImage, ".")
+}
+
+// PodcastImageURL returns the absolute URL of the cover art of the podcast, which is empty when it is not set
+func (l LayoutConfig) PodcastImageURL() template.URL {
+	if l.Podcast == nil {
+		return ""
+	}
+	return l.absoluteURL(l.Podcast.Image, ".")
+}
+
+// absoluteURL resolves a link to an absolute URL using the base URL, paths without a leading slash are relative to dir
+func (l LayoutConfig) absoluteURL(link string, dir string) template.URL {
+	switch {
+	case link == "":
+		return ""
+	case strings.Contains(link, "://") || strings.HasPrefix(link, "//"):
+		return template.URL(link)
+	case strings.HasPrefix(link, "/"):
+		return template.URL(l.BaseURL + link)
+	}
+	return template.URL(l.BaseURL + "/" + path.Join(dir, link))
+}
+
+// UnsafeEnabled reports whether raw HTML is rendered
+func (m MarkdownConfig) UnsafeEnabled() bool {
+	return m.Unsafe == nil || *m.Unsafe
+}
+
+// AutoHeadingIDEnabled reports whether IDs are generated for headings
+func (m MarkdownConfig) AutoHeadingIDEnabled() bool {
+	return m.AutoHeadingID == nil || *m.AutoHeadingID
+}
+
+// FaviconConfig stores the source image (defaults to static/icon.png) and the sizes of the generated favicons
+type FaviconConfig struct {
+	Source             string `json:"source"`
+	Sizes              []int  `json:"sizes"`
+	AppleTouchIconSize int    `json:"appleTouchIconSize"`
+}
+
+// PWAConfig stores the fields of the web app manifest
+type PWAConfig struct {
+	Name            string    `json:"name"`
+	ShortName       string    `json:"shortName"`
+	Description     string    `json:"description"`
+	StartURL        string    `json:"startURL"`
+	Display         string    `json:"display"`
+	ThemeColor      string    `json:"themeColor"`
+	BackgroundColor string    `json:"backgroundColor"`
+	Icons           []PWAIcon `json:"icons"`
+	// Generates a service worker precaching the rendered site for offline use
+	ServiceWorker bool `json:"serviceWorker"`
+	// Maximum number of files precached by the service worker, 0 precaches all files
+	PrecacheLimit int `json:"precacheLimit"`
+}
+
+// PWAIcon stores an icon of the web app manifest, Src is the path to an image in static/
+type PWAIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+// PostSort stores the key (date, title or weight) and direction (asc or desc) used to sort posts
+type PostSort struct {
+	Key       string `json:"key"`
+	Direction string `json:"direction"`
 }
 
 type Frontmatter struct {
-	Title        string              `yaml:"title"`
-	Date         string              `yaml:"date"`
-	Draft        bool                `yaml:"draft"`
-	JSFiles      []string            `yaml:"scripts"`
-	Description  string              `yaml:"description"`
-	PreviewImage string              `yaml:"previewimage"`
-	Tags         []string            `yaml:"tags"`
-	TOC          bool                `yaml:"toc"`
-	Authors      []string            `yaml:"authors"`
-	Collections  []string            `yaml:"collections"`
-	Layout       string              `yaml:"layout"`
-	CustomFields []map[string]string `yaml:"customFields"`
+	Title         string              `yaml:"title"`
+	Date          string              `yaml:"date"`
+	Updated       string              `yaml:"updated"`
+	ExpiryDate    string              `yaml:"expiryDate"`
+	Draft         bool                `yaml:"draft"`
+	JSFiles       []string            `yaml:"scripts"`
+	Description   string              `yaml:"description"`
+	PreviewImage  string              `yaml:"previewimage"`
+	Cover         string              `yaml:"cover"`
+	Canonical     string              `yaml:"canonical"`
+	Tags          []string            `yaml:"tags"`
+	TOC           bool                `yaml:"toc"`
+	TOCMinDepth   int                 `yaml:"tocMinDepth"`
+	TOCMaxDepth   int                 `yaml:"tocMaxDepth"`
+	Authors       []string            `yaml:"authors"`
+	Collections   []string            `yaml:"collections"`
+	Menu          []string            `yaml:"menu"`
+	Layout        string              `yaml:"layout"`
+	Type          string              `yaml:"type"`
+	Weight        int                 `yaml:"weight"`
+	SearchExclude bool                `yaml:"searchExclude"`
+	Hidden        bool                `yaml:"hidden"`
+	Enclosure     *Enclosure          `yaml:"enclosure"`
+	Outputs       *CollectionOutputs  `yaml:"outputs"`
+	Comments      *bool               `yaml:"comments"`
+	OutputFormat  string              `yaml:"outputFormat"`
+	Password      string              `yaml:"password" json:"-"`
+	CustomFields  []map[string]string `yaml:"customFields"`
+	// Classes the layout adds to the <body> of the page, a space-separated string or a list
+	BodyClass ClassList `yaml:"bodyClass"`
+	// Fields of the frontmatter unknown to anna, such as the terms of the taxonomies config
+	Params map[string]any `yaml:",inline" json:",omitempty"`
+}
+
+// Enclosure stores the media file attached to a post, such as the audio of a podcast episode
+type Enclosure struct {
+	// Path to the file in static/ or an absolute URL
+	URL string `yaml:"url"`
+	// Size of the file in bytes
+	Length int64 `yaml:"length"`
+	// MIME type of the file, guessed from its extension when empty
+	Type string `yaml:"type"`
+	// Fields of the episode in podcast feeds, the duration is in seconds or of the form HH:MM:SS
+	Duration string `yaml:"duration"`
+	Episode  int    `yaml:"episode"`
+	Explicit *bool  `yaml:"explicit"`
+}
+
+// CollectionOutputs stores the files generated for a collection, set in the frontmatter of its metadata file
+type CollectionOutputs struct {
+	// Writes the RSS feed of the collection to collections/<name>/feed.xml
+	Feed bool `yaml:"feed"`
+	// Writes the search index of the collection to collections/<name>/index.json
+	JSONIndex bool `yaml:"jsonIndex"`
+	// Types of the pages included in the outputs, such as post, every page of the collection is included when empty
+	Types []string `yaml:"types"`
+}
+
+// IsHTML reports whether the page is rendered to an HTML file
+func (f Frontmatter) IsHTML() bool {
+	return f.OutputFormat == "" || f.OutputFormat == "html"
+}
+
+// LayoutName returns the name of the template used to render the page
+// Pages in another output format use the layout suffixed with the format, such as "post.txt"
+func (f Frontmatter) LayoutName() string {
+	if f.IsHTML() || strings.HasSuffix(f.Layout, "."+f.OutputFormat) {
+		return f.Layout
+	}
+	return f.Layout + "." + f.OutputFormat
 }
 
 // TemplateData This struct holds all of the data required to render any page of the site
 type TemplateData struct {
 	CompleteURL template.URL
 	Date        int64
+	// Date of the last modification, falls back to the published date or the modification time of the file
+	Updated     int64
 	Frontmatter Frontmatter
 	Body        template.HTML
 	LiveReload  bool
+	// Embed of the configured comment system, empty for pages that are not posts
+	CommentsHTML template.HTML
+	// URL of the reader-mode alternate of the page, empty when it has none
+	ReaderURL template.URL
+	// Pages of the section when the page is the index.html of a directory
+	Children []TemplateData
+	// Notes sharing links or tags with the page, empty for pages that are not of type note
+	RelatedNotes []TemplateData
+	// Absolute URL of the image used in link previews, from the previewimage, the cover or the site default
+	PreviewImageURL template.URL
+	// Absolute URL of the enclosure of the page, empty when it has none
+	EnclosureURL template.URL
+	// Canonical URL of the page, from the canonical frontmatter field or the URL of the page on the site
+	CanonicalURL template.URL
+	// Set when the body is encrypted with the password of the page, such pages are left out of feeds, the search index and the sitemap
+	Protected bool
+	// Summary of the page for listings, from its description or the beginning of its body
+	Excerpt string
+	// Estimated number of minutes needed to read the page
+	ReadingTime int
+	// Root-relative URL of the cover image of the page, empty when it has none
+	CoverURL template.URL
+	// Authors of the page, falling back to the author of the site
+	Authors []string
+	// Footnotes of the page, in the order they are referenced
+	Footnotes []Footnote
+	// Posts published right before and after the page, nil for the oldest and newest posts and for pages that are not posts
+	PrevPost *PostLink
+	NextPost *PostLink
+	// Space-separated classes of the bodyClass frontmatter field, for the class attribute of the <body> of the page
+	BodyClass string
+}
+
+// PostLink stores the title and URL of a post linked from another page
+type PostLink struct {
+	Title string
+	URL   template.URL
 }
 
 type Date int64
@@ -67,15 +542,28 @@ type Parser struct {
 	// Access the data for a particular page by using the relative path to the file as the key
 	Templates map[template.URL]TemplateData
 
-	// K-V pair storing all templates correspoding to a particular tag in the site
+	// Posts stores the template data of all pages of type post
+	Posts []TemplateData
+
+	// K-V pair storing all templates correspoding to a particular tag in the site, the terms of the tags taxonomy
 	TagsMap map[template.URL][]TemplateData
 
-	// Collections stores template data of files in collections
+	// Collections stores template data of files in collections, the terms of the collections taxonomy
 	CollectionsMap map[template.URL][]TemplateData
 
 	// K-V pair storing the template layout name for a particular collection in the site
 	CollectionsSubPageLayouts map[template.URL]string
 
+	// K-V pair storing the title, description, layout and body of a collection, parsed from content/collections/<name>.md
+	CollectionsMetadata map[template.URL]TemplateData
+
+	// Display names of tags and collections by the key of their page, such as "My Tag" for tags/my-tag.html
+	DisplayNames map[template.URL]string
+
+	// Pages of every term of every taxonomy by the name of the taxonomy and the key of the page of the term, such as categories/go.html
+	// The terms of the built-in tags and collections taxonomies are the TagsMap and CollectionsMap
+	TaxonomyMap map[string]map[template.URL][]TemplateData
+
 	// Stores data parsed from layout/config.yml
 	LayoutConfig LayoutConfig
 
@@ -85,11 +573,29 @@ type Parser struct {
 	// Stores flag value to render draft posts
 	RenderDrafts bool
 
+	// Renders the pages past their expiry date
+	RenderExpired bool
+
+	// Rejects unknown keys in config.json and checks that the required fields of the config are valid
+	StrictConfig bool
+
+	// Number of markdown files parsed concurrently, defaults to the number of CPUs
+	Jobs int
+
+	// Restricts the pages parsed to these types when set, used for partial builds
+	OnlyTypes []string
+
+	// Pages of these types are not parsed, used for partial builds
+	SkipTypes []string
+
 	// Common logger for all parser functions
 	ErrorLogger *log.Logger
 
 	Helper *helpers.Helper
 
+	// Collects warnings reported while parsing
+	Warnings *helpers.WarningCollector
+
 	// Determines the injection of Live Reload JS in HTML
 	LiveReload bool
 
@@ -97,37 +603,152 @@ type Parser struct {
 	SiteDataPath string
 }
 
+// ContentDirPath returns the path to the directory of the markdown content of the site, set by the contentDir config
+func (p *Parser) ContentDirPath() string {
+	return p.SiteDataPath + p.LayoutConfig.ContentPath()
+}
+
+/*
+ParseMDDir parses the markdown files of a content directory and copies its other files to rendered/
+
+The markdown files are listed first and parsed concurrently by a pool of Jobs workers, then added to the site
+in the order they were listed, so that the pages and their order do not depend on which file is parsed first
+*/
 func (p *Parser) ParseMDDir(baseDirPath string, baseDirFS fs.FS) {
 	helper := helpers.Helper{
 		ErrorLogger: p.ErrorLogger,
 	}
+
+	var mdFilePaths []string
 	err := fs.WalkDir(baseDirFS, ".", func(path string, dir fs.DirEntry, err error) error {
-		if path != "." && path != ".obsidian" {
-			if dir.IsDir() {
-				subDir := os.DirFS(path)
-				p.ParseMDDir(path, subDir)
-			} else {
-				fileName := strings.TrimPrefix(path, baseDirPath)
-				if filepath.Ext(path) == ".md" {
-					content, err := os.ReadFile(baseDirPath + path)
-					if err != nil {
-						p.ErrorLogger.Fatal(err)
-					}
-
-					frontmatter, body, markdownContent, parseSuccess := p.ParseMarkdownContent(string(content), path)
-					if parseSuccess && (p.RenderDrafts || !frontmatter.Draft) {
-						p.AddFile(baseDirPath, fileName, frontmatter, markdownContent, body)
-					}
-				} else {
-					helper.CopyFiles(p.SiteDataPath+"content/"+fileName, p.SiteDataPath+"rendered/"+fileName)
-				}
+		if err != nil {
+			// A missing content directory has no pages, which is reported by the doctor command
+			if path == "." && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
 			}
+			return err
+		}
+		if path != "." && dir.IsDir() && p.LayoutConfig.IsExcludedDir(path) {
+			return fs.SkipDir
+		}
+		// Snippets are only rendered in the pages including them
+		if dir.IsDir() && baseDirPath+path+"/" == p.SiteDataPath+p.LayoutConfig.SnippetsPath() {
+			return fs.SkipDir
+		}
+		if path == "." || path == ".obsidian" || dir.IsDir() {
+			return nil
+		}
+
+		if filepath.Ext(path) == ".md" {
+			mdFilePaths = append(mdFilePaths, path)
+		} else {
+			helper.CopyFiles(baseDirPath+path, p.SiteDataPath+"rendered/"+p.contentKey(baseDirPath+path))
 		}
 		return nil
 	})
 	if err != nil {
 		helper.ErrorLogger.Fatal(err)
 	}
+
+	for i, page := range p.parseMDFiles(baseDirPath, mdFilePaths) {
+		fileName := mdFilePaths[i]
+		if page.parseSuccess && p.isRenderable(page.frontmatter) && p.isIncludedType(page.frontmatter, baseDirPath+fileName) {
+			p.reportAccessibilityIssues(baseDirPath+fileName, page.body)
+			if strings.HasPrefix(fileName, "collections/") {
+				p.AddCollectionMetadata(fileName, page.frontmatter, page.body)
+			} else {
+				p.AddFile(baseDirPath, fileName, page.frontmatter, page.markdownContent, page.body)
+			}
+		}
+	}
+}
+
+// reportAccessibilityIssues warns about the images without alt text and the links without discernible text in the body of a page
+func (p *Parser) reportAccessibilityIssues(filePath string, body string) {
+	issues, err := helpers.AccessibilityIssues(body)
+	if err != nil {
+		p.ErrorLogger.Fatal(err)
+	}
+	for _, issue := range issues {
+		p.Warnings.Warnf("%s: %s (%s)", filePath, issue.Message, issue.Rule)
+	}
+}
+
+// parsedMDFile stores the results of ParseMarkdownContent for a markdown file
+type parsedMDFile struct {
+	frontmatter     Frontmatter
+	body            string
+	markdownContent string
+	parseSuccess    bool
+}
+
+// parseMDFiles reads and parses markdown files of a content directory concurrently, returning the results in the order of the paths
+func (p *Parser) parseMDFiles(baseDirPath string, filePaths []string) []parsedMDFile {
+	jobs := p.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	pages := make([]parsedMDFile, len(filePaths))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(filePaths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				content, err := os.ReadFile(baseDirPath + filePaths[i])
+				if err != nil {
+					p.ErrorLogger.Fatal(err)
+				}
+
+				page := &pages[i]
+				page.frontmatter, page.body, page.markdownContent, page.parseSuccess = p.ParseMarkdownContent(string(content), filePaths[i])
+			}
+		}()
+	}
+
+	for i := range filePaths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return pages
+}
+
+// isRenderable reports whether a page should be rendered, drafts and expired pages of all types are rendered only with their flags
+func (p *Parser) isRenderable(frontmatter Frontmatter) bool {
+	return (p.RenderDrafts || !frontmatter.Draft) && (p.RenderExpired || !p.isExpired(frontmatter))
+}
+
+// isExpired reports whether the build time has reached the expiry date of a page, pages without an expiry date never expire
+func (p *Parser) isExpired(frontmatter Frontmatter) bool {
+	if frontmatter.ExpiryDate == "" {
+		return false
+	}
+
+	buildTime, err := helpers.BuildTime()
+	if err != nil {
+		p.ErrorLogger.Fatal("Invalid SOURCE_DATE_EPOCH: ", err)
+	}
+	return !buildTime.Before(p.DateParse(frontmatter.ExpiryDate))
+}
+
+// isIncludedType reports whether the type of a page is included in a partial build, every type is included by default
+func (p *Parser) isIncludedType(frontmatter Frontmatter, filePath string) bool {
+	if len(p.OnlyTypes) == 0 && len(p.SkipTypes) == 0 {
+		return true
+	}
+
+	pageType := frontmatter.Type
+	if pageType == "" {
+		pageType = p.defaultType(p.contentKey(filePath))
+	}
+
+	if len(p.OnlyTypes) > 0 && !slices.Contains(p.OnlyTypes, pageType) {
+		return false
+	}
+	return !slices.Contains(p.SkipTypes, pageType)
 }
 
 func (p *Parser) AddFile(baseDirPath string, dirEntryPath string, frontmatter Frontmatter, markdownContent string, body string) {
@@ -135,6 +756,18 @@ func (p *Parser) AddFile(baseDirPath string, dirEntryPath string, frontmatter Fr
 	testFilepath := baseDirPath + dirEntryPath
 	p.MdFilesPath = append(p.MdFilesPath, testFilepath)
 
+	key := p.contentKey(testFilepath)
+
+	// The date prefix of files such as 2024-01-02-my-post.md is left out of their URL, and dates them unless the frontmatter does
+	if p.LayoutConfig.DateFromFilename {
+		if fileDate, datelessKey, ok := filenameDate(key); ok {
+			if frontmatter.Date == "" {
+				frontmatter.Date = fileDate
+			}
+			key = datelessKey
+		}
+	}
+
 	var date int64
 	if frontmatter.Date != "" {
 		date = p.DateParse(frontmatter.Date).Unix()
@@ -142,124 +775,311 @@ func (p *Parser) AddFile(baseDirPath string, dirEntryPath string, frontmatter Fr
 		date = 0
 	}
 
-	key, _ := strings.CutPrefix(testFilepath, p.SiteDataPath+"content/")
-	url, _ := strings.CutSuffix(key, ".md")
-	url += ".html"
+	if frontmatter.OutputFormat == "" {
+		frontmatter.OutputFormat = "html"
+	}
+	frontmatter.Tags = p.normalizeTags(frontmatter.Tags)
+	url, completeURL := p.pageURLs(key, frontmatter.OutputFormat)
+
+	// An explicit type in the frontmatter overrides the type of the directory
+	if frontmatter.Type == "" {
+		frontmatter.Type = p.defaultType(key)
+	}
+
+	frontmatter.Collections = append(frontmatter.Collections, p.queriedCollections(p.contentKey(testFilepath), frontmatter)...)
+
+	// The layout of the frontmatter takes precedence over the layout of the type
+	if frontmatter.Layout == "" {
+		frontmatter.Layout = p.LayoutConfig.Layouts[frontmatter.Type]
+	}
+	if frontmatter.Layout == "" {
+		frontmatter.Layout = "page"
+	}
+
+	// The footnotes of protected pages are left in their encrypted body
+	var footnotes []Footnote
+	protected := frontmatter.Password != ""
+	if !protected {
+		body, footnotes = p.footnotes(body)
+	}
+
+	// The password is cleared so that it is never available to layouts
+	if protected {
+		// Notes are transcluded once every page is parsed, after the body of a protected page is encrypted
+		if transclusionRegex.MatchString(body) {
+			p.Warnings.Warnf("%s: ![[...]] is not transcluded in a protected page, as its body is encrypted first", testFilepath)
+		}
+		body = string(p.protectBody(body, frontmatter.Password, testFilepath, url))
+		frontmatter.Password = ""
+	}
 
 	page := TemplateData{
-		CompleteURL: template.URL(url),
+		CompleteURL: completeURL,
 		Date:        date,
+		Updated:     p.updatedDate(frontmatter, testFilepath, date),
 		Frontmatter: frontmatter,
 		Body:        template.HTML(body),
 		LiveReload:  p.LiveReload,
+		Protected:   protected,
+		Footnotes:   footnotes,
+	}
+	page.CommentsHTML = p.commentsHTML(page)
+	page.PreviewImageURL = p.previewImageURL(frontmatter, key)
+	page.CanonicalURL = p.canonicalURL(frontmatter, key, completeURL)
+	page.BodyClass = p.bodyClass(frontmatter.BodyClass, testFilepath)
+	if frontmatter.Enclosure != nil {
+		page.EnclosureURL = p.LayoutConfig.absoluteURL(frontmatter.Enclosure.URL, path.Dir(key))
+		if page.EnclosureURL == "" {
+			p.Warnings.Warn("Enclosure is missing a url, it will be left out of the feed: ", testFilepath)
+		}
+	}
+	p.setListingFields(&page, key)
+	if p.LayoutConfig.ReaderMode && page.Frontmatter.Type == "post" && page.Frontmatter.IsHTML() {
+		page.ReaderURL = p.readerURL(completeURL)
 	}
 
-	p.Templates[template.URL(url)] = page
+	// Files of different content directories can be rendered to the same page, the last one parsed is used
+	if _, ok := p.Templates[url]; ok {
+		p.Warnings.Warnf("%s is rendered to %s, replacing the page of another content file", testFilepath, url)
+	}
+	p.Templates[url] = page
 
-	// Adding the page to the tags map with the corresponding tags
-	for _, tag := range page.Frontmatter.Tags {
-		tagsMapKey := "tags/" + tag + ".html"
-		p.TagsMap[template.URL(tagsMapKey)] = append(p.TagsMap[template.URL(tagsMapKey)], page)
+	if p.LayoutConfig.CopyMarkdown && !protected {
+		p.copyMarkdown(testFilepath, url)
+	}
+
+	// Hidden pages are rendered but left out of the posts, tags and collections listings
+	if page.Frontmatter.Hidden {
+		return
+	}
 
+	if page.Frontmatter.Type == "post" {
+		if page.Frontmatter.Date == "" {
+			p.Warnings.Warn("Post is missing a date, it will be sorted as the oldest post: ", testFilepath)
+		}
+		p.Posts = append(p.Posts, page)
 	}
 
-	p.collectionsParser(page)
+	// Adding the page to its tags, collections and the terms of the taxonomies of the config
+	p.taxonomiesParser(page)
 }
 
-func (p *Parser) ParseMarkdownContent(filecontent string, path string) (Frontmatter, string, string, bool) {
-	var parsedFrontmatter Frontmatter
-	var markdown string
-	/*
-	   ---
-	   frontmatter_content
-	   ---
+// previewImageURL returns the absolute URL of the preview image of a page, falling back to its cover and the default of the site
+// Paths without a leading slash are relative to the directory of the markdown file
+func (p *Parser) previewImageURL(frontmatter Frontmatter, key string) template.URL {
+	if image := cmp.Or(frontmatter.PreviewImage, frontmatter.Cover); image != "" {
+		return p.LayoutConfig.absoluteURL(image, path.Dir(key))
+	}
+	return p.LayoutConfig.DefaultPreviewImageURL()
+}
 
-	   markdown content
-	   --- => markdown divider and not to be touched while yaml parsing
-	*/
-	splitContents := strings.Split(filecontent, "---")
-	frontmatterSplit := ""
+// defaultExcerptLength is the maximum number of characters in excerpts when the excerptLength config is not set
+const defaultExcerptLength = 200
 
-	if len(splitContents) <= 1 {
-		p.ErrorLogger.Fatal("Frontmatter missing on path: ", path)
+// setListingFields computes the excerpt, reading time, cover and authors of a page once, for the cards of listings
+// The body of protected pages is encrypted, so only their description is used
+func (p *Parser) setListingFields(page *TemplateData, key string) {
+	page.Excerpt = page.Frontmatter.Description
+	if !page.Protected {
+		if page.Excerpt == "" {
+			page.Excerpt = helpers.Excerpt(string(page.Body), cmp.Or(p.LayoutConfig.ExcerptLength, defaultExcerptLength))
+		}
+		page.ReadingTime = helpers.ReadingTime(string(page.Body))
 	}
 
-	// If the first section of the page contains a title field, continue parsing
-	// Else, prevent parsing of the current file
-	regex := regexp.MustCompile(`title(.*): (.*)`)
-	match := regex.FindStringSubmatch(splitContents[1])
+	switch cover := page.Frontmatter.Cover; {
+	case cover == "":
+	case strings.Contains(cover, "://") || strings.HasPrefix(cover, "/"):
+		page.CoverURL = template.URL(cover)
+	default:
+		page.CoverURL = template.URL("/" + path.Join(path.Dir(key), cover))
+	}
+
+	page.Authors = page.Frontmatter.Authors
+	if len(page.Authors) == 0 && p.LayoutConfig.Author != "" {
+		page.Authors = []string{p.LayoutConfig.Author}
+	}
+}
+
+// canonicalURL returns the canonical URL of a page, which is set in the frontmatter of pages republished from other sites
+func (p *Parser) canonicalURL(frontmatter Frontmatter, key string, completeURL template.URL) template.URL {
+	if frontmatter.Canonical != "" {
+		return p.LayoutConfig.absoluteURL(frontmatter.Canonical, path.Dir(key))
+	}
+	return template.URL(p.LayoutConfig.BaseURL + "/" + string(completeURL))
+}
 
-	if match == nil {
-		p.ErrorLogger.Fatal("Title field missing from frontmatter missing on path: ", path)
+// copyMarkdown copies the markdown source of a page to the output directory, replacing the extension of its output file
+func (p *Parser) copyMarkdown(srcPath string, url template.URL) {
+	helper := helpers.Helper{
+		ErrorLogger: p.ErrorLogger,
 	}
 
-	frontmatterSplit = splitContents[1]
-	// Parsing YAML frontmatter
-	err := yaml.Unmarshal([]byte(frontmatterSplit), &parsedFrontmatter)
+	destPath := strings.TrimSuffix(string(url), filepath.Ext(string(url))) + ".md"
+	helper.CopyFiles(srcPath, p.SiteDataPath+"rendered/"+destPath)
+}
+
+// updatedDate returns the date a page was last modified, warning if it is before the published date
+func (p *Parser) updatedDate(frontmatter Frontmatter, filePath string, date int64) int64 {
+	if frontmatter.Updated != "" {
+		updated := p.DateParse(frontmatter.Updated).Unix()
+		if date != 0 && updated < date {
+			p.Warnings.Warn("Updated date is before the published date: ", filePath)
+		}
+		return updated
+	}
+
+	if date != 0 {
+		return date
+	}
+
+	info, err := os.Stat(filePath)
 	if err != nil {
-		p.ErrorLogger.Println("Error at path: ", path)
-		p.ErrorLogger.Fatal(err)
+		return 0
 	}
 
-	if parsedFrontmatter.Layout == "" {
-		parsedFrontmatter.Layout = "page"
+	// Modification times after SOURCE_DATE_EPOCH are clamped to it, as checkouts set them to the time of the checkout
+	epoch, ok, err := helpers.SourceDateEpoch()
+	if err != nil {
+		p.ErrorLogger.Fatal("Invalid SOURCE_DATE_EPOCH: ", err)
+	}
+	if ok && info.ModTime().After(epoch) {
+		return epoch.Unix()
 	}
+	return info.ModTime().Unix()
+}
 
-	markdown = strings.Join(strings.Split(filecontent, "---")[2:], "---")
+// defaultType returns the type of a page whose frontmatter does not specify one
+func (p *Parser) defaultType(key string) string {
+	postsDir := strings.Trim(p.LayoutConfig.PostsDir, "/")
+	if postsDir != "" && strings.HasPrefix(key, postsDir+"/") {
+		return "post"
+	}
+	return "page"
+}
 
-	// Parsing markdown to HTML
-	var parsedMarkdown bytes.Buffer
-	var md goldmark.Markdown
-
-	if parsedFrontmatter.TOC {
-		md = goldmark.New(
-			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
-			goldmark.WithExtensions(
-				extension.TaskList,
-				figure.Figure,
-				&toc.Extender{
-					Compact: true,
-				},
-				&mermaid.Extender{
-					RenderMode: mermaid.RenderModeClient, // or RenderModeClient
-				},
-				&anchor.Extender{
-					Texter: anchor.Text("#"),
-				},
-			),
-			goldmark.WithRendererOptions(
-				html.WithUnsafe(),
-			),
-		)
-	} else {
-		md = goldmark.New(
-			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
-			goldmark.WithExtensions(
-				extension.TaskList,
-				figure.Figure,
-				&mermaid.Extender{
-					RenderMode: mermaid.RenderModeClient, // or RenderModeClient
-				},
-			),
-			goldmark.WithRendererOptions(
-				html.WithUnsafe(),
-			),
-		)
+/*
+readerURL computes the URL of the reader-mode alternate of a page from its URL
+
+	posts/file.html -> posts/file/reader.html
+	posts/file/     -> posts/file/reader/ (pretty URLs)
+	posts/file      -> posts/file-reader (extensionless URLs, as posts/file is a file)
+*/
+func (p *Parser) readerURL(completeURL template.URL) template.URL {
+	base, _ := strings.CutSuffix(strings.TrimSuffix(string(completeURL), "/"), ".html")
+	style := p.LayoutConfig.PageURLStyle()
+	if style == URLStyleExtensionless && base != "" && !strings.HasSuffix(string(completeURL), "/") {
+		return template.URL(base + "-reader")
+	}
+
+	if base != "" {
+		base += "/"
+	}
+
+	switch style {
+	case URLStylePretty:
+		return template.URL(base + "reader/")
+	case URLStyleExtensionless:
+		return template.URL(base + "reader")
+	}
+	return template.URL(base + "reader.html")
+}
+
+/*
+pageURLs computes the output path and the URL of a page from its path relative to content/
+The output path is used as the key in the Templates map, while the URL is used to link to the page
+
+	posts/file.md  -> posts/file.html, posts/file.html
+	posts/file.md  -> posts/file/index.html, posts/file/ (pretty URLs)
+	posts/index.md -> posts/index.html, posts/ (pretty URLs)
+	posts/file.md  -> posts/file, posts/file (extensionless URLs)
+	posts/file.md  -> posts/file.txt, posts/file.txt (txt output format)
+*/
+func (p *Parser) pageURLs(key string, outputFormat string) (template.URL, template.URL) {
+	url, _ := strings.CutSuffix(key, ".md")
+
+	// Pretty URLs only apply to HTML pages, which are served as the index of a directory
+	if outputFormat != "html" {
+		return template.URL(url + "." + outputFormat), template.URL(url + "." + outputFormat)
+	}
+
+	// index.md is the root page of its section and is served at the directory
+	if url == "index" || strings.HasSuffix(url, "/index") {
+		dirURL, _ := strings.CutSuffix(url, "index")
+		return template.URL(url + ".html"), template.URL(dirURL)
 	}
 
+	switch p.LayoutConfig.PageURLStyle() {
+	case URLStylePretty:
+		return template.URL(url + "/index.html"), template.URL(url + "/")
+	case URLStyleExtensionless:
+		return template.URL(url), template.URL(url)
+	}
+	return template.URL(url + ".html"), template.URL(url + ".html")
+}
+
+var filenameDateRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-(.+)$`)
+
+// filenameDate returns the date in the name of a markdown file, such as 2024-01-02 for posts/2024-01-02-my-post.md, and the key without it
+// File names without a valid date followed by a name are not dated
+func filenameDate(key string) (string, string, bool) {
+	dir, name := path.Split(key)
+	match := filenameDateRegex.FindStringSubmatch(name)
+	if match == nil || match[2] == ".md" {
+		return "", "", false
+	}
+	if _, err := time.Parse("2006-01-02", match[1]); err != nil {
+		return "", "", false
+	}
+	return match[1], dir + match[2], true
+}
+
+func (p *Parser) ParseMarkdownContent(filecontent string, path string) (Frontmatter, string, string, bool) {
+	parsedFrontmatter, markdown, errs := ParseFrontmatter(filecontent, path)
+	if len(errs) > 0 {
+		for _, err := range errs[:len(errs)-1] {
+			p.ErrorLogger.Println(err)
+		}
+		p.ErrorLogger.Fatal(errs[len(errs)-1])
+	}
+
+	markdown, err := p.IncludeSnippets(markdown)
+	if err != nil {
+		p.ErrorLogger.Fatalf("%s: %v", path, err)
+	}
+
+	// Parsing markdown to HTML
+	var parsedMarkdown bytes.Buffer
+	md := cachedMarkdown(p.markdownOptions(parsedFrontmatter, markdown))
 	if err := md.Convert([]byte(markdown), &parsedMarkdown); err != nil {
 		p.ErrorLogger.Fatal(err)
 	}
 
-	return parsedFrontmatter, parsedMarkdown.String(), markdown, true
+	body := parsedMarkdown.String()
+	if markdownConfig := p.LayoutConfig.Markdown; markdownConfig.Sanitize {
+		body = markdownConfig.Policy().Sanitize(body)
+	}
+	if p.LayoutConfig.LazyImagesEnabled() {
+		body = lazyLoadImages(body, p.LayoutConfig.EagerFirstImage)
+	}
+
+	return parsedFrontmatter, body, markdown, true
 }
 
+// Layouts of the dates of the frontmatter, dates without a time zone are in UTC
+var dateLayouts = []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"}
+
+// DateParse parses a date of the frontmatter, either a day such as 2024-01-02 or a time such as 2024-01-02T15:04:05+05:30
 func (p *Parser) DateParse(date string) time.Time {
-	parsedTime, err := time.Parse("2006-01-02", date)
-	if err != nil {
-		p.ErrorLogger.Fatal(err)
+	var err error
+	for _, layout := range dateLayouts {
+		var parsedTime time.Time
+		if parsedTime, err = time.Parse(layout, date); err == nil {
+			return parsedTime
+		}
 	}
-	return parsedTime
+	p.ErrorLogger.Fatalf("invalid date %q, expected YYYY-MM-DD or YYYY-MM-DDTHH:MM:SS with an optional time zone", date)
+	return time.Time{}
 }
 
 func (p *Parser) ParseConfig(inFilePath string) {
@@ -276,113 +1096,193 @@ func (p *Parser) ParseConfig(inFilePath string) {
 		p.ErrorLogger.Fatal(err)
 	}
 
-	err = json.Unmarshal(configFile, &p.LayoutConfig)
+	// Misspelled keys are ignored unless the config is parsed strictly
+	decoder := json.NewDecoder(bytes.NewReader(configFile))
+	if p.StrictConfig {
+		decoder.DisallowUnknownFields()
+	}
+	err = decoder.Decode(&p.LayoutConfig)
 	if err != nil {
 		p.ErrorLogger.Println("Error at: ", inFilePath)
 		p.ErrorLogger.Fatal(err)
 	}
 
-	p.parseCollectionLayoutEntries()
-}
+	p.ApplyEnvOverrides()
+	p.LayoutConfig.BaseURL = strings.TrimSuffix(p.LayoutConfig.BaseURL, "/")
 
-func (p *Parser) ParseRobots(inFilePath string, outFilePath string) {
-	tmpl, err := template.ParseFiles(inFilePath)
-	if err != nil {
-		p.ErrorLogger.Fatal(err)
+	if p.StrictConfig {
+		configErrors := p.LayoutConfig.Validate()
+		for _, configErr := range configErrors {
+			p.ErrorLogger.Printf("%s: %s", inFilePath, configErr)
+		}
+		if len(configErrors) > 0 {
+			p.ErrorLogger.Fatalf("%s: %d error(s) in the config", inFilePath, len(configErrors))
+		}
 	}
 
-	var buffer bytes.Buffer
-	err = tmpl.Execute(&buffer, p.LayoutConfig)
+	// The expiry of security.txt is checked at the build time, so that a build with SOURCE_DATE_EPOCH is reproducible
+	buildTime, err := helpers.BuildTime()
 	if err != nil {
-		p.ErrorLogger.Fatal(err)
+		p.ErrorLogger.Fatal("Invalid SOURCE_DATE_EPOCH: ", err)
 	}
 
-	outputFile, err := os.Create(outFilePath)
-	if err != nil {
-		p.ErrorLogger.Fatal(err)
+	var entryErrors []string
+	for _, query := range p.LayoutConfig.Collections {
+		entryErrors = append(entryErrors, query.validate()...)
 	}
-	defer func() {
-		err = outputFile.Close()
-		if err != nil {
-			p.ErrorLogger.Fatal(err)
+	entryErrors = append(entryErrors, p.LayoutConfig.validateMenus()...)
+	entryErrors = append(entryErrors, p.LayoutConfig.validateTaxonomies()...)
+	entryErrors = append(entryErrors, p.LayoutConfig.validateTheme()...)
+	entryErrors = append(entryErrors, p.LayoutConfig.validateTextFiles(buildTime)...)
+	for _, entryErr := range entryErrors {
+		p.ErrorLogger.Printf("%s: %s", inFilePath, entryErr)
+	}
+	if len(entryErrors) > 0 {
+		p.ErrorLogger.Fatalf("%s: %d error(s) in the collections, menus, taxonomies, theme, humans and security config", inFilePath, len(entryErrors))
+	}
+
+	if themePath := p.LayoutConfig.ThemePath(); themePath != "" {
+		if _, err := os.Stat(p.SiteDataPath + themePath); err != nil {
+			p.ErrorLogger.Fatalf("%s: theme %q is not installed in %s, install it with anna theme install", inFilePath, p.LayoutConfig.Theme, themePath)
 		}
-	}()
+	}
 
-	_, err = outputFile.Write(buffer.Bytes())
-	if err != nil {
+	for _, pattern := range p.LayoutConfig.ExcludeDirs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			p.ErrorLogger.Fatalf("%s: invalid excludeDirs pattern %q: %v", inFilePath, pattern, err)
+		}
+	}
+
+	switch p.LayoutConfig.PageURLStyle() {
+	case URLStyleHTML, URLStylePretty, URLStyleExtensionless:
+	default:
+		p.ErrorLogger.Fatalf("%s: unknown urlStyle %q, expected html, pretty or extensionless", inFilePath, p.LayoutConfig.URLStyle)
+	}
+	p.parseCollectionLayoutEntries()
+}
+
+// envOverride stores the key of the field of config.json an environment variable overrides, and how it is set
+type envOverride struct {
+	key   string
+	apply func(*LayoutConfig, string)
+}
+
+// Environment variables which override the corresponding fields of config.json
+var envOverrides = map[string]envOverride{
+	"ANNA_BASE_URL":   {"baseURL", func(c *LayoutConfig, v string) { c.BaseURL = v }},
+	"ANNA_BASE_PATH":  {"basePath", func(c *LayoutConfig, v string) { c.PathPrefix = v }},
+	"ANNA_SITE_TITLE": {"siteTitle", func(c *LayoutConfig, v string) { c.SiteTitle = v }},
+	"ANNA_AUTHOR":     {"author", func(c *LayoutConfig, v string) { c.Author = v }},
+	"ANNA_COPYRIGHT":  {"copyright", func(c *LayoutConfig, v string) { c.Copyright = v }},
+	"ANNA_THEME_URL":  {"themeURL", func(c *LayoutConfig, v string) { c.ThemeURL = v }},
+}
+
+// ApplyEnvOverrides overrides the layout config with the values of the set ANNA_* environment variables
+func (p *Parser) ApplyEnvOverrides() {
+	for envVar, override := range envOverrides {
+		if value, ok := os.LookupEnv(envVar); ok {
+			override.apply(&p.LayoutConfig, value)
+		}
+	}
+}
+
+// ParseRobots renders the robots.txt template with the site config
+func (p *Parser) ParseRobots(inFilePath string, outFilePath string) {
+	if err := ExecuteTemplateFile(inFilePath, outFilePath, p.LayoutConfig); err != nil {
 		p.ErrorLogger.Fatal(err)
 	}
 }
 
 // ParseLayoutFiles Parse all the ".html" layout files in the layout/ directory
 func (p *Parser) ParseLayoutFiles() *template.Template {
+	templ, err := p.LayoutTemplates()
+	if err != nil {
+		p.ErrorLogger.Fatal(err)
+	}
+	return templ
+}
 
-	// Function to check if an element is present in a slice
-	templ := template.New("templates").Funcs(template.FuncMap{
-		"strSliceContains": func(items []string, search string) bool {
-			for _, item := range items {
-				if search == item {
-					return true
-				}
-			}
-			return false
-		},
-	})
+// LayoutTemplates parses the layouts in layout/ and the partials in layout/partials/ and returns the errors found
+func (p *Parser) LayoutTemplates() (*template.Template, error) {
+	templ := template.New("templates").Funcs(templateFuncs())
+	if p.LayoutConfig.Theme != "" {
+		return p.parseThemeLayouts(templ)
+	}
 
 	// Parsing all files in the layout/ dir hich match the "*.html" pattern
 	templ, err := templ.ParseGlob(p.SiteDataPath + "layout/*.html")
 	if err != nil {
-		p.ErrorLogger.Fatal(err)
+		return nil, err
 	}
 
 	// Parsing all files in the partials/ dir which match the "*.html" pattern
-	templ, err = templ.ParseGlob(p.SiteDataPath + "layout/partials/*.html")
-	if err != nil {
-		p.ErrorLogger.Fatal(err)
+	return templ.ParseGlob(p.SiteDataPath + "layout/partials/*.html")
+}
+
+/*
+AddCollectionMetadata stores the frontmatter and body of content/collections/<name>.md as the metadata of the collection
+Nested collections are described by nested files, content/collections/posts/tech.md describes "posts>tech"
+*/
+func (p *Parser) AddCollectionMetadata(dirEntryPath string, frontmatter Frontmatter, body string) {
+	if p.CollectionsMetadata == nil {
+		p.CollectionsMetadata = make(map[template.URL]TemplateData)
 	}
 
-	return templ
+	collectionKey := slugifyCollectionKey(dirEntryPath)
+
+	p.CollectionsMetadata[collectionKey] = TemplateData{
+		CompleteURL: collectionKey,
+		Frontmatter: frontmatter,
+		Body:        template.HTML(body),
+		LiveReload:  p.LiveReload,
+	}
 }
 
-// Adding the page to the collections map with the corresponding collections and sub-collections
-func (p *Parser) collectionsParser(page TemplateData) {
-	// Iterating over all sets of collections defined in the frontmatter
-	for _, collectionSet := range page.Frontmatter.Collections {
+func (p *Parser) parseCollectionLayoutEntries() {
+	for collectionURL, layoutName := range p.LayoutConfig.CollectionLayouts {
+		p.CollectionsSubPageLayouts[slugifyCollectionKey(collectionURL)] = layoutName
+	}
+}
 
-		var collections []string
-		// Collections will be nested using > as the separator - "posts>tech>Go"
-		for _, item := range strings.Split(collectionSet, ">") {
-			collections = append(collections, strings.TrimSpace(item))
+/*
+termKey returns the key of the page of a tag or nested collection, such as tags/my-tag.html for the tag "My Tag"
+The names are slugified for the URL and the display name is stored in DisplayNames
+Different names with the same slug share a page, which is reported unless the names only differ in case
+*/
+func (p *Parser) termKey(prefix string, names []string) (template.URL, bool) {
+	slugs := make([]string, len(names))
+	for i, name := range names {
+		slugs[i] = helpers.Slugify(name)
+		if slugs[i] == "" {
+			p.Warnings.Warnf("%q has no characters usable in a URL, skipping it", name)
+			return "", false
 		}
+	}
 
-		for i := range len(collections) {
-			collectionKey := "collections/"
-			for j := range i + 1 {
-				collectionKey += collections[j]
-				if j != i {
-					collectionKey += "/"
-				}
+	key := template.URL(prefix + strings.Join(slugs, "/") + ".html")
+	displayName := strings.Join(names, "/")
 
-			}
-			collectionKey += ".html"
+	if p.DisplayNames == nil {
+		p.DisplayNames = make(map[template.URL]string)
+	}
+	if existing, ok := p.DisplayNames[key]; !ok {
+		p.DisplayNames[key] = displayName
+	} else if !strings.EqualFold(existing, displayName) {
+		p.Warnings.Warnf("%q and %q have the same URL %s, their pages are merged", existing, displayName, key)
+	}
 
-			var found bool
-			for _, map_page := range p.CollectionsMap[template.URL(collectionKey)] {
-				if map_page.CompleteURL == page.CompleteURL {
-					found = true
-				}
-			}
-			if !found {
-				p.CollectionsMap[template.URL(collectionKey)] = append(p.CollectionsMap[template.URL(collectionKey)], page)
-			}
+	return key, true
+}
 
-		}
+// slugifyCollectionKey slugifies the names in the path of a collection, such as collections/Posts/Tech.md to collections/posts/tech.html
+func slugifyCollectionKey(collectionPath string) template.URL {
+	collectionPath = strings.TrimSuffix(collectionPath, filepath.Ext(collectionPath))
+	collectionPath, _ = strings.CutPrefix(collectionPath, "collections/")
 
+	names := strings.Split(collectionPath, "/")
+	for i, name := range names {
+		names[i] = helpers.Slugify(name)
 	}
-}
 
-func (p *Parser) parseCollectionLayoutEntries() {
-	for collectionURL, layoutName := range p.LayoutConfig.CollectionLayouts {
-		p.CollectionsSubPageLayouts[template.URL(collectionURL)] = layoutName
-	}
+	return template.URL("collections/" + strings.Join(names, "/") + ".html")
 }
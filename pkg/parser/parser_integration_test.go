@@ -1,9 +1,13 @@
 package parser_test
 
 import (
+	"fmt"
 	"html/template"
 	"log"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/anna-ssg/anna/v3/pkg/parser"
@@ -48,3 +52,144 @@ func TestParseMDDir(t *testing.T) {
 		}
 	})
 }
+
+func TestParseMDDirDrafts(t *testing.T) {
+	tests := []struct {
+		file         string
+		renderDrafts bool
+		wantRendered bool
+	}{
+		{"post.md", false, true},
+		{"post_draft.md", false, false},
+		{"page.md", false, true},
+		{"page_draft.md", false, false},
+		{"note.md", false, true},
+		{"note_draft.md", false, false},
+		{"post.md", true, true},
+		{"post_draft.md", true, true},
+		{"page.md", true, true},
+		{"page_draft.md", true, true},
+		{"note.md", true, true},
+		{"note_draft.md", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s with draft flag %v", tt.file, tt.renderDrafts), func(t *testing.T) {
+			p := parser.Parser{
+				Templates:      make(map[template.URL]parser.TemplateData),
+				TagsMap:        make(map[template.URL][]parser.TemplateData),
+				CollectionsMap: make(map[template.URL][]parser.TemplateData),
+				ErrorLogger:    log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				RenderDrafts:   tt.renderDrafts,
+			}
+
+			p.ParseMDDir(TestDirPath+"drafts/", os.DirFS(TestDirPath+"drafts"))
+
+			gotRendered := slices.Contains(p.MdFilesName, tt.file)
+			if gotRendered != tt.wantRendered {
+				t.Errorf("got rendered %v, want %v", gotRendered, tt.wantRendered)
+			}
+		})
+	}
+}
+
+func TestParseMDDirCollectionMetadata(t *testing.T) {
+	p := parser.Parser{
+		Templates:      make(map[template.URL]parser.TemplateData),
+		TagsMap:        make(map[template.URL][]parser.TemplateData),
+		CollectionsMap: make(map[template.URL][]parser.TemplateData),
+		ErrorLogger:    log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+
+	p.ParseMDDir(TestDirPath+"collections/", os.DirFS(TestDirPath+"collections"))
+
+	t.Run("collection metadata files are not rendered as pages", func(t *testing.T) {
+		if _, ok := p.Templates["collections/posts.html"]; ok {
+			t.Errorf("collections/posts.md was added as a page")
+		}
+		if len(p.CollectionsMap["collections/posts.html"]) != 1 {
+			t.Errorf("got %v pages in the posts collection, want 1", len(p.CollectionsMap["collections/posts.html"]))
+		}
+	})
+
+	t.Run("collection metadata is parsed from the frontmatter and body", func(t *testing.T) {
+		metadata, ok := p.CollectionsMetadata["collections/posts.html"]
+		if !ok {
+			t.Fatalf("metadata of the posts collection not found")
+		}
+		if metadata.Frontmatter.Title != "All Posts" || metadata.Frontmatter.Layout != "all-posts" {
+			t.Errorf("got title %q and layout %q, want %q and %q", metadata.Frontmatter.Title, metadata.Frontmatter.Layout, "All Posts", "all-posts")
+		}
+		if metadata.Body != "<p>Posts about <strong>anna</strong></p>\n" {
+			t.Errorf("got body %q", metadata.Body)
+		}
+	})
+}
+
+func TestParseMDDirParallel(t *testing.T) {
+	contentDir := t.TempDir() + "/"
+	for i := 0; i < 50; i++ {
+		dir := fmt.Sprintf("%ssection-%d/", contentDir, i%5)
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+		content := fmt.Sprintf("---\ntitle: Post %d\ntype: post\ntags: [tag-%d]\n---\nPost %d\n", i, i%3, i)
+		if err := os.WriteFile(fmt.Sprintf("%spost-%d.md", dir, i), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parse := func(jobs int) parser.Parser {
+		p := parser.Parser{
+			Templates:   make(map[template.URL]parser.TemplateData),
+			TagsMap:     make(map[template.URL][]parser.TemplateData),
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			Jobs:        jobs,
+		}
+		p.ParseMDDir(contentDir, os.DirFS(contentDir))
+		return p
+	}
+
+	// Files are added in the order of the directory walk, whichever worker parses them first
+	want := parse(1)
+	if len(want.MdFilesName) != 50 {
+		t.Fatalf("got %d parsed files, want 50", len(want.MdFilesName))
+	}
+	for range 5 {
+		got := parse(8)
+		if !slices.Equal(got.MdFilesName, want.MdFilesName) {
+			t.Errorf("got files %v, want %v", got.MdFilesName, want.MdFilesName)
+		}
+		if !reflect.DeepEqual(got.Posts, want.Posts) {
+			t.Errorf("the posts parsed concurrently differ from the posts parsed by a single worker")
+		}
+		if !reflect.DeepEqual(got.TagsMap, want.TagsMap) {
+			t.Errorf("the tags parsed concurrently differ from the tags parsed by a single worker")
+		}
+	}
+}
+
+func BenchmarkParseMDDir(b *testing.B) {
+	contentDir := b.TempDir() + "/"
+	for i := 0; i < 300; i++ {
+		content := fmt.Sprintf("---\ntitle: Post %d\ndate: 2024-01-02\ntags: [bench]\ntoc: %v\n---\n%s", i, i%2 == 0, strings.Repeat("## Heading\nLorem ipsum *dolor* sit amet\n\n", 20))
+		if err := os.WriteFile(fmt.Sprintf("%spost-%d.md", contentDir, i), []byte(content), 0600); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, jobs := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p := parser.Parser{
+					Templates:   make(map[template.URL]parser.TemplateData),
+					TagsMap:     make(map[template.URL][]parser.TemplateData),
+					ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+					Jobs:        jobs,
+				}
+				p.ParseMDDir(contentDir, os.DirFS(contentDir))
+			}
+		})
+	}
+}
@@ -1,13 +1,24 @@
 package parser_test
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"html"
 	"html/template"
 	"log"
 	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
 	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
 	"github.com/anna-ssg/anna/v3/pkg/parser"
 )
 
@@ -52,11 +63,23 @@ func TestAddFileAndRender(t *testing.T) {
 		fileURL := "testpost.html"
 		wantParser.MdFilesName = append(wantParser.MdFilesName, filename)
 		wantParser.MdFilesPath = append(wantParser.MdFilesPath, filename)
+		// Pages without a type in the frontmatter default to the page type
+		wantFrontmatter := sampleFrontmatter
+		wantFrontmatter.Type = "page"
+		wantFrontmatter.Layout = "page"
+		wantFrontmatter.OutputFormat = "html"
 		wantPage := parser.TemplateData{
 			CompleteURL: template.URL(fileURL),
 			Date:        wantParser.DateParse(sampleFrontmatter.Date).Unix(),
-			Frontmatter: sampleFrontmatter,
+			Updated:     wantParser.DateParse(sampleFrontmatter.Date).Unix(),
+			Frontmatter: wantFrontmatter,
 			Body:        template.HTML(sampleBody),
+			// Pages without a canonical in the frontmatter are canonical at their own URL
+			CanonicalURL: template.URL("example.org/" + fileURL),
+			// The description is the excerpt and the site author is the author of pages without authors
+			Excerpt:     sampleFrontmatter.Description,
+			ReadingTime: 1,
+			Authors:     []string{"Anna"},
 			// Layout:      want_layout,
 		}
 		wantParser.LayoutConfig = wantLayout
@@ -75,6 +98,639 @@ func TestAddFileAndRender(t *testing.T) {
 	})
 }
 
+func TestAddFilePrettyURLs(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	p.LayoutConfig.PrettyURLs = true
+
+	tests := []struct {
+		filename    string
+		wantKey     template.URL
+		wantPageURL template.URL
+	}{
+		{"about.md", "about/index.html", "about/"},
+		{"posts/file.md", "posts/file/index.html", "posts/file/"},
+		{"index.md", "index.html", ""},
+		{"posts/index.md", "posts/index.html", "posts/"},
+	}
+
+	for _, tt := range tests {
+		t.Run("pretty url for "+tt.filename, func(t *testing.T) {
+			p.AddFile("", tt.filename, parser.Frontmatter{Title: tt.filename}, "", "")
+
+			page, ok := p.Templates[tt.wantKey]
+			if !ok {
+				t.Fatalf("page %s not found at key %s", tt.filename, tt.wantKey)
+			}
+			if page.CompleteURL != tt.wantPageURL {
+				t.Errorf("got %v, want %v", page.CompleteURL, tt.wantPageURL)
+			}
+		})
+	}
+}
+
+func TestAddFileExtensionlessURLs(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	p.LayoutConfig.URLStyle = parser.URLStyleExtensionless
+	p.LayoutConfig.ReaderMode = true
+
+	tests := []struct {
+		filename      string
+		frontmatter   parser.Frontmatter
+		wantKey       template.URL
+		wantPageURL   template.URL
+		wantReaderURL template.URL
+	}{
+		{"about.md", parser.Frontmatter{}, "about", "about", ""},
+		{"posts/file.md", parser.Frontmatter{Type: "post", Date: "2024-01-02"}, "posts/file", "posts/file", "posts/file-reader"},
+		{"index.md", parser.Frontmatter{}, "index.html", "", ""},
+		{"posts/index.md", parser.Frontmatter{}, "posts/index.html", "posts/", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run("extensionless url for "+tt.filename, func(t *testing.T) {
+			p.AddFile("", tt.filename, tt.frontmatter, "", "")
+
+			page, ok := p.Templates[tt.wantKey]
+			if !ok {
+				t.Fatalf("page %s not found at key %s", tt.filename, tt.wantKey)
+			}
+			if page.CompleteURL != tt.wantPageURL {
+				t.Errorf("got %v, want %v", page.CompleteURL, tt.wantPageURL)
+			}
+			if page.ReaderURL != tt.wantReaderURL {
+				t.Errorf("got reader URL %v, want %v", page.ReaderURL, tt.wantReaderURL)
+			}
+		})
+	}
+}
+
+func TestAddFileSectionRoots(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+
+	tests := []struct {
+		filename    string
+		wantKey     template.URL
+		wantPageURL template.URL
+	}{
+		{"index.md", "index.html", ""},
+		{"blog/index.md", "blog/index.html", "blog/"},
+		{"blog/first.md", "blog/first.html", "blog/first.html"},
+		{"blog/index-of-posts.md", "blog/index-of-posts.html", "blog/index-of-posts.html"},
+	}
+
+	for _, tt := range tests {
+		t.Run("url of "+tt.filename, func(t *testing.T) {
+			p.AddFile("", tt.filename, parser.Frontmatter{Title: tt.filename}, "", "")
+
+			page, ok := p.Templates[tt.wantKey]
+			if !ok {
+				t.Fatalf("page %s not found at key %s", tt.filename, tt.wantKey)
+			}
+			if page.CompleteURL != tt.wantPageURL {
+				t.Errorf("got %v, want %v", page.CompleteURL, tt.wantPageURL)
+			}
+		})
+	}
+}
+
+func TestAddFilePostsDir(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	p.LayoutConfig.PostsDir = "blog"
+
+	tests := []struct {
+		filename string
+		fileType string
+		wantType string
+		wantURL  template.URL
+	}{
+		{"blog/first.md", "", "post", "blog/first.html"},
+		{"blog/nested/second.md", "", "post", "blog/nested/second.html"},
+		{"blog/about.md", "page", "page", "blog/about.html"},
+		{"blogroll.md", "", "page", "blogroll.html"},
+		{"notes/note.md", "note", "note", "notes/note.html"},
+	}
+
+	for _, tt := range tests {
+		t.Run("type of "+tt.filename, func(t *testing.T) {
+			p.AddFile("", tt.filename, parser.Frontmatter{Title: tt.filename, Type: tt.fileType}, "", "")
+
+			page, ok := p.Templates[tt.wantURL]
+			if !ok {
+				t.Fatalf("page %s not found at key %s", tt.filename, tt.wantURL)
+			}
+			if page.Frontmatter.Type != tt.wantType {
+				t.Errorf("got %v, want %v", page.Frontmatter.Type, tt.wantType)
+			}
+		})
+	}
+
+	if len(p.Posts) != 2 {
+		t.Errorf("got %v posts, want 2", len(p.Posts))
+	}
+}
+
+func TestAddFileTypeLayouts(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	p.LayoutConfig.PostsDir = "posts"
+	p.LayoutConfig.Layouts = map[string]string{
+		"post": "article",
+		"note": "zettel",
+	}
+
+	tests := []struct {
+		filename    string
+		frontmatter parser.Frontmatter
+		wantURL     template.URL
+		wantLayout  string
+	}{
+		{"posts/first.md", parser.Frontmatter{}, "posts/first.html", "article"},
+		{"posts/second.md", parser.Frontmatter{Layout: "post"}, "posts/second.html", "post"},
+		{"notes/note.md", parser.Frontmatter{Type: "note"}, "notes/note.html", "zettel"},
+		{"about.md", parser.Frontmatter{}, "about.html", "page"},
+		{"links.md", parser.Frontmatter{Type: "link"}, "links.html", "page"},
+	}
+
+	for _, tt := range tests {
+		t.Run("layout of "+tt.filename, func(t *testing.T) {
+			p.AddFile("", tt.filename, tt.frontmatter, "", "")
+
+			page, ok := p.Templates[tt.wantURL]
+			if !ok {
+				t.Fatalf("page %s not found at key %s", tt.filename, tt.wantURL)
+			}
+			if page.Frontmatter.Layout != tt.wantLayout {
+				t.Errorf("got %v, want %v", page.Frontmatter.Layout, tt.wantLayout)
+			}
+		})
+	}
+}
+
+func TestAddFileOutputFormat(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	p.LayoutConfig.PrettyURLs = true
+
+	tests := []struct {
+		filename       string
+		frontmatter    parser.Frontmatter
+		wantURL        template.URL
+		wantLayoutName string
+	}{
+		{"about.md", parser.Frontmatter{}, "about/index.html", "page"},
+		{"posts/first.md", parser.Frontmatter{Layout: "post", OutputFormat: "txt"}, "posts/first.txt", "post.txt"},
+		{"data.md", parser.Frontmatter{Layout: "data.json", OutputFormat: "json"}, "data.json", "data.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run("output format of "+tt.filename, func(t *testing.T) {
+			p.AddFile("", tt.filename, tt.frontmatter, "", "")
+
+			page, ok := p.Templates[tt.wantURL]
+			if !ok {
+				t.Fatalf("page %s not found at key %s", tt.filename, tt.wantURL)
+			}
+			if got := page.Frontmatter.LayoutName(); got != tt.wantLayoutName {
+				t.Errorf("got %v, want %v", got, tt.wantLayoutName)
+			}
+		})
+	}
+}
+
+func TestAddFileCopyMarkdown(t *testing.T) {
+	siteDirPath := TestDirPath + "copy_markdown/"
+
+	for _, prettyURLs := range []bool{false, true} {
+		p := parser.Parser{
+			Templates:    make(map[template.URL]parser.TemplateData),
+			TagsMap:      make(map[template.URL][]parser.TemplateData),
+			SiteDataPath: siteDirPath,
+			ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		p.LayoutConfig.CopyMarkdown = true
+		p.LayoutConfig.PrettyURLs = prettyURLs
+
+		tests := []struct {
+			filename string
+			wantPath string
+		}{
+			{"posts/first.md", "posts/first.md"},
+			{"data.md", "data.md"},
+		}
+		if prettyURLs {
+			tests[0].wantPath = "posts/first/index.md"
+		}
+
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("copy of %s with prettyURLs %v", tt.filename, prettyURLs), func(t *testing.T) {
+				if err := os.RemoveAll(siteDirPath + "rendered/"); err != nil {
+					t.Fatal(err)
+				}
+
+				content, err := os.ReadFile(siteDirPath + "content/" + tt.filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				frontmatter, body, markdownContent, _ := p.ParseMarkdownContent(string(content), tt.filename)
+				p.AddFile(siteDirPath+"content/", tt.filename, frontmatter, markdownContent, body)
+
+				got, err := os.ReadFile(siteDirPath + "rendered/" + tt.wantPath)
+				if err != nil {
+					t.Fatalf("markdown source not copied: %v", err)
+				}
+				if !slices.Equal(got, content) {
+					t.Errorf("got %q, want %q", got, content)
+				}
+			})
+		}
+	}
+}
+
+func TestAddFileReaderURL(t *testing.T) {
+	for _, prettyURLs := range []bool{false, true} {
+		p := parser.Parser{
+			Templates:   make(map[template.URL]parser.TemplateData),
+			TagsMap:     make(map[template.URL][]parser.TemplateData),
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+		p.LayoutConfig.ReaderMode = true
+		p.LayoutConfig.PrettyURLs = prettyURLs
+
+		p.AddFile("", "posts/first.md", parser.Frontmatter{Type: "post", Date: "2024-01-02"}, "", "")
+		p.AddFile("", "about.md", parser.Frontmatter{}, "", "")
+
+		wantReaderURL := template.URL("posts/first/reader.html")
+		postKey := template.URL("posts/first.html")
+		if prettyURLs {
+			wantReaderURL = "posts/first/reader/"
+			postKey = "posts/first/index.html"
+		}
+
+		if got := p.Templates[postKey].ReaderURL; got != wantReaderURL {
+			t.Errorf("got %v, want %v", got, wantReaderURL)
+		}
+		for key, page := range p.Templates {
+			if key != postKey && page.ReaderURL != "" {
+				t.Errorf("got reader URL %v for %v, want none for pages that are not posts", page.ReaderURL, key)
+			}
+		}
+	}
+}
+
+func TestAddFileUpdated(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:    helpers.NewWarningCollector(),
+	}
+
+	p.AddFile("", "updated.md", parser.Frontmatter{Date: "2024-01-02", Updated: "2024-03-04"}, "", "")
+	p.AddFile("", "dated.md", parser.Frontmatter{Date: "2024-01-02"}, "", "")
+	p.AddFile("", "backdated.md", parser.Frontmatter{Date: "2024-01-02", Updated: "2023-12-31"}, "", "")
+
+	tests := []struct {
+		key  template.URL
+		want string
+	}{
+		{"updated.html", "2024-03-04"},
+		{"dated.html", "2024-01-02"},
+		{"backdated.html", "2023-12-31"},
+	}
+	for _, tt := range tests {
+		if got := time.Unix(p.Templates[tt.key].Updated, 0).UTC().Format("2006-01-02"); got != tt.want {
+			t.Errorf("got %v, want %v for %v", got, tt.want, tt.key)
+		}
+	}
+
+	if got := p.Warnings.Count(); got != 1 {
+		t.Errorf("got %v warnings, want 1 for the updated date before the published date", got)
+	}
+}
+
+func TestAddFileTermSlugs(t *testing.T) {
+	p := parser.Parser{
+		Templates:      make(map[template.URL]parser.TemplateData),
+		TagsMap:        make(map[template.URL][]parser.TemplateData),
+		CollectionsMap: make(map[template.URL][]parser.TemplateData),
+		ErrorLogger:    log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:       &helpers.WarningCollector{},
+	}
+
+	p.AddFile("", "first.md", parser.Frontmatter{Tags: []string{"My Tag", "C++"}, Collections: []string{"Web Dev>Go Lang"}}, "", "")
+	p.AddFile("", "second.md", parser.Frontmatter{Tags: []string{"my-tag"}}, "", "")
+
+	t.Run("tags and collections are keyed by their slug", func(t *testing.T) {
+		wantDisplayNames := map[template.URL]string{
+			"tags/my-tag.html":                 "My Tag",
+			"tags/c.html":                      "C++",
+			"collections/web-dev.html":         "Web Dev",
+			"collections/web-dev/go-lang.html": "Web Dev/Go Lang",
+		}
+		if !reflect.DeepEqual(p.DisplayNames, wantDisplayNames) {
+			t.Errorf("got %v, want %v", p.DisplayNames, wantDisplayNames)
+		}
+
+		if got := len(p.TagsMap["tags/my-tag.html"]); got != 2 {
+			t.Errorf("got %v pages tagged my-tag, want 2", got)
+		}
+		if got := len(p.CollectionsMap["collections/web-dev/go-lang.html"]); got != 1 {
+			t.Errorf("got %v pages in web-dev/go-lang, want 1", got)
+		}
+	})
+
+	t.Run("different names with the same slug are reported", func(t *testing.T) {
+		if got := p.Warnings.Count(); got != 1 {
+			t.Errorf("got %v warnings, want 1: %v", got, p.Warnings.Warnings())
+		}
+	})
+}
+
+func TestAddFileDateFromFilename(t *testing.T) {
+	tests := []struct {
+		name             string
+		dateFromFilename bool
+		file             string
+		frontmatter      parser.Frontmatter
+		wantKey          template.URL
+		wantDate         string
+	}{
+		{"date from the file name", true, "posts/2024-01-02-first.md", parser.Frontmatter{}, "posts/first.html", "2024-01-02"},
+		{"frontmatter date takes precedence", true, "posts/2024-01-02-second.md", parser.Frontmatter{Date: "2024-02-01"}, "posts/second.html", "2024-02-01"},
+		{"invalid date is kept in the url", true, "posts/2024-13-01-third.md", parser.Frontmatter{}, "posts/2024-13-01-third.html", ""},
+		{"date without a name is kept in the url", true, "posts/2024-01-02.md", parser.Frontmatter{}, "posts/2024-01-02.html", ""},
+		{"disabled by default", false, "posts/2024-01-02-fourth.md", parser.Frontmatter{}, "posts/2024-01-02-fourth.html", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.Parser{
+				Templates:   make(map[template.URL]parser.TemplateData),
+				TagsMap:     make(map[template.URL][]parser.TemplateData),
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				Warnings:    &helpers.WarningCollector{},
+			}
+			p.LayoutConfig.DateFromFilename = tt.dateFromFilename
+
+			p.AddFile("", tt.file, tt.frontmatter, "", "")
+
+			page, ok := p.Templates[tt.wantKey]
+			if !ok {
+				t.Fatalf("%v missing from the pages", tt.wantKey)
+			}
+			if page.Frontmatter.Date != tt.wantDate {
+				t.Errorf("got date %q, want %q", page.Frontmatter.Date, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestAddFileListingFields(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:    &helpers.WarningCollector{},
+	}
+	p.LayoutConfig.Author = "Site Author"
+	p.LayoutConfig.ExcerptLength = 12
+
+	p.AddFile("", "posts/excerpt.md", parser.Frontmatter{Cover: "images/cover.png"}, "", "<p>The quick brown fox</p>")
+	p.AddFile("", "posts/described.md", parser.Frontmatter{Description: "A description", Cover: "/static/cover.png", Authors: []string{"Jane"}}, "", "<p>Body</p>")
+
+	tests := []struct {
+		key             template.URL
+		wantExcerpt     string
+		wantReadingTime int
+		wantCoverURL    template.URL
+		wantAuthors     []string
+	}{
+		{"posts/excerpt.html", "The quick…", 1, "/posts/images/cover.png", []string{"Site Author"}},
+		{"posts/described.html", "A description", 1, "/static/cover.png", []string{"Jane"}},
+	}
+	for _, tt := range tests {
+		page := p.Templates[tt.key]
+		if page.Excerpt != tt.wantExcerpt {
+			t.Errorf("%v: got excerpt %q, want %q", tt.key, page.Excerpt, tt.wantExcerpt)
+		}
+		if page.ReadingTime != tt.wantReadingTime {
+			t.Errorf("%v: got reading time %v, want %v", tt.key, page.ReadingTime, tt.wantReadingTime)
+		}
+		if page.CoverURL != tt.wantCoverURL {
+			t.Errorf("%v: got cover %q, want %q", tt.key, page.CoverURL, tt.wantCoverURL)
+		}
+		if !slices.Equal(page.Authors, tt.wantAuthors) {
+			t.Errorf("%v: got authors %v, want %v", tt.key, page.Authors, tt.wantAuthors)
+		}
+	}
+}
+
+func TestAddFileHidden(t *testing.T) {
+	p := parser.Parser{
+		Templates:      make(map[template.URL]parser.TemplateData),
+		TagsMap:        make(map[template.URL][]parser.TemplateData),
+		CollectionsMap: make(map[template.URL][]parser.TemplateData),
+		ErrorLogger:    log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:       &helpers.WarningCollector{},
+	}
+
+	p.AddFile("", "listed.md", parser.Frontmatter{Title: "listed", Type: "post", Date: "2024-01-01", Tags: []string{"go"}, Collections: []string{"essays"}}, "", "")
+	p.AddFile("", "hidden.md", parser.Frontmatter{Title: "hidden", Type: "post", Date: "2024-01-02", Tags: []string{"go"}, Collections: []string{"essays"}, Hidden: true}, "", "")
+
+	if _, ok := p.Templates["hidden.html"]; !ok {
+		t.Errorf("hidden page missing from Templates, want it rendered")
+	}
+	if got := len(p.Posts); got != 1 {
+		t.Errorf("got %v posts, want 1", got)
+	}
+	if got := len(p.TagsMap["tags/go.html"]); got != 1 {
+		t.Errorf("got %v pages tagged go, want 1", got)
+	}
+	if got := len(p.CollectionsMap["collections/essays.html"]); got != 1 {
+		t.Errorf("got %v pages in essays, want 1", got)
+	}
+}
+
+func TestAddFileTagAliases(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:    &helpers.WarningCollector{},
+	}
+	p.LayoutConfig.TagAliases = map[string]string{"js": "JavaScript"}
+
+	p.AddFile("", "first.md", parser.Frontmatter{Tags: []string{"Go", " JS ", "javascript"}}, "", "")
+	p.AddFile("", "second.md", parser.Frontmatter{Tags: []string{"go", "GO", "js"}}, "", "")
+
+	t.Run("aliases and case variants are merged", func(t *testing.T) {
+		wantDisplayNames := map[template.URL]string{
+			"tags/go.html":         "Go",
+			"tags/javascript.html": "JavaScript",
+		}
+		if !reflect.DeepEqual(p.DisplayNames, wantDisplayNames) {
+			t.Errorf("got %v, want %v", p.DisplayNames, wantDisplayNames)
+		}
+
+		for key := range wantDisplayNames {
+			if got := len(p.TagsMap[key]); got != 2 {
+				t.Errorf("got %v pages tagged %v, want 2", got, key)
+			}
+		}
+		if got := p.Warnings.Count(); got != 0 {
+			t.Errorf("got %v warnings, want none: %v", got, p.Warnings.Warnings())
+		}
+	})
+
+	t.Run("the tags of pages are normalized", func(t *testing.T) {
+		if got, want := p.Templates["first.html"].Frontmatter.Tags, []string{"Go", "JavaScript"}; !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := p.Templates["second.html"].Frontmatter.Tags, []string{"go", "JavaScript"}; !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestAddFilePreviewImage(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	p.LayoutConfig.BaseURL = "https://example.org"
+
+	tests := []struct {
+		name         string
+		siteDefault  string
+		frontmatter  parser.Frontmatter
+		wantImageURL template.URL
+	}{
+		{"previewimage takes precedence", "/static/default.png", parser.Frontmatter{PreviewImage: "/static/preview.png", Cover: "/static/cover.png"}, "https://example.org/static/preview.png"},
+		{"cover is the fallback", "/static/default.png", parser.Frontmatter{Cover: "/static/cover.png"}, "https://example.org/static/cover.png"},
+		{"site default is the last fallback", "/static/default.png", parser.Frontmatter{}, "https://example.org/static/default.png"},
+		{"relative to the markdown file", "", parser.Frontmatter{Cover: "images/cover.png"}, "https://example.org/posts/images/cover.png"},
+		{"absolute urls are unchanged", "", parser.Frontmatter{Cover: "https://cdn.example.org/cover.png"}, "https://cdn.example.org/cover.png"},
+		{"no image", "", parser.Frontmatter{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.LayoutConfig.DefaultPreviewImage = tt.siteDefault
+			p.AddFile("", "posts/first.md", tt.frontmatter, "", "")
+
+			if got := p.Templates["posts/first.html"].PreviewImageURL; got != tt.wantImageURL {
+				t.Errorf("got %v, want %v", got, tt.wantImageURL)
+			}
+		})
+	}
+}
+
+func TestAddFileComments(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	p.LayoutConfig.BaseURL = "https://example.org/"
+	p.LayoutConfig.Comments = &parser.CommentsConfig{Provider: "disqus", Shortname: "anna"}
+
+	disabled := false
+	p.AddFile("", "posts/first.md", parser.Frontmatter{Type: "post"}, "", "")
+	p.AddFile("", "posts/second.md", parser.Frontmatter{Type: "post", Comments: &disabled}, "", "")
+	p.AddFile("", "about.md", parser.Frontmatter{}, "", "")
+
+	want := template.HTML("<div id=\"disqus_thread\"></div>\n<script>var disqus_config = function () { this.page.url = 'https://example.org/posts/first.html'; this.page.identifier = 'posts/first.html'; }; (function () { var d = document, s = d.createElement('script'); s.src = 'https://anna.disqus.com/embed.js'; s.setAttribute('data-timestamp', +new Date()); (d.head || d.body).appendChild(s); })();</script>")
+	if got := p.Templates["posts/first.html"].CommentsHTML; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := p.Templates["posts/second.html"].CommentsHTML; got != "" {
+		t.Errorf("got %v, want no comments for a post with comments disabled", got)
+	}
+	if got := p.Templates["about.html"].CommentsHTML; got != "" {
+		t.Errorf("got %v, want no comments for a page", got)
+	}
+
+	t.Run("giscus", func(t *testing.T) {
+		p.LayoutConfig.Comments = &parser.CommentsConfig{Provider: "giscus", Repo: "anna-ssg/anna", RepoID: "R_1", Category: "Comments", CategoryID: "C_1"}
+		p.AddFile("", "posts/third.md", parser.Frontmatter{Type: "post"}, "", "")
+
+		want := template.HTML(`<script src="https://giscus.app/client.js" data-repo="anna-ssg/anna" data-repo-id="R_1" data-category="Comments" data-category-id="C_1" data-mapping="pathname" data-reactions-enabled="1" data-theme="preferred_color_scheme" crossorigin="anonymous" async></script>`)
+		if got := p.Templates["posts/third.html"].CommentsHTML; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestParseFrontmatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantErrs []parser.FrontmatterError
+	}{
+		{
+			"valid frontmatter",
+			"---\ntitle: Hello\ndate: 2024-03-28\n---\n# Hello\n",
+			nil,
+		},
+		{
+			"missing frontmatter",
+			"# Hello\n",
+			[]parser.FrontmatterError{{Path: "post.md", Line: 1, Message: "frontmatter is missing"}},
+		},
+		{
+			"type error at the value",
+			"---\ntitle: Hello\ndraft: maybe\n---\n",
+			[]parser.FrontmatterError{{Path: "post.md", Line: 3, Column: 8, Message: "cannot unmarshal !!str `maybe` into bool"}},
+		},
+		{
+			"syntax error offset by the lines before the frontmatter",
+			"\n\n---\ntitle: Hello\n  bad: : value\n---\n",
+			[]parser.FrontmatterError{{Path: "post.md", Line: 5, Message: "mapping values are not allowed in this context"}},
+		},
+		{
+			"invalid date",
+			"---\ntitle: Hello\ndate: 28-03-2024\n---\n",
+			[]parser.FrontmatterError{{Path: "post.md", Line: 3, Column: 7, Message: `date "28-03-2024" is not of the form YYYY-MM-DD or YYYY-MM-DDTHH:MM:SS`}},
+		},
+		{
+			"dates with a time",
+			"---\ntitle: Hello\ndate: 2024-03-28T09:30:00+05:30\nupdated: 2024-03-29 18:00\n---\n",
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, gotErrs := parser.ParseFrontmatter(tt.content, "post.md")
+			if !reflect.DeepEqual(gotErrs, tt.wantErrs) {
+				t.Errorf("got %v, want %v", gotErrs, tt.wantErrs)
+			}
+		})
+	}
+}
+
 func TestParseMarkdownContent(t *testing.T) {
 	p := parser.Parser{
 		Templates:   make(map[template.URL]parser.TemplateData),
@@ -123,25 +779,139 @@ func TestParseMarkdownContent(t *testing.T) {
 	})
 }
 
-func TestParseConfig(t *testing.T) {
-	t.Run("unmarshal `config.json` to LayoutConfig", func(t *testing.T) {
-		gotParser := parser.Parser{
-			Templates:   make(map[template.URL]parser.TemplateData),
-			TagsMap:     make(map[template.URL][]parser.TemplateData),
-			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
-		}
-
-		wantLayout := parser.LayoutConfig{
-			Navbar: []map[string]string{
-				{"Index": "index.html"},
-				{"Docs": "docs.html"},
-				{"Tags": "tags.html"},
-				{"Posts": "posts.html"},
-			},
+func TestParseMarkdownContentOptions(t *testing.T) {
+	disabled := false
+	input := "---\ntitle: Options\n---\n# Heading\n\nline one\nline two <b>raw</b>\n"
 
-			BaseURL:   "example.org",
-			SiteTitle: "ssg",
-			Author:    "Anna",
+	tests := []struct {
+		name     string
+		markdown parser.MarkdownConfig
+		want     string
+	}{
+		{
+			"defaults render raw html and heading ids",
+			parser.MarkdownConfig{},
+			"<h1 id=\"heading\">Heading</h1>\n<p>line one\nline two <b>raw</b></p>\n",
+		},
+		{
+			"disable unsafe html and heading ids",
+			parser.MarkdownConfig{Unsafe: &disabled, AutoHeadingID: &disabled},
+			"<h1>Heading</h1>\n<p>line one\nline two <!-- raw HTML omitted -->raw<!-- raw HTML omitted --></p>\n",
+		},
+		{
+			"hard wraps with xhtml",
+			parser.MarkdownConfig{HardWraps: true, XHTML: true},
+			"<h1 id=\"heading\">Heading</h1>\n<p>line one<br />\nline two <b>raw</b></p>\n",
+		},
+		{
+			"sanitize with the default policy",
+			parser.MarkdownConfig{Sanitize: true},
+			"<h1 id=\"heading\">Heading</h1>\n<p>line one\nline two <b>raw</b></p>\n",
+		},
+		{
+			"sanitize with the strict policy",
+			parser.MarkdownConfig{Sanitize: true, SanitizePolicy: "strict"},
+			"Heading\nline one\nline two raw\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.Parser{
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			p.LayoutConfig.Markdown = tt.markdown
+
+			_, got, _, _ := p.ParseMarkdownContent(input, "options.md")
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownContentImages(t *testing.T) {
+	disabled := false
+	input := "---\ntitle: Images\n---\n![Hero](/static/hero.png)\nA **bold** caption\n\n<img src=\"/static/b.png\" loading=\"eager\">\n"
+
+	tests := []struct {
+		name            string
+		lazyImages      *bool
+		eagerFirstImage bool
+		want            string
+	}{
+		{
+			"lazy images by default",
+			nil,
+			false,
+			"<figure>\n<img loading=\"lazy\" decoding=\"async\" src=\"/static/hero.png\" alt=\"Hero\">\n<figcaption><p>A <strong>bold</strong> caption</p></figcaption>\n</figure>\n<img decoding=\"async\" src=\"/static/b.png\" loading=\"eager\">\n",
+		},
+		{
+			"eager first image",
+			nil,
+			true,
+			"<figure>\n<img decoding=\"async\" src=\"/static/hero.png\" alt=\"Hero\">\n<figcaption><p>A <strong>bold</strong> caption</p></figcaption>\n</figure>\n<img decoding=\"async\" src=\"/static/b.png\" loading=\"eager\">\n",
+		},
+		{
+			"lazy images disabled",
+			&disabled,
+			false,
+			"<figure>\n<img src=\"/static/hero.png\" alt=\"Hero\">\n<figcaption><p>A <strong>bold</strong> caption</p></figcaption>\n</figure>\n<img src=\"/static/b.png\" loading=\"eager\">\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.Parser{
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			p.LayoutConfig.LazyImages = tt.lazyImages
+			p.LayoutConfig.EagerFirstImage = tt.eagerFirstImage
+
+			_, got, _, _ := p.ParseMarkdownContent(input, "images.md")
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeScripts(t *testing.T) {
+	p := parser.Parser{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	p.LayoutConfig.Markdown.Sanitize = true
+	p.LayoutConfig.Markdown.SanitizeAllowAttributes = []string{"class"}
+
+	input := "---\ntitle: Sanitize\n---\n<script>alert(1)</script>\n\n<p class=\"note\" onclick=\"alert(1)\">text</p>\n"
+
+	_, got, _, _ := p.ParseMarkdownContent(input, "sanitize.md")
+
+	want := "\n<p class=\"note\">text</p>\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Run("unmarshal `config.json` to LayoutConfig", func(t *testing.T) {
+		gotParser := parser.Parser{
+			Templates:   make(map[template.URL]parser.TemplateData),
+			TagsMap:     make(map[template.URL][]parser.TemplateData),
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+
+		wantLayout := parser.LayoutConfig{
+			Navbar: []map[string]string{
+				{"Index": "index.html"},
+				{"Docs": "docs.html"},
+				{"Tags": "tags.html"},
+				{"Posts": "posts.html"},
+			},
+
+			BaseURL:   "example.org",
+			SiteTitle: "ssg",
+			Author:    "Anna",
 		}
 
 		gotParser.ParseConfig(TestDirPath + "layout/config.json")
@@ -152,6 +922,189 @@ func TestParseConfig(t *testing.T) {
 	})
 }
 
+func TestParseConfigEnvOverrides(t *testing.T) {
+	t.Run("environment variables override `config.json`", func(t *testing.T) {
+		t.Setenv("ANNA_BASE_URL", "https://staging.example.org")
+		t.Setenv("ANNA_SITE_TITLE", "ssg staging")
+
+		gotParser := parser.Parser{
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		}
+
+		gotParser.ParseConfig(TestDirPath + "layout/config.json")
+
+		if gotParser.LayoutConfig.BaseURL != "https://staging.example.org" {
+			t.Errorf("got base url %v, want %v", gotParser.LayoutConfig.BaseURL, "https://staging.example.org")
+		}
+		if gotParser.LayoutConfig.SiteTitle != "ssg staging" {
+			t.Errorf("got site title %v, want %v", gotParser.LayoutConfig.SiteTitle, "ssg staging")
+		}
+		if gotParser.LayoutConfig.Author != "Anna" {
+			t.Errorf("got author %v, want %v", gotParser.LayoutConfig.Author, "Anna")
+		}
+	})
+}
+
+func TestParseConfigSecurityExpiresAtBuildTime(t *testing.T) {
+	// An expiry date in the past of the clock is valid for a reproducible build of an earlier date
+	t.Setenv("SOURCE_DATE_EPOCH", "1546300800")
+
+	configPath := t.TempDir() + "/config.json"
+	config := `{"security": {"contact": ["mailto:security@example.org"], "expires": "2020-01-01T00:00:00Z"}}`
+	if err := os.WriteFile(configPath, []byte(config), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	p := parser.Parser{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	p.ParseConfig(configPath)
+
+	if p.LayoutConfig.Security == nil || p.LayoutConfig.Security.Expires != "2020-01-01T00:00:00Z" {
+		t.Errorf("got security config %+v, want the expiry date of the config", p.LayoutConfig.Security)
+	}
+}
+
+func TestConfigSources(t *testing.T) {
+	t.Setenv("ANNA_SITE_TITLE", "ssg staging")
+
+	p := parser.Parser{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	got := p.ConfigSources(TestDirPath + "layout/config.json")
+
+	want := map[string]string{
+		"baseURL":   parser.ConfigSourceFile,
+		"siteTitle": "env ANNA_SITE_TITLE",
+		"feed":      parser.ConfigSourceDefault,
+	}
+	for key, source := range want {
+		if got[key] != source {
+			t.Errorf("got source %q of %s, want %q", got[key], key, source)
+		}
+	}
+	if len(got) != len(parser.ConfigKeys()) {
+		t.Errorf("got sources of %d keys, want every one of the %d keys of the config", len(got), len(parser.ConfigKeys()))
+	}
+}
+
+func TestLayoutConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config parser.LayoutConfig
+		want   []string
+	}{
+		{"valid", parser.LayoutConfig{BaseURL: "https://example.org", SiteTitle: "ssg"}, nil},
+		{"missing fields", parser.LayoutConfig{}, []string{"baseURL is not set", "siteTitle is not set"}},
+		{"relative base url", parser.LayoutConfig{BaseURL: "example.org", SiteTitle: "ssg"}, []string{`baseURL "example.org" is not an absolute http or https URL`}},
+		{"other scheme", parser.LayoutConfig{BaseURL: "ftp://example.org", SiteTitle: "ssg"}, []string{`baseURL "ftp://example.org" is not an absolute http or https URL`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.Validate(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurityConfigValidate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		config parser.SecurityConfig
+		want   []string
+	}{
+		{"valid", parser.SecurityConfig{Contact: []string{"security@example.org"}, Expires: "2027-01-01T00:00:00Z"}, nil},
+		{"missing fields", parser.SecurityConfig{}, []string{"security: at least one contact is required", "security: expires is required"}},
+		{"empty contact", parser.SecurityConfig{Contact: []string{" "}, Expires: "2027-01-01T00:00:00Z"}, []string{"security: contact 0 is empty"}},
+		{"date without time", parser.SecurityConfig{Contact: []string{"https://example.org/report"}, Expires: "2027-01-01"}, []string{`security: invalid expires "2027-01-01", expected a date such as 2026-12-31T23:59:59Z`}},
+		{"expired", parser.SecurityConfig{Contact: []string{"https://example.org/report"}, Expires: "2025-12-31T23:59:59Z"}, []string{`security: expires "2025-12-31T23:59:59Z" is in the past`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.Validate(now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateParse(t *testing.T) {
+	p := parser.Parser{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+
+	tests := []struct {
+		date string
+		want time.Time
+	}{
+		{"2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"2024-01-02T15:04:05Z", time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2024-01-02T15:04:05+05:30", time.Date(2024, 1, 2, 9, 34, 5, 0, time.UTC)},
+		{"2024-01-02T15:04:05", time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2024-01-02 15:04", time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := p.DateParse(tt.date); !got.Equal(tt.want) {
+			t.Errorf("DateParse(%q) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestLayoutConfigBasePath(t *testing.T) {
+	tests := []struct {
+		baseURL  string
+		basePath string
+		want     string
+	}{
+		{"https://example.org", "", ""},
+		{"https://example.org/", "", ""},
+		{"https://user.github.io/repo", "", "/repo"},
+		{"https://user.github.io/repo/", "", "/repo"},
+		{"https://example.org/docs/v3/", "", "/docs/v3"},
+		{"example.org", "", ""},
+		{"", "", ""},
+		{"https://example.org", "/assets", "/assets"},
+		{"https://example.org", "preview/", "/preview"},
+		{"https://user.github.io/repo/", "/", ""},
+		{"https://user.github.io/repo/", "/mirror/repo/", "/mirror/repo"},
+	}
+
+	for _, tt := range tests {
+		config := parser.LayoutConfig{BaseURL: tt.baseURL, PathPrefix: tt.basePath}
+		if got := config.BasePath(); got != tt.want {
+			t.Errorf("BasePath() of %q with basePath %q = %q, want %q", tt.baseURL, tt.basePath, got, tt.want)
+		}
+	}
+}
+
+func TestLayoutConfigCopyrightNotice(t *testing.T) {
+	buildTime := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		copyright string
+		want      string
+	}{
+		{"CC BY-SA 4.0", "CC BY-SA 4.0"},
+		{"© {year} Anna", "© 2026 Anna"},
+		{"© {year-range 2019} Anna", "© 2019-2026 Anna"},
+		{"© {year-range 2026} Anna", "© 2026 Anna"},
+		{"© {year-range 2030} Anna", "© 2026 Anna"},
+		{"{year}, {year}", "2026, 2026"},
+		{"{years} {year-range}", "{years} {year-range}"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := (parser.LayoutConfig{Copyright: tt.copyright}).CopyrightNotice(buildTime); got != tt.want {
+			t.Errorf("CopyrightNotice() of %q = %q, want %q", tt.copyright, got, tt.want)
+		}
+	}
+}
+
 func TestParseRobots(t *testing.T) {
 	t.Run("parse and render `robots.txt`", func(t *testing.T) {
 		testParser := parser.Parser{
@@ -176,3 +1129,1087 @@ func TestParseRobots(t *testing.T) {
 		}
 	})
 }
+
+func TestAddFileProtected(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	t.Setenv("ANNA_TEST_PASSWORD", "from the environment")
+
+	body := "<p>members only</p>"
+
+	tests := []struct {
+		filename string
+		password string
+		want     string
+	}{
+		{"members.md", "hunter2", "hunter2"},
+		{"env.md", "env:ANNA_TEST_PASSWORD", "from the environment"},
+	}
+
+	for _, tt := range tests {
+		t.Run("protecting "+tt.filename, func(t *testing.T) {
+			p.AddFile("", tt.filename, parser.Frontmatter{Title: "members", Password: tt.password}, "", body)
+
+			page := p.Templates[template.URL(strings.TrimSuffix(tt.filename, ".md")+".html")]
+			if !page.Protected {
+				t.Error("page is not marked as protected")
+			}
+			if page.Frontmatter.Password != "" {
+				t.Error("password is available to layouts")
+			}
+			if strings.Contains(string(page.Body), "members only") {
+				t.Fatal("body is not encrypted")
+			}
+
+			if got := decryptProtectedBody(t, string(page.Body), tt.want); got != body {
+				t.Errorf("got %q, want %q", got, body)
+			}
+		})
+	}
+}
+
+func TestAddFileProtectedReproducible(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1709251200")
+
+	protect := func(filename string, body string) string {
+		p := parser.Parser{
+			Templates:   make(map[template.URL]parser.TemplateData),
+			ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			Warnings:    helpers.NewWarningCollector(),
+		}
+		p.AddFile("", filename, parser.Frontmatter{Title: "members", Password: "hunter2"}, "", body)
+		return string(p.Templates[template.URL(strings.TrimSuffix(filename, ".md")+".html")].Body)
+	}
+
+	first := protect("members.md", "<p>members only</p>")
+	if second := protect("members.md", "<p>members only</p>"); first != second {
+		t.Errorf("got different protected bodies for two builds at the same SOURCE_DATE_EPOCH")
+	}
+	if got := decryptProtectedBody(t, first, "hunter2"); got != "<p>members only</p>" {
+		t.Errorf("got %q, want the body", got)
+	}
+
+	// A different body or page is never encrypted with the same nonce or salt
+	nonceRegex := regexp.MustCompile(`data-nonce="([^"]*)"`)
+	saltRegex := regexp.MustCompile(`data-salt="([^"]*)"`)
+	changed := protect("members.md", "<p>members only, updated</p>")
+	if nonceRegex.FindString(first) == nonceRegex.FindString(changed) {
+		t.Errorf("got the same nonce for different bodies")
+	}
+	if saltRegex.FindString(first) == saltRegex.FindString(protect("other.md", "<p>members only</p>")) {
+		t.Errorf("got the same salt for different pages")
+	}
+}
+
+func TestAddFileProtectedTransclusionWarning(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:    helpers.NewWarningCollector(),
+	}
+	p.AddFile("", "members.md", parser.Frontmatter{Title: "members", Password: "hunter2"}, "", "<p>![[notes/idea]]</p>\n")
+
+	want := []string{"members.md: ![[...]] is not transcluded in a protected page, as its body is encrypted first"}
+	if got := p.Warnings.Warnings(); !slices.Equal(got, want) {
+		t.Errorf("got warnings %q, want %q", got, want)
+	}
+}
+
+// decryptProtectedBody decrypts the body of a protected page with its password, as the password prompt does in the browser
+func decryptProtectedBody(t *testing.T, body string, password string) string {
+	t.Helper()
+	attrRegex := regexp.MustCompile(`data-(salt|nonce|iterations|ciphertext)="([^"]*)"`)
+	attrs := make(map[string]string)
+	for _, match := range attrRegex.FindAllStringSubmatch(body, -1) {
+		attrs[match[1]] = html.UnescapeString(match[2])
+	}
+	decode := func(name string) []byte {
+		value, err := base64.StdEncoding.DecodeString(attrs[name])
+		if err != nil {
+			t.Fatalf("decoding %s: %v", name, err)
+		}
+		return value
+	}
+	iterations, err := strconv.Atoi(attrs["iterations"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := aes.NewCipher(helpers.PBKDF2SHA256([]byte(password), decode("salt"), iterations, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := gcm.Open(nil, decode("nonce"), decode("ciphertext"), nil)
+	if err != nil {
+		t.Fatalf("decrypting the body: %v", err)
+	}
+	return string(got)
+}
+
+func TestAddFileUpdatedSourceDateEpoch(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	contentDir := siteDirPath + "content/"
+	if err := os.MkdirAll(contentDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(contentDir+"about.md", []byte("---\ntitle: about\n---\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Unix(1800000000, 0)
+	if err := os.Chtimes(contentDir+"about.md", modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		epoch string
+		want  int64
+	}{
+		{"", 1800000000},
+		{"1700000000", 1700000000},
+		{"1900000000", 1800000000},
+	}
+
+	for _, tt := range tests {
+		t.Run("SOURCE_DATE_EPOCH "+tt.epoch, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tt.epoch)
+			p := parser.Parser{
+				Templates:    make(map[template.URL]parser.TemplateData),
+				TagsMap:      make(map[template.URL][]parser.TemplateData),
+				SiteDataPath: siteDirPath,
+				ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			p.AddFile(contentDir, "about.md", parser.Frontmatter{Title: "about"}, "", "")
+
+			if got := p.Templates["about.html"].Updated; got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMDDirTypeFilter(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	files := map[string]string{
+		"posts/first.md": "---\ntitle: first\ndate: 2024-01-01\n---\n",
+		"notes/idea.md":  "---\ntitle: idea\ntype: note\n---\n",
+		"about.md":       "---\ntitle: about\n---\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(siteDirPath+"content/"+name), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(siteDirPath+"content/"+name, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		onlyTypes []string
+		skipTypes []string
+		want      []string
+	}{
+		{"full build", nil, nil, []string{"about.html", "notes/idea.html", "posts/first.html"}},
+		{"only posts", []string{"post"}, nil, []string{"posts/first.html"}},
+		{"only posts and pages", []string{"post", "page"}, nil, []string{"about.html", "posts/first.html"}},
+		{"skip notes", nil, []string{"note"}, []string{"about.html", "posts/first.html"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.Parser{
+				Templates:      make(map[template.URL]parser.TemplateData),
+				TagsMap:        make(map[template.URL][]parser.TemplateData),
+				CollectionsMap: make(map[template.URL][]parser.TemplateData),
+				SiteDataPath:   siteDirPath,
+				OnlyTypes:      tt.onlyTypes,
+				SkipTypes:      tt.skipTypes,
+				ErrorLogger:    log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				Warnings:       helpers.NewWarningCollector(),
+			}
+			p.LayoutConfig.PostsDir = "posts"
+			p.ParseMDDir(siteDirPath+"content/", os.DirFS(siteDirPath+"content/"))
+
+			var got []string
+			for key := range p.Templates {
+				got = append(got, string(key))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddFileCanonical(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	p.LayoutConfig.BaseURL = "https://example.org"
+
+	tests := []struct {
+		filename  string
+		canonical string
+		want      template.URL
+	}{
+		{"own.md", "", "https://example.org/own.html"},
+		{"syndicated.md", "https://dev.to/anna/syndicated", "https://dev.to/anna/syndicated"},
+		{"moved.md", "/posts/moved.html", "https://example.org/posts/moved.html"},
+	}
+
+	for _, tt := range tests {
+		t.Run("canonical of "+tt.filename, func(t *testing.T) {
+			p.AddFile("", tt.filename, parser.Frontmatter{Title: tt.filename, Canonical: tt.canonical}, "", "")
+
+			got := p.Templates[template.URL(strings.TrimSuffix(tt.filename, ".md")+".html")].CanonicalURL
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddFileBodyClass(t *testing.T) {
+	p := parser.Parser{
+		Templates:   make(map[template.URL]parser.TemplateData),
+		TagsMap:     make(map[template.URL][]parser.TemplateData),
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:    &helpers.WarningCollector{},
+	}
+
+	tests := []struct {
+		name         string
+		frontmatter  string
+		want         string
+		wantWarnings int
+	}{
+		{"none", "title: none", "", 0},
+		{"string", "title: string\nbodyClass: wide  dark", "wide dark", 0},
+		{"list", "title: list\nbodyClass: [wide, dark-variant wide]", "wide dark-variant", 0},
+		{"invalid", "title: invalid\nbodyClass: wide \"><script>", "wide", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frontmatter, _, errs := parser.ParseFrontmatter("---\n"+tt.frontmatter+"\n---\n", tt.name+".md")
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+
+			before := p.Warnings.Count()
+			p.AddFile("", tt.name+".md", frontmatter, "", "")
+			if got := p.Templates[template.URL(tt.name+".html")].BodyClass; got != tt.want {
+				t.Errorf("got body class %q, want %q", got, tt.want)
+			}
+			if got := p.Warnings.Count() - before; got != tt.wantWarnings {
+				t.Errorf("got %d warnings, want %d", got, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownContentTOCDepth(t *testing.T) {
+	content := "---\ntitle: toc\ntoc: true\n%s---\n# Title\n## Section\n### Subsection\n#### Detail\n"
+
+	tests := []struct {
+		name        string
+		frontmatter string
+		minDepth    int
+		maxDepth    int
+		want        []string
+	}{
+		{"every level by default", "", 0, 0, []string{"title", "section", "subsection", "detail"}},
+		{"max depth from the config", "", 0, 3, []string{"title", "section", "subsection"}},
+		{"min and max depth from the config", "", 2, 3, []string{"section", "subsection"}},
+		{"depth from the frontmatter", "tocMaxDepth: 2\n", 0, 3, []string{"title", "section"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.Parser{
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			p.LayoutConfig.TOCMinDepth = tt.minDepth
+			p.LayoutConfig.TOCMaxDepth = tt.maxDepth
+
+			_, body, _, _ := p.ParseMarkdownContent(fmt.Sprintf(content, tt.frontmatter), "toc.md")
+
+			// Every heading links to itself, headings in the table of contents are linked twice
+			var got []string
+			for _, id := range []string{"title", "section", "subsection", "detail"} {
+				if strings.Count(body, `href="#`+id+`"`) == 2 {
+					got = append(got, id)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v in the table of contents, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownContentCallouts(t *testing.T) {
+	p := parser.Parser{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{"note", "> [!NOTE]\n> Useful information", "<div class=\"callout callout-note\" role=\"note\">\n<p class=\"callout-title\">Note</p>\n<p>Useful information</p>\n</div>\n"},
+		{"tip", "> [!TIP]\n> Helpful advice", "<div class=\"callout callout-tip\" role=\"note\">\n<p class=\"callout-title\">Tip</p>\n<p>Helpful advice</p>\n</div>\n"},
+		{"important", "> [!IMPORTANT]\n> Key information", "<div class=\"callout callout-important\" role=\"note\">\n<p class=\"callout-title\">Important</p>\n<p>Key information</p>\n</div>\n"},
+		{"warning", "> [!WARNING]\n> Urgent info with **emphasis**", "<div class=\"callout callout-warning\" role=\"note\">\n<p class=\"callout-title\">Warning</p>\n<p>Urgent info with <strong>emphasis</strong></p>\n</div>\n"},
+		{"caution", "> [!caution]\n> Negative outcomes", "<div class=\"callout callout-caution\" role=\"note\">\n<p class=\"callout-title\">Caution</p>\n<p>Negative outcomes</p>\n</div>\n"},
+		{"content in the next paragraph", "> [!NOTE]\n>\n> First\n>\n> Second", "<div class=\"callout callout-note\" role=\"note\">\n<p class=\"callout-title\">Note</p>\n<p>First</p>\n<p>Second</p>\n</div>\n"},
+		{"plain blockquote", "> Just a quote", "<blockquote>\n<p>Just a quote</p>\n</blockquote>\n"},
+		{"unknown type", "> [!UNKNOWN]\n> Text", "<blockquote>\n<p>[!UNKNOWN]\nText</p>\n</blockquote>\n"},
+		{"marker not alone on its line", "> [!NOTE] Text", "<blockquote>\n<p>[!NOTE] Text</p>\n</blockquote>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, _, _ := p.ParseMarkdownContent("---\ntitle: callouts\n---\n"+tt.markdown+"\n", "callouts.md")
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMDDirContentDir(t *testing.T) {
+	for _, contentDir := range []string{"docs", "/docs/", "src/docs"} {
+		t.Run("content in "+contentDir, func(t *testing.T) {
+			siteDirPath := t.TempDir() + "/"
+			contentDirPath := siteDirPath + strings.Trim(contentDir, "/") + "/"
+			if err := os.MkdirAll(contentDirPath+"guides/", 0750); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(contentDirPath+"guides/setup.md", []byte("---\ntitle: setup\n---\n"), 0640); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(contentDirPath+"diagram.svg", []byte("<svg></svg>"), 0640); err != nil {
+				t.Fatal(err)
+			}
+
+			p := parser.Parser{
+				Templates:    make(map[template.URL]parser.TemplateData),
+				TagsMap:      make(map[template.URL][]parser.TemplateData),
+				SiteDataPath: siteDirPath,
+				ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			p.LayoutConfig.ContentDir = contentDir
+
+			if got := p.ContentDirPath(); got != contentDirPath {
+				t.Errorf("got content path %s, want %s", got, contentDirPath)
+			}
+
+			p.ParseMDDir(p.ContentDirPath(), os.DirFS(p.ContentDirPath()))
+
+			if _, ok := p.Templates["guides/setup.html"]; !ok {
+				t.Error("guides/setup.html was not parsed")
+			}
+			if _, err := os.Stat(siteDirPath + "rendered/diagram.svg"); err != nil {
+				t.Errorf("static file in the content directory not copied: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseContentDirs(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	files := map[string]string{
+		"blog/first.md":      "---\ntitle: first\n---\n",
+		"blog/docs/setup.md": "---\ntitle: colliding setup\n---\n",
+		"docs/setup.md":      "---\ntitle: setup\n---\n",
+		"docs/diagram.svg":   "<svg></svg>",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(siteDirPath+name), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(siteDirPath+name, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := parser.Parser{
+		Templates:    make(map[template.URL]parser.TemplateData),
+		TagsMap:      make(map[template.URL][]parser.TemplateData),
+		SiteDataPath: siteDirPath,
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:     &helpers.WarningCollector{},
+	}
+	p.LayoutConfig.ContentDirs = []parser.ContentDirConfig{
+		{Dir: "blog"},
+		{Dir: "docs/", URLPrefix: "/docs/"},
+	}
+
+	p.ParseContentDirs()
+
+	t.Run("pages of every directory are merged", func(t *testing.T) {
+		for _, key := range []template.URL{"first.html", "docs/setup.html"} {
+			if _, ok := p.Templates[key]; !ok {
+				t.Errorf("%v was not parsed", key)
+			}
+		}
+		if _, err := os.Stat(siteDirPath + "rendered/docs/diagram.svg"); err != nil {
+			t.Errorf("static file not copied under the url prefix: %v", err)
+		}
+	})
+
+	t.Run("pages rendered to the same url are reported", func(t *testing.T) {
+		if got := p.Warnings.Count(); got != 1 {
+			t.Errorf("got %v warnings, want 1: %v", got, p.Warnings.Warnings())
+		}
+		if got := p.Templates["docs/setup.html"].Frontmatter.Title; got != "setup" {
+			t.Errorf("got %q, want the page of the last directory", got)
+		}
+	})
+}
+
+func TestAddFileFootnotes(t *testing.T) {
+	markdown := "---\ntitle: footnotes\n---\nText[^a] and more[^b]\n\n[^a]: First *note*\n[^b]: Second note\n"
+	wantFootnotes := []parser.Footnote{
+		{ID: "fn:1", Index: 1, Body: `<p>First <em>note</em>&#160;<a href="#fnref:1" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>`},
+		{ID: "fn:2", Index: 2, Body: `<p>Second note&#160;<a href="#fnref:2" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>`},
+	}
+
+	tests := []struct {
+		name     string
+		heading  string
+		separate bool
+		wantBody []string
+		skipBody []string
+	}{
+		{"inline", "", false, []string{"<div class=\"footnotes\" role=\"doc-endnotes\">\n<hr>\n<ol>"}, []string{"footnotes-heading"}},
+		{"heading", "References", false, []string{"<div class=\"footnotes\" role=\"doc-endnotes\">\n<h2 class=\"footnotes-heading\">References</h2>\n<ol>"}, []string{"<hr>"}},
+		{"separate", "References", true, []string{`<a href="#fn:1" class="footnote-ref"`}, []string{"doc-endnotes", "References"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.Parser{
+				Templates:   make(map[template.URL]parser.TemplateData),
+				TagsMap:     make(map[template.URL][]parser.TemplateData),
+				ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+			}
+			p.LayoutConfig.FootnoteHeading = tt.heading
+			p.LayoutConfig.SeparateFootnotes = tt.separate
+
+			frontmatter, body, markdownContent, _ := p.ParseMarkdownContent(markdown, "footnotes.md")
+			p.AddFile("", "footnotes.md", frontmatter, markdownContent, body)
+
+			page := p.Templates["footnotes.html"]
+			if !reflect.DeepEqual(page.Footnotes, wantFootnotes) {
+				t.Errorf("got footnotes %v, want %v", page.Footnotes, wantFootnotes)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(string(page.Body), want) {
+					t.Errorf("body %q is missing %q", page.Body, want)
+				}
+			}
+			for _, skip := range tt.skipBody {
+				if strings.Contains(string(page.Body), skip) {
+					t.Errorf("body %q contains %q", page.Body, skip)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMDDirExpiryDate(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", strconv.FormatInt(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC).Unix(), 10))
+
+	siteDirPath := t.TempDir() + "/"
+	files := map[string]string{
+		"future.md": "---\ntitle: future\nexpiryDate: 2024-06-02\n---\n",
+		"past.md":   "---\ntitle: past\nexpiryDate: 2024-05-31\n---\n",
+		"today.md":  "---\ntitle: today\nexpiryDate: 2024-06-01\n---\n",
+		"unset.md":  "---\ntitle: unset\n---\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(siteDirPath+"content/", 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(siteDirPath+"content/"+name, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		renderExpired bool
+		want          []string
+	}{
+		{"expired pages are excluded", false, []string{"future.html", "unset.html"}},
+		{"expired pages are rendered with the flag", true, []string{"future.html", "past.html", "today.html", "unset.html"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.Parser{
+				Templates:      make(map[template.URL]parser.TemplateData),
+				TagsMap:        make(map[template.URL][]parser.TemplateData),
+				CollectionsMap: make(map[template.URL][]parser.TemplateData),
+				SiteDataPath:   siteDirPath,
+				RenderExpired:  tt.renderExpired,
+				ErrorLogger:    log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				Warnings:       helpers.NewWarningCollector(),
+			}
+			p.ParseMDDir(siteDirPath+"content/", os.DirFS(siteDirPath+"content/"))
+
+			var got []string
+			for key := range p.Templates {
+				got = append(got, string(key))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayoutTemplatesTheme(t *testing.T) {
+	p := parser.Parser{
+		SiteDataPath: TestDirPath + "theme/",
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+	p.LayoutConfig.Theme = "base"
+
+	templ, err := p.LayoutTemplates()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// The partials of the site override the partials of the theme with the same name
+	var got strings.Builder
+	if err := templ.ExecuteTemplate(&got, "page", nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<main><header>theme</header><footer>site</footer></main>"; strings.TrimSpace(got.String()) != want {
+		t.Errorf("got %q, want %q", got.String(), want)
+	}
+
+	p.LayoutConfig.Theme = "missing"
+	if _, err := p.LayoutTemplates(); err == nil {
+		t.Errorf("got no error for a theme that is not installed")
+	}
+}
+
+func TestLayoutTemplatesFuncs(t *testing.T) {
+	p := parser.Parser{
+		SiteDataPath: TestDirPath + "template_funcs/",
+		ErrorLogger:  log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+
+	templ, err := p.LayoutTemplates()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	type post struct {
+		Title string
+		Image string
+	}
+	tests := []struct {
+		name      string
+		showImage bool
+		want      string
+	}{
+		{"partial called with arguments", true, `<article><h2>Anna</h2><img src="/static/anna.png"></article><article><h2>Untitled</h2><img src=""></article>#go#ssg`},
+		{"argument turning off the image", false, `<article><h2>Anna</h2></article><article><h2>Untitled</h2></article>#go#ssg`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]any{
+				"Posts":     []post{{Title: "Anna", Image: "/static/anna.png"}, {}},
+				"ShowImage": tt.showImage,
+			}
+
+			var got strings.Builder
+			if err := templ.ExecuteTemplate(&got, "page", data); err != nil {
+				t.Fatalf("%v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("got %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("dict with a missing value", func(t *testing.T) {
+		templ, err := p.LayoutTemplates()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		broken, err := templ.New("broken").Parse(`{{ template "card" (dict "Post") }}`)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := broken.Execute(&strings.Builder{}, nil); err == nil {
+			t.Errorf("expected an error for an odd number of dict arguments")
+		}
+	})
+}
+
+func TestParseMarkdownContentHeadingIDs(t *testing.T) {
+	p := parser.Parser{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+
+	content := "---\ntitle: Headings\ntoc: true\n---\n# Table of Contents\n## Setup\nInstall anna[^1]\n## Setup\n## Setup\n## Setup 1\n### Setup\n\n[^1]: With go install\n"
+	_, body, _, _ := p.ParseMarkdownContent(content, "headings.md")
+
+	ids := make(map[string]bool)
+	for _, match := range regexp.MustCompile(`\sid="([^"]*)"`).FindAllStringSubmatch(body, -1) {
+		if ids[match[1]] {
+			t.Errorf("duplicate id %q in %s", match[1], body)
+		}
+		ids[match[1]] = true
+	}
+
+	// The anchor, table of contents and footnote links all point to an element of the page
+	for _, match := range regexp.MustCompile(`href="#([^"]*)"`).FindAllStringSubmatch(body, -1) {
+		if !ids[match[1]] {
+			t.Errorf("link to missing id %q in %s", match[1], body)
+		}
+	}
+
+	headings := []string{
+		`<h2 id="setup">Setup <a class="anchor" href="#setup">#</a></h2>`,
+		`<h2 id="setup-1">Setup <a class="anchor" href="#setup-1">#</a></h2>`,
+		`<h2 id="setup-2">Setup <a class="anchor" href="#setup-2">#</a></h2>`,
+		`<h2 id="setup-1-1">Setup 1 <a class="anchor" href="#setup-1-1">#</a></h2>`,
+		`<h3 id="setup-3">Setup <a class="anchor" href="#setup-3">#</a></h3>`,
+	}
+	for _, heading := range headings {
+		if !strings.Contains(body, heading) {
+			t.Errorf("missing %s in %s", heading, body)
+		}
+	}
+
+	entries := []string{`href="#setup"`, `href="#setup-1"`, `href="#setup-2"`, `href="#setup-1-1"`, `href="#setup-3"`}
+	// The table of contents is rendered before the first heading of the page
+	toc := body[:strings.Index(body, `<h1 id="table-of-contents">`)]
+	for _, entry := range entries {
+		if !strings.Contains(toc, entry) {
+			t.Errorf("missing table of contents entry %s in %s", entry, toc)
+		}
+	}
+}
+
+func TestParseMDDirExcludeDirs(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	files := map[string]string{
+		"about.md":                 "---\ntitle: about\n---\n",
+		"posts/first.md":           "---\ntitle: first\n---\n",
+		"posts/wip/second.md":      "---\ntitle: second\n---\n",
+		"notes/wip/idea.md":        "---\ntitle: idea\n---\n",
+		"archive/2019/old.md":      "---\ntitle: old\n---\n",
+		"archive/2019/deep/old.md": "---\ntitle: deep\n---\n",
+		"archive/2020/kept.md":     "---\ntitle: kept\n---\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(siteDirPath+"content/"+name), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(siteDirPath+"content/"+name, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		excludeDirs []string
+		want        []string
+	}{
+		{"no exclusions", nil, []string{"about.html", "archive/2019/deep/old.html", "archive/2019/old.html", "archive/2020/kept.html", "notes/wip/idea.html", "posts/first.html", "posts/wip/second.html"}},
+		{"top-level directory", []string{"archive"}, []string{"about.html", "notes/wip/idea.html", "posts/first.html", "posts/wip/second.html"}},
+		{"nested directory and its subdirectories", []string{"archive/2019"}, []string{"about.html", "archive/2020/kept.html", "notes/wip/idea.html", "posts/first.html", "posts/wip/second.html"}},
+		{"pattern matching nested directories", []string{"*/wip"}, []string{"about.html", "archive/2019/deep/old.html", "archive/2019/old.html", "archive/2020/kept.html", "posts/first.html"}},
+		{"pattern matching no path", []string{"wip"}, []string{"about.html", "archive/2019/deep/old.html", "archive/2019/old.html", "archive/2020/kept.html", "notes/wip/idea.html", "posts/first.html", "posts/wip/second.html"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.Parser{
+				Templates:      make(map[template.URL]parser.TemplateData),
+				TagsMap:        make(map[template.URL][]parser.TemplateData),
+				CollectionsMap: make(map[template.URL][]parser.TemplateData),
+				SiteDataPath:   siteDirPath,
+				ErrorLogger:    log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				Warnings:       helpers.NewWarningCollector(),
+			}
+			p.LayoutConfig.ExcludeDirs = tt.excludeDirs
+			p.ParseMDDir(siteDirPath+"content/", os.DirFS(siteDirPath+"content/"))
+
+			var got []string
+			for key := range p.Templates {
+				got = append(got, string(key))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddFileCollectionQueries(t *testing.T) {
+	p := parser.Parser{
+		Templates:      make(map[template.URL]parser.TemplateData),
+		TagsMap:        make(map[template.URL][]parser.TemplateData),
+		CollectionsMap: make(map[template.URL][]parser.TemplateData),
+		ErrorLogger:    log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:       helpers.NewWarningCollector(),
+	}
+	p.LayoutConfig.PostsDir = "blog"
+	p.LayoutConfig.TagAliases = map[string]string{"star": "Featured"}
+	p.LayoutConfig.Collections = []parser.CollectionQuery{
+		{Name: "featured", Path: "blog", Tags: []string{"featured"}},
+		{Name: "notes", Type: "note"},
+		{Name: "2024", Type: "post", From: "2024-01-01", To: "2024-12-31"},
+		{Name: "guides", Path: "docs/*.md"},
+	}
+
+	pages := []struct {
+		filename    string
+		frontmatter parser.Frontmatter
+	}{
+		{"blog/first.md", parser.Frontmatter{Title: "first", Date: "2024-03-01", Tags: []string{"Featured"}}},
+		{"blog/2023/old.md", parser.Frontmatter{Title: "old", Date: "2023-12-31", Tags: []string{"star"}}},
+		{"blog/plain.md", parser.Frontmatter{Title: "plain", Date: "2024-12-31"}},
+		{"blog/undated.md", parser.Frontmatter{Title: "undated"}},
+		{"about.md", parser.Frontmatter{Title: "about", Tags: []string{"featured"}}},
+		{"notes/idea.md", parser.Frontmatter{Title: "idea", Type: "note"}},
+		{"docs/setup.md", parser.Frontmatter{Title: "setup", Collections: []string{"guides"}}},
+		{"docs/advanced/tuning.md", parser.Frontmatter{Title: "tuning"}},
+	}
+	for _, page := range pages {
+		p.AddFile("", page.filename, page.frontmatter, "", "")
+	}
+
+	tests := []struct {
+		collection template.URL
+		want       []string
+	}{
+		{"collections/featured.html", []string{"first", "old"}},
+		{"collections/notes.html", []string{"idea"}},
+		{"collections/2024.html", []string{"first", "plain"}},
+		{"collections/guides.html", []string{"setup"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("pages of "+string(tt.collection), func(t *testing.T) {
+			var got []string
+			for _, page := range p.CollectionsMap[tt.collection] {
+				got = append(got, page.Frontmatter.Title)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("collections of a page listed in its frontmatter", func(t *testing.T) {
+		want := []string{"featured", "2024"}
+		if got := p.Templates["blog/first.html"].Frontmatter.Collections; !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestParseMarkdownContentMermaid(t *testing.T) {
+	p := parser.Parser{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+
+	tests := []struct {
+		name        string
+		markdown    string
+		wantDiagram bool
+	}{
+		{"backtick fence", "```mermaid\ngraph TD;\n  A-->B;\n```\n", true},
+		{"tilde fence", "~~~ mermaid\ngraph TD;\n  A-->B;\n~~~\n", true},
+		{"other language", "```go\nfmt.Println(\"mermaid\")\n```\n", false},
+		{"no code blocks", "A page mentioning mermaid diagrams\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The same parser is reused so that pages share the cached renderers
+			for _, toc := range []bool{false, true} {
+				content := fmt.Sprintf("---\ntitle: Mermaid\ntoc: %v\n---\n%s", toc, tt.markdown)
+				_, body, _, _ := p.ParseMarkdownContent(content, "mermaid.md")
+
+				gotDiagram := strings.Contains(body, `<pre class="mermaid">`)
+				gotScript := strings.Contains(body, "mermaid.initialize")
+				if gotDiagram != tt.wantDiagram || gotScript != tt.wantDiagram {
+					t.Errorf("toc %v: got diagram %v and script %v, want %v in %s", toc, gotDiagram, gotScript, tt.wantDiagram, body)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseMarkdownContent(b *testing.B) {
+	p := parser.Parser{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+	}
+
+	pages := map[string]string{
+		"plain":   "---\ntitle: Plain\n---\n" + strings.Repeat("## Heading\nLorem ipsum *dolor* sit amet\n\n", 20),
+		"toc":     "---\ntitle: TOC\ntoc: true\n---\n" + strings.Repeat("## Heading\nLorem ipsum *dolor* sit amet\n\n", 20),
+		"mermaid": "---\ntitle: Mermaid\n---\n```mermaid\ngraph TD;\n  A-->B;\n```\n" + strings.Repeat("Lorem ipsum *dolor* sit amet\n\n", 20),
+	}
+	for _, name := range []string{"plain", "toc", "mermaid"} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p.ParseMarkdownContent(pages[name], name+".md")
+			}
+		})
+	}
+}
+
+func TestTranscludeNotes(t *testing.T) {
+	note := func(key string, title string, body string) parser.TemplateData {
+		return parser.TemplateData{CompleteURL: template.URL(key), Frontmatter: parser.Frontmatter{Title: title, Type: "note"}, Body: template.HTML(body)}
+	}
+	p := parser.Parser{
+		ErrorLogger: log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:    &helpers.WarningCollector{},
+		Templates: map[template.URL]parser.TemplateData{
+			"notes/host.html": note("notes/host.html", "Host", `<h2 id="intro">Intro</h2>
+<p>![[idea]]</p>
+<p>![[notes/recipe#steps]]</p>
+<p>![[missing]]</p>
+<p>![[idea.md]]</p>
+`),
+			"notes/idea.html": note("notes/idea.html", "Idea", `<h2 id="intro">Intro</h2>
+<p>See <a href="#intro">the intro</a></p>
+`),
+			"notes/recipe.html": note("notes/recipe.html", "Recipe", `<h2 id="ingredients">Ingredients</h2>
+<p>Flour</p>
+<h2 id="steps">Steps</h2>
+<h3 id="mix">Mix</h3>
+<p>Stir</p>
+<h2 id="serving">Serving</h2>
+`),
+			"notes/a.html": note("notes/a.html", "A", "<p>a</p>\n<p>![[b]]</p>\n"),
+			"notes/b.html": note("notes/b.html", "B", "<p>b</p>\n<p>![[a]]</p>\n"),
+		},
+	}
+	p.TagsMap = map[template.URL][]parser.TemplateData{"tags/a.html": {p.Templates["notes/a.html"]}}
+
+	p.TranscludeNotes()
+
+	want := map[template.URL]string{
+		"notes/host.html": `<h2 id="intro">Intro</h2>
+<div class="transclusion" data-source="/notes/idea.html">
+<h2 id="idea-intro">Intro</h2>
+<p>See <a href="#idea-intro">the intro</a></p>
+</div>
+<div class="transclusion" data-source="/notes/recipe.html">
+<h2 id="notes-recipe-steps">Steps</h2>
+<h3 id="notes-recipe-mix">Mix</h3>
+<p>Stir</p>
+</div>
+<p>![[missing]]</p>
+<div class="transclusion" data-source="/notes/idea.html">
+<h2 id="idea-intro-2">Intro</h2>
+<p>See <a href="#idea-intro-2">the intro</a></p>
+</div>
+`,
+		"notes/a.html": `<p>a</p>
+<div class="transclusion" data-source="/notes/b.html">
+<p>b</p>
+<p><a href="/notes/a.html">A</a></p>
+</div>
+`,
+	}
+	for key, body := range want {
+		if got := string(p.Templates[key].Body); got != body {
+			t.Errorf("got body of %s\n%s\nwant\n%s", key, got, body)
+		}
+	}
+	if got := string(p.TagsMap["tags/a.html"][0].Body); got != want["notes/a.html"] {
+		t.Errorf("got body of notes/a.html in its tag %s, want the transcluded body", got)
+	}
+
+	wantWarnings := []string{
+		"notes/b.html: ![[a]] forms a transclusion cycle notes/a.html -> notes/b.html -> notes/a.html, linking to the note instead",
+		"notes/a.html: ![[b]] forms a transclusion cycle notes/b.html -> notes/a.html -> notes/b.html, linking to the note instead",
+		"notes/host.html: ![[missing]] does not match any note",
+	}
+	if got := p.Warnings.Warnings(); !slices.Equal(got, wantWarnings) {
+		t.Errorf("got warnings %q, want %q", got, wantWarnings)
+	}
+}
+
+func TestIncludeSnippets(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	files := map[string]string{
+		"content/about.md":                  "---\ntitle: about\n---\n# About\n\n{{% include \"disclaimer.md\" %}}\n\n```md\n{{% include \"disclaimer.md\" %}}\n```\n",
+		"content/snippets/disclaimer.md":    "**Opinions are my own.** {{% include \"contact/email.md\" %}}\n",
+		"content/snippets/contact/email.md": "Mail [me](mailto:me@example.org)\n",
+		"content/snippets/loop/a.md":        "{{% include \"loop/b.md\" %}}",
+		"content/snippets/loop/b.md":        "{{% include \"loop/a.md\" %}}",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(siteDirPath+name), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(siteDirPath+name, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := parser.Parser{
+		Templates:      make(map[template.URL]parser.TemplateData),
+		TagsMap:        make(map[template.URL][]parser.TemplateData),
+		CollectionsMap: make(map[template.URL][]parser.TemplateData),
+		SiteDataPath:   siteDirPath,
+		ErrorLogger:    log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Warnings:       helpers.NewWarningCollector(),
+	}
+	p.ParseMDDir(siteDirPath+"content/", os.DirFS(siteDirPath+"content/"))
+
+	t.Run("snippets are rendered in the including page and not on their own", func(t *testing.T) {
+		var keys []string
+		for key := range p.Templates {
+			keys = append(keys, string(key))
+		}
+		if !slices.Equal(keys, []string{"about.html"}) {
+			t.Fatalf("got pages %v, want only about.html", keys)
+		}
+
+		body := string(p.Templates["about.html"].Body)
+		want := `<p><strong>Opinions are my own.</strong> Mail <a href="mailto:me@example.org">me</a></p>`
+		if !strings.Contains(body, want) {
+			t.Errorf("body %q does not contain %q", body, want)
+		}
+		if !strings.Contains(body, `{{% include &quot;disclaimer.md&quot; %}}`) {
+			t.Errorf("include in a code block was resolved in %q", body)
+		}
+	})
+
+	t.Run("invalid includes", func(t *testing.T) {
+		tests := []struct {
+			markdown string
+			wantErr  string
+		}{
+			{`{{% include "loop/a.md" %}}`, "cycle loop/a.md -> loop/b.md -> loop/a.md"},
+			{`{{% include "missing.md" %}}`, "snippet not found"},
+			{`{{% include "../about.md" %}}`, "outside of the snippets directory"},
+		}
+		for _, tt := range tests {
+			_, err := p.IncludeSnippets(tt.markdown)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("IncludeSnippets(%q) error = %v, want %q", tt.markdown, err, tt.wantErr)
+			}
+		}
+	})
+
+	t.Run("snippetsDir config", func(t *testing.T) {
+		p.LayoutConfig.SnippetsDir = "content/snippets/contact/"
+		got, err := p.IncludeSnippets(`Contact: {{% include "email.md" %}}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "Contact: Mail [me](mailto:me@example.org)"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestParseContentDirsCheckDescriptions(t *testing.T) {
+	siteDirPath := t.TempDir() + "/"
+	files := map[string]string{
+		"first.md":  "---\ntitle: first\ntype: post\ndate: 2024-01-01\ndescription: A post about Go\n---\n",
+		"second.md": "---\ntitle: second\ntype: post\ndate: 2024-01-02\ndescription: \"  a POST  about\n  go \"\n---\n",
+		"third.md":  "---\ntitle: third\ntype: post\ndate: 2024-01-03\n---\n",
+		"fourth.md": "---\ntitle: fourth\ntype: post\ndate: 2024-01-04\ndescription: \" \"\n---\n",
+		"fifth.md":  "---\ntitle: fifth\ntype: post\ndate: 2024-01-05\ndescription: Unique\n---\n",
+		"about.md":  "---\ntitle: about\n---\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(siteDirPath+"content/", 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(siteDirPath+"content/"+name, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name              string
+		checkDescriptions bool
+		want              []string
+	}{
+		{"disabled", false, nil},
+		{"enabled", true, []string{
+			"first.html, second.html: posts share the same description",
+			"fourth.html: post is missing a description",
+			"third.html: post is missing a description",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.Parser{
+				Templates:      make(map[template.URL]parser.TemplateData),
+				TagsMap:        make(map[template.URL][]parser.TemplateData),
+				CollectionsMap: make(map[template.URL][]parser.TemplateData),
+				SiteDataPath:   siteDirPath,
+				ErrorLogger:    log.New(os.Stderr, "TEST ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+				Warnings:       helpers.NewWarningCollector(),
+			}
+			p.LayoutConfig.CheckDescriptions = tt.checkDescriptions
+			p.ParseContentDirs()
+
+			got := p.Warnings.Warnings()
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got warnings %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrontmatterTerms(t *testing.T) {
+	frontmatter, _, errs := parser.ParseFrontmatter("---\ntitle: terms\nauthors: [Ada]\ncategories: [Go, \" \", Web]\nseries: Getting started\nyear: 2024\n---\n", "post.md")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	tests := []struct {
+		field string
+		want  []string
+	}{
+		{"categories", []string{"Go", "Web"}},
+		{"series", []string{"Getting started"}},
+		{"year", []string{"2024"}},
+		{"authors", []string{"Ada"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		if got := frontmatter.Terms(tt.field); !slices.Equal(got, tt.want) {
+			t.Errorf("Terms(%q) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
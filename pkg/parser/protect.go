@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"html/template"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
+)
+
+/*
+Protected pages are encrypted with AES-256-GCM using a key derived from the password with PBKDF2-HMAC-SHA256
+The salt and nonce are random for every page and build, and are stored in the page next to the ciphertext
+The page is decrypted in the browser with the Web Crypto API once the password is entered
+
+When SOURCE_DATE_EPOCH is set, the build is reproducible: the salt is derived from the build time and the URL of the page,
+and the nonce from the key and the body, so that a different body is never encrypted with the same key and nonce.
+Protected pages then reveal whether their body changed between two builds of the same build time
+*/
+const (
+	protectIterations = 600000
+	protectSaltSize   = 16
+	protectKeySize    = 32
+)
+
+// protectPasswordEnvPrefix marks passwords read from an environment variable, such as "env:MEMBERS_PASSWORD"
+const protectPasswordEnvPrefix = "env:"
+
+// protectedBodyTemplate is the password prompt replacing the body of a protected page, which decrypts the body in place
+var protectedBodyTemplate = template.Must(template.New("protected").Parse(`<div class="protected-page" data-salt="{{.Salt}}" data-nonce="{{.Nonce}}" data-iterations="{{.Iterations}}" data-ciphertext="{{.Ciphertext}}">
+<form class="protected-form">
+<label>This page is password protected <input type="password" name="password" autocomplete="current-password" required /></label>
+<button type="submit">Unlock</button>
+<p class="protected-error" hidden>Incorrect password</p>
+</form>
+</div>
+<script>
+(() => {
+  const page = document.currentScript.previousElementSibling;
+  const decode = (value) => Uint8Array.from(atob(value), (c) => c.charCodeAt(0));
+  page.querySelector("form").addEventListener("submit", async (event) => {
+    event.preventDefault();
+    try {
+      const password = new TextEncoder().encode(event.target.password.value);
+      const material = await crypto.subtle.importKey("raw", password, "PBKDF2", false, ["deriveKey"]);
+      const key = await crypto.subtle.deriveKey(
+        { name: "PBKDF2", hash: "SHA-256", salt: decode(page.dataset.salt), iterations: Number(page.dataset.iterations) },
+        material, { name: "AES-GCM", length: 256 }, false, ["decrypt"]);
+      const body = await crypto.subtle.decrypt({ name: "AES-GCM", iv: decode(page.dataset.nonce) }, key, decode(page.dataset.ciphertext));
+      page.outerHTML = new TextDecoder().decode(body);
+    } catch {
+      page.querySelector(".protected-error").hidden = false;
+    }
+  });
+})();
+</script>`))
+
+// protectBody encrypts the rendered body of the page at pageURL with its password and returns the password prompt that decrypts it
+func (p *Parser) protectBody(body string, password string, filePath string, pageURL template.URL) template.HTML {
+	if envName, ok := strings.CutPrefix(password, protectPasswordEnvPrefix); ok {
+		password = os.Getenv(envName)
+		if password == "" {
+			p.ErrorLogger.Fatalf("%s: the password environment variable %s is not set", filePath, envName)
+		}
+	}
+
+	epoch, reproducible, err := helpers.SourceDateEpoch()
+	if err != nil {
+		p.ErrorLogger.Fatal("Invalid SOURCE_DATE_EPOCH: ", err)
+	}
+
+	salt := make([]byte, protectSaltSize)
+	if reproducible {
+		saltHash := sha256.Sum256([]byte("anna-protect-salt\x00" + strconv.FormatInt(epoch.Unix(), 10) + "\x00" + string(pageURL)))
+		copy(salt, saltHash[:])
+	} else if _, err := rand.Read(salt); err != nil {
+		p.ErrorLogger.Fatal(err)
+	}
+
+	key := helpers.PBKDF2SHA256([]byte(password), salt, protectIterations, protectKeySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		p.ErrorLogger.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		p.ErrorLogger.Fatal(err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if reproducible {
+		nonceMAC := hmac.New(sha256.New, key)
+		nonceMAC.Write([]byte(body))
+		copy(nonce, nonceMAC.Sum(nil))
+	} else if _, err := rand.Read(nonce); err != nil {
+		p.ErrorLogger.Fatal(err)
+	}
+
+	var prompt strings.Builder
+	err = protectedBodyTemplate.Execute(&prompt, map[string]any{
+		"Salt":       base64.StdEncoding.EncodeToString(salt),
+		"Nonce":      base64.StdEncoding.EncodeToString(nonce),
+		"Iterations": protectIterations,
+		"Ciphertext": base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, []byte(body), nil)),
+	})
+	if err != nil {
+		p.ErrorLogger.Fatal(err)
+	}
+
+	return template.HTML(prompt.String())
+}
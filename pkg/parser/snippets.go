@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// A markdown snippet included in a page, {{% include "disclaimer.md" %}}
+var includeRegex = regexp.MustCompile(`\{\{%\s*include\s+"([^"\n]+)"\s*%\}\}`)
+
+// SnippetsPath returns the directory of the markdown snippets relative to the site directory, with a trailing slash
+func (l LayoutConfig) SnippetsPath() string {
+	if dir := strings.Trim(l.SnippetsDir, "/"); dir != "" {
+		return dir + "/"
+	}
+	return l.ContentPath() + "snippets/"
+}
+
+/*
+IncludeSnippets replaces every {{% include "name.md" %}} of a markdown document with the content of the snippet
+at name relative to the snippets directory, before the document is rendered so that the snippet is rendered in context
+
+Snippets may include other snippets, and an error is returned for a snippet which includes itself or one it is included in.
+Includes in fenced code blocks are left as they are, so that the syntax can be documented
+*/
+func (p *Parser) IncludeSnippets(markdown string) (string, error) {
+	return p.includeSnippets(markdown, nil)
+}
+
+// includeSnippets resolves the includes of a markdown document, stack holds the snippets being included
+func (p *Parser) includeSnippets(markdown string, stack []string) (string, error) {
+	if !strings.Contains(markdown, "{{%") {
+		return markdown, nil
+	}
+
+	var output strings.Builder
+	var fence string
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			output.WriteString(line)
+			continue
+		}
+		if marker := codeFence(trimmed); marker != "" {
+			fence = marker
+			output.WriteString(line)
+			continue
+		}
+
+		var err error
+		output.WriteString(includeRegex.ReplaceAllStringFunc(line, func(match string) string {
+			if err != nil {
+				return match
+			}
+			var snippet string
+			snippet, err = p.snippet(includeRegex.FindStringSubmatch(match)[1], stack)
+			return snippet
+		}))
+		if err != nil {
+			return "", err
+		}
+	}
+	return output.String(), nil
+}
+
+// snippet returns the content of the snippet at name with its own includes resolved
+func (p *Parser) snippet(name string, stack []string) (string, error) {
+	cleanName := path.Clean(strings.TrimSpace(name))
+	if path.IsAbs(cleanName) || cleanName == ".." || strings.HasPrefix(cleanName, "../") {
+		return "", fmt.Errorf("include %q is outside of the snippets directory %s", name, p.LayoutConfig.SnippetsPath())
+	}
+	if i := slices.Index(stack, cleanName); i >= 0 {
+		return "", fmt.Errorf("include %q forms a cycle %s", name, strings.Join(append(stack[i:], cleanName), " -> "))
+	}
+
+	content, err := os.ReadFile(p.SiteDataPath + p.LayoutConfig.SnippetsPath() + cleanName)
+	if err != nil {
+		return "", fmt.Errorf("include %q: snippet not found in %s", name, p.LayoutConfig.SnippetsPath())
+	}
+
+	snippet, err := p.includeSnippets(string(content), append(stack[:len(stack):len(stack)], cleanName))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(snippet, "\n"), nil
+}
+
+// codeFence returns the marker opening a fenced code block on a line, such as ``` or ~~~~, which is empty for other lines
+func codeFence(line string) string {
+	for _, char := range []string{"`", "~"} {
+		if strings.HasPrefix(line, strings.Repeat(char, 3)) {
+			return line[:len(line)-len(strings.TrimLeft(line, char))]
+		}
+	}
+	return ""
+}
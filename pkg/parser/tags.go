@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
+)
+
+/*
+normalizeTags trims the tags of a page and replaces aliases by their canonical names from the tagAliases config
+Aliases and canonical names are matched case-insensitively, so that js, JS and Javascript all become the configured javascript
+Tags with the same URL, such as Go and go, are listed once with the first spelling used on the page
+*/
+func (p *Parser) normalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return tags
+	}
+
+	canonicalTags := make(map[string]string, 2*len(p.LayoutConfig.TagAliases))
+	for alias, canonical := range p.LayoutConfig.TagAliases {
+		canonical = strings.TrimSpace(canonical)
+		canonicalTags[strings.ToLower(strings.TrimSpace(alias))] = canonical
+		canonicalTags[strings.ToLower(canonical)] = canonical
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if canonical, ok := canonicalTags[strings.ToLower(tag)]; ok {
+			tag = canonical
+		}
+
+		slug := helpers.Slugify(tag)
+		if tag == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
+)
+
+// TaxonomyConfig declares a taxonomy whose terms are read from a field of the frontmatter, such as categories or series
+type TaxonomyConfig struct {
+	// Name of the taxonomy, the pages of its terms are rendered to <name>/<term>.html and listed at <name>.html
+	Name string `json:"name"`
+	// Field of the frontmatter listing the terms of a page, a string or a list, defaults to the name
+	Field string `json:"field"`
+	// Layout of the pages of the terms, defaults to taxonomy-subpage
+	Layout string `json:"layout"`
+	// Layout of the page listing the terms, defaults to taxonomy
+	ListLayout string `json:"listLayout"`
+	// Terms may be nested with > as the separator, "posts>tech" lists a page under posts and posts/tech
+	Nested bool `json:"nested"`
+}
+
+// FieldName returns the field of the frontmatter listing the terms of the taxonomy
+func (t TaxonomyConfig) FieldName() string {
+	return cmp.Or(t.Field, t.Name)
+}
+
+// LayoutName returns the layout of the pages of the terms of the taxonomy
+func (t TaxonomyConfig) LayoutName() string {
+	return cmp.Or(t.Layout, "taxonomy-subpage")
+}
+
+// ListLayoutName returns the layout of the page listing the terms of the taxonomy
+func (t TaxonomyConfig) ListLayoutName() string {
+	return cmp.Or(t.ListLayout, "taxonomy")
+}
+
+/*
+Taxonomies built into anna, the tags and collections of the frontmatter
+Their terms are also stored in the TagsMap and CollectionsMap of the parser, which layouts of earlier versions read
+*/
+var builtinTaxonomies = []TaxonomyConfig{
+	{Name: "tags", Layout: "tag-subpage", ListLayout: "all-tags"},
+	{Name: "collections", Layout: "collection-subpage", ListLayout: "all-collections", Nested: true},
+}
+
+// IsBuiltinTaxonomy reports whether the taxonomy of the given name is built into anna
+func IsBuiltinTaxonomy(name string) bool {
+	return slices.ContainsFunc(builtinTaxonomies, func(taxonomy TaxonomyConfig) bool { return taxonomy.Name == name })
+}
+
+// AllTaxonomies returns the taxonomies built into anna followed by the taxonomies of the config
+func (l LayoutConfig) AllTaxonomies() []TaxonomyConfig {
+	return append(slices.Clone(builtinTaxonomies), l.Taxonomies...)
+}
+
+// validateTaxonomies returns the errors in the taxonomies config, names are used in URLs and must be unique slugs
+func (l LayoutConfig) validateTaxonomies() []string {
+	var errs []string
+	seen := make(map[string]bool)
+	for i, taxonomy := range l.Taxonomies {
+		switch {
+		case taxonomy.Name == "":
+			errs = append(errs, fmt.Sprintf("taxonomies: taxonomy %d is missing its name", i))
+		case helpers.Slugify(taxonomy.Name) != taxonomy.Name:
+			errs = append(errs, fmt.Sprintf("taxonomies: name %q is not a slug, such as %q", taxonomy.Name, helpers.Slugify(taxonomy.Name)))
+		case IsBuiltinTaxonomy(taxonomy.Name):
+			errs = append(errs, fmt.Sprintf("taxonomies: %q is built in and cannot be declared", taxonomy.Name))
+		case seen[taxonomy.Name]:
+			errs = append(errs, fmt.Sprintf("taxonomies: %q is declared more than once", taxonomy.Name))
+		}
+		seen[taxonomy.Name] = true
+	}
+	return errs
+}
+
+/*
+Terms returns the values of a field of the frontmatter listing the terms of a page, such as the categories of a post
+The field may hold a single term or a list of terms, and the tags, collections and authors fields are read as they are parsed
+*/
+func (f Frontmatter) Terms(field string) []string {
+	switch field {
+	case "tags":
+		return f.Tags
+	case "collections":
+		return f.Collections
+	case "authors":
+		return f.Authors
+	}
+
+	var terms []string
+	switch value := f.Params[field].(type) {
+	case string:
+		terms = append(terms, value)
+	case []any:
+		for _, item := range value {
+			if item != nil {
+				terms = append(terms, fmt.Sprint(item))
+			}
+		}
+	case nil:
+	default:
+		terms = append(terms, fmt.Sprint(value))
+	}
+
+	nonEmpty := terms[:0]
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			nonEmpty = append(nonEmpty, term)
+		}
+	}
+	return nonEmpty
+}
+
+/*
+taxonomiesParser adds a page to the terms of every taxonomy it lists in its frontmatter, the tags and collections
+along with the taxonomies of the config
+The terms of nested taxonomies are split on >, and the page is added to every level of the term once
+*/
+func (p *Parser) taxonomiesParser(page TemplateData) {
+	for _, taxonomy := range p.LayoutConfig.AllTaxonomies() {
+		for _, term := range page.Frontmatter.Terms(taxonomy.FieldName()) {
+			names := []string{term}
+			if taxonomy.Nested {
+				names = strings.Split(term, ">")
+				for i := range names {
+					names[i] = strings.TrimSpace(names[i])
+				}
+			}
+
+			terms := p.taxonomyTerms(taxonomy.Name)
+			for i := range names {
+				termKey, ok := p.termKey(taxonomy.Name+"/", names[:i+1])
+				if !ok {
+					break
+				}
+				if taxonomy.Nested && slices.ContainsFunc(terms[termKey], func(termPage TemplateData) bool {
+					return termPage.CompleteURL == page.CompleteURL
+				}) {
+					continue
+				}
+				terms[termKey] = append(terms[termKey], page)
+			}
+		}
+	}
+}
+
+// taxonomyTerms returns the pages of every term of a taxonomy, the terms of tags and collections are shared with the TagsMap and CollectionsMap
+func (p *Parser) taxonomyTerms(name string) map[template.URL][]TemplateData {
+	if terms, ok := p.TaxonomyMap[name]; ok {
+		return terms
+	}
+
+	var terms map[template.URL][]TemplateData
+	switch name {
+	case "tags":
+		if p.TagsMap == nil {
+			p.TagsMap = make(map[template.URL][]TemplateData)
+		}
+		terms = p.TagsMap
+	case "collections":
+		if p.CollectionsMap == nil {
+			p.CollectionsMap = make(map[template.URL][]TemplateData)
+		}
+		terms = p.CollectionsMap
+	default:
+		terms = make(map[template.URL][]TemplateData)
+	}
+
+	if p.TaxonomyMap == nil {
+		p.TaxonomyMap = make(map[string]map[template.URL][]TemplateData)
+	}
+	p.TaxonomyMap[name] = terms
+	return terms
+}
@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	texttemplate "text/template"
+)
+
+/*
+ExecuteTemplateFile executes the file at inFilePath as a text template with data and writes the output to outFilePath
+The directories of outFilePath are created, so that files can be rendered to any path of the site
+*/
+func ExecuteTemplateFile(inFilePath string, outFilePath string, data any) error {
+	tmpl, err := texttemplate.New(filepath.Base(inFilePath)).Funcs(templateFuncs()).ParseFiles(inFilePath)
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outFilePath), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(outFilePath, buffer.Bytes(), 0640)
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
+)
+
+// templateFuncs returns the functions available to layouts and template files
+func templateFuncs() map[string]any {
+	return map[string]any{
+		"slugify": helpers.Slugify,
+		// Function to check if an element is present in a slice
+		"strSliceContains": func(items []string, search string) bool {
+			for _, item := range items {
+				if search == item {
+					return true
+				}
+			}
+			return false
+		},
+		"dict":    dict,
+		"slice":   slice,
+		"default": defaultValue,
+	}
+}
+
+/*
+dict builds a map from alternating keys and values, so that partials can be called with arguments
+{{ template "card" (dict "Post" . "ShowImage" true) }}
+*/
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict expects pairs of keys and values, got %d arguments", len(pairs))
+	}
+
+	values := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got %T", pairs[i])
+		}
+		values[key] = pairs[i+1]
+	}
+	return values, nil
+}
+
+// slice builds a list from its arguments
+func slice(items ...any) []any {
+	return items
+}
+
+// defaultValue returns value, or fallback when value is missing or the zero value of its type
+// {{ default "Untitled" .Frontmatter.Title }}
+func defaultValue(fallback any, value any) any {
+	if value == nil || reflect.ValueOf(value).IsZero() {
+		return fallback
+	}
+	return value
+}
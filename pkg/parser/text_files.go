@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HumansConfig stores the people and tools credited in humans.txt
+type HumansConfig struct {
+	// People who built the site, listed under TEAM
+	Team []HumansMember `json:"team"`
+	// People and projects thanked under THANKS
+	Thanks []string `json:"thanks"`
+	// Standards, components and software used to build the site, listed under SITE
+	Tech []string `json:"tech"`
+}
+
+// HumansMember is a person of the team of humans.txt, only the name is required
+type HumansMember struct {
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Contact  string `json:"contact"`
+	Location string `json:"location"`
+}
+
+// SecurityConfig stores the fields of .well-known/security.txt as defined by RFC 9116
+type SecurityConfig struct {
+	// Addresses to report vulnerabilities to, URIs such as https:// or mailto: links, bare email addresses are prefixed with mailto:
+	Contact []string `json:"contact"`
+	// Date after which the file is stale in the RFC 3339 format, such as 2026-12-31T23:59:59Z
+	Expires string `json:"expires"`
+	// Link to the vulnerability disclosure policy
+	Policy string `json:"policy"`
+	// Link to the key used to encrypt reports
+	Encryption string `json:"encryption"`
+	// Link to the page thanking the reporters of vulnerabilities
+	Acknowledgments string `json:"acknowledgments"`
+	// Languages reports can be written in, such as ["en", "de"]
+	PreferredLanguages []string `json:"preferredLanguages"`
+}
+
+// ContactURIs returns the contacts of security.txt as URIs
+func (s SecurityConfig) ContactURIs() []string {
+	contacts := make([]string, 0, len(s.Contact))
+	for _, contact := range s.Contact {
+		contact = strings.TrimSpace(contact)
+		if !strings.Contains(contact, ":") && strings.Contains(contact, "@") {
+			contact = "mailto:" + contact
+		}
+		contacts = append(contacts, contact)
+	}
+	return contacts
+}
+
+// validateTextFiles returns the errors in the humans and security configs
+func (l LayoutConfig) validateTextFiles(now time.Time) []string {
+	var errs []string
+	if l.Humans != nil {
+		for i, member := range l.Humans.Team {
+			if strings.TrimSpace(member.Name) == "" {
+				errs = append(errs, fmt.Sprintf("humans: member %d of the team is missing its name", i))
+			}
+		}
+	}
+	if l.Security != nil {
+		errs = append(errs, l.Security.Validate(now)...)
+	}
+	return errs
+}
+
+// Validate returns the errors in the fields of security.txt, which requires a contact and an expiry date after now
+func (s SecurityConfig) Validate(now time.Time) []string {
+	var errs []string
+	if len(s.Contact) == 0 {
+		errs = append(errs, "security: at least one contact is required")
+	}
+	for i, contact := range s.Contact {
+		if strings.TrimSpace(contact) == "" {
+			errs = append(errs, fmt.Sprintf("security: contact %d is empty", i))
+		}
+	}
+	if s.Expires == "" {
+		errs = append(errs, "security: expires is required")
+	} else if expires, err := time.Parse(time.RFC3339, s.Expires); err != nil {
+		errs = append(errs, fmt.Sprintf("security: invalid expires %q, expected a date such as 2026-12-31T23:59:59Z", s.Expires))
+	} else if !expires.After(now) {
+		errs = append(errs, fmt.Sprintf("security: expires %q is in the past", s.Expires))
+	}
+	return errs
+}
@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"fmt"
+	"html/template"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ThemesDir is the directory of the site the themes are installed to by anna theme install, relative to the site directory
+const ThemesDir = "themes/"
+
+// ThemePath returns the path of the directory of the theme set by the theme config relative to the site directory, empty without a theme
+func (l LayoutConfig) ThemePath() string {
+	if l.Theme == "" {
+		return ""
+	}
+	return ThemesDir + l.Theme + "/"
+}
+
+// validateTheme returns the errors in the theme config, which is a directory of themes/ joined into the paths of the layouts
+func (l LayoutConfig) validateTheme() []string {
+	if l.Theme == "" {
+		return nil
+	}
+	cleanTheme := path.Clean(filepath.ToSlash(l.Theme))
+	if filepath.IsAbs(l.Theme) || path.IsAbs(cleanTheme) || cleanTheme == "." || cleanTheme == ".." || strings.HasPrefix(cleanTheme, "../") {
+		return []string{fmt.Sprintf("theme: %q is outside of the %s directory, set the name of an installed theme", l.Theme, ThemesDir)}
+	}
+	return nil
+}
+
+/*
+parseThemeLayouts parses the layouts and partials of the theme before those of the site, so that the site can override
+any of them by defining a template of the same name. A site using a theme may have no layouts of its own
+*/
+func (p *Parser) parseThemeLayouts(templ *template.Template) (*template.Template, error) {
+	themeDirPath := p.SiteDataPath + p.LayoutConfig.ThemePath()
+	templ, err := templ.ParseGlob(themeDirPath + "layout/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pattern := range []string{
+		themeDirPath + "layout/partials/*.html",
+		p.SiteDataPath + "layout/*.html",
+		p.SiteDataPath + "layout/partials/*.html",
+	} {
+		if matches, _ := filepath.Glob(pattern); len(matches) == 0 {
+			continue
+		}
+		if templ, err = templ.ParseGlob(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return templ, nil
+}
@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"fmt"
+	"html/template"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/anna-ssg/anna/v3/pkg/helpers"
+)
+
+var (
+	// A paragraph holding only a reference to a note, ![[note]] or ![[note#section]]
+	transclusionRegex = regexp.MustCompile(`<p>!\[\[([^\]\n]+)\]\]</p>`)
+	elementIDRegex    = regexp.MustCompile(`\sid="([^"]*)"`)
+	anchorLinkRegex   = regexp.MustCompile(`href="#([^"]*)"`)
+)
+
+// transclusion resolves the references of notes to other notes, pages are identified by their key
+type transclusion struct {
+	p *Parser
+	// Keys of the notes by their path without the extension and, when it is unique, by their file name
+	notes map[string]template.URL
+	// File names shared by several notes, which must be referenced by their path
+	ambiguous map[string]bool
+	// Warnings already reported, as a cycle is found from every note it goes through
+	warned map[string]bool
+}
+
+/*
+TranscludeNotes replaces every paragraph of a note holding only ![[note]] with the body of the referenced note,
+and every ![[note#section]] with the section of the note under the heading with the ID or text section
+
+Notes are referenced by their path relative to the root of the site without the extension, or by their file name
+when no other note shares it. Transcluded notes are resolved recursively, and a note transcluding a note it is
+transcluded in is linked instead with a warning. The IDs of the transcluded elements are prefixed with the name
+of their note and numbered when they are already used in the page, so that anchors stay unique
+*/
+func (p *Parser) TranscludeNotes() {
+	t := transclusion{
+		p:         p,
+		notes:     make(map[string]template.URL),
+		ambiguous: make(map[string]bool),
+		warned:    make(map[string]bool),
+	}
+
+	var keys []string
+	for key, page := range p.Templates {
+		if page.Frontmatter.Type == "note" && page.Frontmatter.IsHTML() {
+			keys = append(keys, string(key))
+		}
+	}
+	sort.Strings(keys)
+
+	// Paths take precedence over file names, which only match when a single note has them
+	names := make(map[string][]template.URL)
+	for _, key := range keys {
+		ref := strings.TrimSuffix(key, ".html")
+		t.notes[ref] = template.URL(key)
+		name := ref[strings.LastIndex(ref, "/")+1:]
+		names[name] = append(names[name], template.URL(key))
+	}
+	for name, notes := range names {
+		if _, ok := t.notes[name]; ok {
+			continue
+		}
+		if len(notes) > 1 {
+			t.ambiguous[name] = true
+			continue
+		}
+		t.notes[name] = notes[0]
+	}
+
+	// Notes are transcluded as they were parsed, so the pages are updated once every note is resolved
+	bodies := make(map[template.URL]template.HTML)
+	for _, key := range keys {
+		page := p.Templates[template.URL(key)]
+		if transclusionRegex.MatchString(string(page.Body)) {
+			bodies[page.CompleteURL] = template.HTML(t.transclude(template.URL(key), []template.URL{template.URL(key)}, idsOf(string(page.Body))))
+		}
+	}
+	for key, page := range p.Templates {
+		if body, ok := bodies[page.CompleteURL]; ok {
+			page.Body = body
+			p.Templates[key] = page
+		}
+	}
+
+	// Tags, collections and taxonomies hold copies of the pages
+	replaceBodies := func(pages []TemplateData) {
+		for i, page := range pages {
+			if body, ok := bodies[page.CompleteURL]; ok {
+				pages[i].Body = body
+			}
+		}
+	}
+	replaceBodies(p.Posts)
+	for _, pages := range p.TagsMap {
+		replaceBodies(pages)
+	}
+	for _, pages := range p.CollectionsMap {
+		replaceBodies(pages)
+	}
+	for name, terms := range p.TaxonomyMap {
+		// The terms of the tags and collections are the TagsMap and CollectionsMap
+		if IsBuiltinTaxonomy(name) {
+			continue
+		}
+		for _, pages := range terms {
+			replaceBodies(pages)
+		}
+	}
+}
+
+// transclude returns the body of the note at key with its references resolved, path holds the notes being transcluded
+func (t *transclusion) transclude(key template.URL, path []template.URL, usedIDs map[string]bool) string {
+	body := string(t.p.Templates[key].Body)
+	return transclusionRegex.ReplaceAllStringFunc(body, func(match string) string {
+		ref := strings.TrimSpace(transclusionRegex.FindStringSubmatch(match)[1])
+		noteRef, section, _ := strings.Cut(ref, "#")
+		noteRef = strings.TrimSuffix(strings.Trim(strings.TrimSpace(noteRef), "/"), ".md")
+
+		if t.ambiguous[noteRef] {
+			t.warnf("%s: ![[%s]] matches several notes, reference the note by its path", path[0], ref)
+			return match
+		}
+		target, ok := t.notes[noteRef]
+		if !ok {
+			t.warnf("%s: ![[%s]] does not match any note", path[0], ref)
+			return match
+		}
+		targetPage := t.p.Templates[target]
+
+		for i, transcluded := range path {
+			if transcluded != target {
+				continue
+			}
+			cycle := make([]string, 0, len(path)-i+1)
+			for _, note := range path[i:] {
+				cycle = append(cycle, string(note))
+			}
+			t.warnf("%s: ![[%s]] forms a transclusion cycle %s, linking to the note instead", path[len(path)-1], ref, strings.Join(append(cycle, string(target)), " -> "))
+			return fmt.Sprintf(`<p><a href="/%s">%s</a></p>`, targetPage.CompleteURL, template.HTMLEscapeString(targetPage.Frontmatter.Title))
+		}
+
+		fragment := t.transclude(target, append(path[:len(path):len(path)], target), usedIDs)
+		if section != "" {
+			var found bool
+			fragment, found = sectionOf(fragment, strings.TrimSpace(section))
+			if !found {
+				t.warnf("%s: ![[%s]] does not match any heading of %s", path[0], ref, target)
+				return match
+			}
+		}
+
+		prefix := helpers.Slugify(strings.ReplaceAll(noteRef, "/", "-")) + "-"
+		return fmt.Sprintf(`<div class="transclusion" data-source="/%s">`, targetPage.CompleteURL) + "\n" + uniqueIDs(fragment, prefix, usedIDs) + "</div>"
+	})
+}
+
+// warnf reports a warning once
+func (t *transclusion) warnf(format string, v ...any) {
+	message := fmt.Sprintf(format, v...)
+	if t.warned[message] {
+		return
+	}
+	t.warned[message] = true
+	t.p.Warnings.Warn(message)
+}
+
+// sectionOf returns the heading with the ID or text section and the elements following it up to the next heading of the same or a higher level
+func sectionOf(body string, section string) (string, bool) {
+	document, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return "", false
+	}
+
+	heading := document.Find("h1, h2, h3, h4, h5, h6").FilterFunction(func(_ int, heading *goquery.Selection) bool {
+		id, _ := heading.Attr("id")
+		return id == section || strings.EqualFold(strings.TrimSpace(heading.Text()), section)
+	}).First()
+	if heading.Length() == 0 {
+		return "", false
+	}
+
+	level := goquery.NodeName(heading)
+	var html strings.Builder
+	for element := heading; element.Length() > 0; element = element.Next() {
+		if name := goquery.NodeName(element); element != heading && isHeading(name) && name <= level {
+			break
+		}
+		outer, err := goquery.OuterHtml(element)
+		if err != nil {
+			return "", false
+		}
+		html.WriteString(outer)
+		html.WriteString("\n")
+	}
+	return html.String(), true
+}
+
+// isHeading reports whether an element name is that of a heading, h1 to h6
+func isHeading(name string) bool {
+	return len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6'
+}
+
+// idsOf returns the IDs of the elements of a body
+func idsOf(body string) map[string]bool {
+	ids := make(map[string]bool)
+	for _, match := range elementIDRegex.FindAllStringSubmatch(body, -1) {
+		ids[match[1]] = true
+	}
+	return ids
+}
+
+// uniqueIDs prefixes the IDs of a transcluded fragment, numbering those already used, and updates the links to them within the fragment
+func uniqueIDs(fragment string, prefix string, usedIDs map[string]bool) string {
+	renamed := make(map[string]string)
+	fragment = elementIDRegex.ReplaceAllStringFunc(fragment, func(match string) string {
+		id := elementIDRegex.FindStringSubmatch(match)[1]
+		newID := prefix + id
+		for i := 2; usedIDs[newID]; i++ {
+			newID = fmt.Sprintf("%s%s-%d", prefix, id, i)
+		}
+		usedIDs[newID] = true
+		renamed[id] = newID
+		return fmt.Sprintf(` id="%s"`, newID)
+	})
+
+	return anchorLinkRegex.ReplaceAllStringFunc(fragment, func(match string) string {
+		if newID, ok := renamed[anchorLinkRegex.FindStringSubmatch(match)[1]]; ok {
+			return `href="#` + newID + `"`
+		}
+		return match
+	})
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anna-ssg/anna/v3/cmd/anna"
+)
+
+// The tests of the log output run the test binary as anna, as failed builds exit the process
+const commandHelperEnv = "ANNA_TEST_COMMAND"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(commandHelperEnv) == "" {
+		os.Exit(m.Run())
+	}
+
+	rootCmd := newRootCmd()
+	rootCmd.SetArgs(os.Args[1:])
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestServeCommand(t *testing.T) {
+	var served []anna.Cmd
+	liveReloadManager = func(cmd *anna.Cmd) { served = append(served, *cmd) }
+	t.Cleanup(func() { liveReloadManager = (*anna.Cmd).LiveReloadManager })
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    anna.Cmd
+		wantErr string
+	}{
+		{
+			name: "default options",
+			args: []string{"serve"},
+			want: anna.Cmd{Addr: "localhost:8000", LiveReload: true},
+		},
+		{
+			name: "drafts",
+			args: []string{"serve", "--drafts"},
+			want: anna.Cmd{Addr: "localhost:8000", LiveReload: true, RenderDrafts: true},
+		},
+		{
+			name: "drafts with the flag of the root command",
+			args: []string{"serve", "--draft"},
+			want: anna.Cmd{Addr: "localhost:8000", LiveReload: true, RenderDrafts: true},
+		},
+		{
+			name: "every option",
+			args: []string{"serve", "-d", "--expired", "-q", "--host", "0.0.0.0", "--port", "9000", "--no-reload", "--base-url", "https://example.org/blog", "--base-path", "/preview", "-r", "docs/"},
+			want: anna.Cmd{
+				Addr:              "0.0.0.0:9000",
+				LiveReload:        true,
+				RenderDrafts:      true,
+				RenderExpired:     true,
+				Quiet:             true,
+				NoReload:          true,
+				BaseURL:           "https://example.org/blog",
+				BasePath:          "/preview",
+				ServeSpecificSite: "docs/",
+			},
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"serve", "--expire"},
+			wantErr: "unknown flag: --expire",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served = nil
+			rootCmd := newRootCmd()
+			rootCmd.SetArgs(tt.args)
+			rootCmd.SetOut(io.Discard)
+			rootCmd.SetErr(io.Discard)
+
+			err := rootCmd.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(served) != 1 {
+				t.Fatalf("served the site %d times, want once", len(served))
+			}
+
+			got := served[0]
+			if got.Addr != tt.want.Addr || got.LiveReload != tt.want.LiveReload || got.RenderDrafts != tt.want.RenderDrafts || got.RenderExpired != tt.want.RenderExpired ||
+				got.Quiet != tt.want.Quiet || got.NoReload != tt.want.NoReload || got.BaseURL != tt.want.BaseURL ||
+				got.BasePath != tt.want.BasePath || got.ServeSpecificSite != tt.want.ServeSpecificSite {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebuildCommandFlags(t *testing.T) {
+	// The serve loop passes the options of the site to the rebuild subcommand with the same flags as the root command
+	rootCmd := newRootCmd()
+	rebuildCmd, _, err := rootCmd.Find([]string{anna.RebuildCommand})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rebuildCmd.ParseFlags([]string{"--draft", "--expired", "--quiet", "--jobs", "2", "--base-path", "/preview"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"draft", "expired", "quiet"} {
+		if value, _ := rebuildCmd.Flags().GetBool(name); !value {
+			t.Errorf("got --%s unset, want it set", name)
+		}
+	}
+}
+
+func TestQuietOutput(t *testing.T) {
+	// A site whose post is missing a date, which is reported as a warning
+	site := map[string]string{
+		"layout/config.json":        `{"baseURL": "https://example.org"}`,
+		"layout/page.html":          `{{define "page"}}page{{end}}{{define "all-tags"}}tags{{end}}{{define "all-collections"}}collections{{end}}`,
+		"layout/partials/head.html": `{{define "head"}}{{end}}`,
+		"content/index.md":          "---\ntitle: Home\n---\n",
+		"content/posts/hello.md":    "---\ntitle: Hello\ntype: post\n---\n",
+		"static/style.css":          "body {}",
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		// Removes a file of the site, failing the build
+		remove     string
+		wantFailed bool
+		want       []string
+		notWant    []string
+	}{
+		{name: "info and warnings are printed by default", args: nil, want: []string{"LOG\t", "WARN\t"}},
+		{name: "quiet mode prints only the warnings", args: []string{"--quiet"}, want: []string{"WARN\t"}, notWant: []string{"LOG\t"}},
+		{
+			name:       "quiet mode prints the errors of a failed build",
+			args:       []string{"--quiet"},
+			remove:     "layout/partials/head.html",
+			wantFailed: true,
+			want:       []string{"WARN\t", "ERROR\t"},
+			notWant:    []string{"LOG\t"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			siteDataPath := t.TempDir() + "/"
+			for name, content := range site {
+				if err := os.MkdirAll(filepath.Dir(siteDataPath+name), 0750); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(siteDataPath+name, []byte(content), 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.remove != "" {
+				if err := os.Remove(siteDataPath + tt.remove); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The rebuilds of anna serve forward the quiet flag to the rebuild subcommand
+			var stderr bytes.Buffer
+			cmd := exec.Command(os.Args[0], append([]string{anna.RebuildCommand, siteDataPath}, tt.args...)...)
+			cmd.Env = append(os.Environ(), commandHelperEnv+"=1")
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+			if (err != nil) != tt.wantFailed {
+				t.Fatalf("got error %v, want a failed build %t\n%s", err, tt.wantFailed, stderr.String())
+			}
+
+			output := stderr.String()
+			for _, prefix := range tt.want {
+				if !strings.Contains(output, prefix) {
+					t.Errorf("got output without %q lines\n%s", prefix, output)
+				}
+			}
+			for _, prefix := range tt.notWant {
+				if strings.Contains(output, prefix) {
+					t.Errorf("got output with %q lines\n%s", prefix, output)
+				}
+			}
+		})
+	}
+}
+
+func TestQuietVersion(t *testing.T) {
+	// The version is printed in quiet mode, before the site in the working directory is rendered
+	var stdout bytes.Buffer
+	cmd := exec.Command(os.Args[0], "-q", "-v")
+	cmd.Env = append(os.Environ(), commandHelperEnv+"=1", "SOURCE_DATE_EPOCH=1700000000")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "Current version: ") {
+		t.Errorf("got output %q, want the version", stdout.String())
+	}
+}